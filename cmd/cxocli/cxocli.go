@@ -2,12 +2,16 @@ package main
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +20,8 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 
+	"github.com/skycoin/cxo/cxoutils"
+	"github.com/skycoin/cxo/data"
 	"github.com/skycoin/cxo/node"
 	"github.com/skycoin/cxo/skyobject"
 	"github.com/skycoin/cxo/skyobject/registry"
@@ -52,6 +58,7 @@ var (
 		"tcp subsribe ",
 		"tcp unsubscribe ",
 
+		"tcp stat ",
 		"tcp address ",
 
 		// udp
@@ -62,6 +69,7 @@ var (
 		"udp subscribe ",
 		"udp unsubscribe ",
 
+		"udp stat ",
 		"udp address ",
 
 		// all connections
@@ -69,16 +77,65 @@ var (
 		"connections ",
 		"connections of feed ",
 
+		// known peers
+
+		"peers ",
+		"add peer ",
+		"del peer ",
+
+		// bans
+
+		"bans ",
+		"ban ",
+		"unban ",
+		"score ",
+
+		// identity
+
+		"id ",
+		"rotate id ",
+
 		// root objects
 
 		"root info ",
 		"root tree ",
 		"last root ",
 
+		"object get ",
+		"roots list ",
+		"registry show ",
+		"refs page ",
+
+		// publishing
+
+		"generate feed keypair ",
+		"add feed with key ",
+		"list feed keys ",
+		"unlock keys ",
+		"publish ",
+
+		// retention
+
+		"retention show ",
+		"retention set ",
+		"retention del ",
+		"retain ",
+
+		// access control lists
+
+		"acl show ",
+		"acl set ",
+		"acl del ",
+		"acl list ",
+
 		// stat
 
 		"stat ",
 
+		// backup
+
+		"backup ",
+
 		// help
 
 		"help",
@@ -95,6 +152,11 @@ func main() {
 	var (
 		address string
 		execute string
+		restore string
+
+		fsck, fix bool
+
+		conf = skyobject.NewConfig()
 
 		rpc = new(client)
 		err error
@@ -126,11 +188,27 @@ func main() {
 		"",
 		"execute command and exit")
 
+	flag.StringVar(&restore,
+		"restore",
+		"",
+		"restore given backup to fresh DB (see -data-dir and -db-path) and exit")
+
+	flag.BoolVar(&fsck,
+		"fsck",
+		false,
+		"check DB of stopped node (see -data-dir and -db-path) and exit")
+	flag.BoolVar(&fix,
+		"fsck-fix",
+		false,
+		"check and fix DB of stopped node and exit")
+
 	flag.BoolVar(&help,
 		"h",
 		false,
 		"show help")
 
+	conf.FromFlags()
+
 	flag.Parse()
 
 	if help {
@@ -139,6 +217,22 @@ func main() {
 		return
 	}
 
+	if restore != "" {
+		if err = restoreBackup(conf, restore); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+		return
+	}
+
+	if fsck == true || fix == true {
+		if err = checkDB(conf, fix); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+		return
+	}
+
 	if address == "" {
 		fmt.Fprintln(os.Stderr, "empty address")
 		code = 1
@@ -164,6 +258,8 @@ func main() {
 	line = liner.NewLiner()
 	defer line.Close()
 
+	rpc.line = line
+
 	line.SetCtrlCAborts(true) // why it is not work
 
 	line.SetCompleter(func(line string) (c []string) {
@@ -246,8 +342,9 @@ Error:
 }
 
 type client struct {
-	r *node.RPCClient
-	m map[string]func(in []string) (err error)
+	r    *node.RPCClient
+	line *liner.State // nil if the cli executes command and exits
+	m    map[string]func(in []string) (err error)
 
 	// TODO (kostyarin): autocomplite feeds, nonces, seq numbers,
 	//                   connections
@@ -269,23 +366,60 @@ func (c *client) mapping() map[string]func(in []string) (err error) {
 		"tcp disconnect":  c.tcpDisconnet,
 		"tcp subsribe":    c.tcpSubscribe,
 		"tcp unsubscribe": c.tcpUnsubscribe,
+		"tcp stat":        c.tcpStat,
 		"tcp address":     c.tcpAddress,
 
 		"udp connect":     c.udpConnect,
 		"udp disconnect":  c.udpDisconnet,
 		"udp subsribe":    c.udpSubscribe,
 		"udp unsubscribe": c.udpUnsubscribe,
+		"udp stat":        c.udpStat,
 		"udp address":     c.udpAddress,
 
 		"connections":         c.connections,
 		"connections of feed": c.connectionsOfFeed,
 
+		"peers":    c.peers,
+		"add peer": c.addPeer,
+		"del peer": c.delPeer,
+
+		"bans":  c.bans,
+		"ban":   c.ban,
+		"unban": c.unban,
+		"score": c.score,
+
+		"id":        c.id,
+		"rotate id": c.rotateID,
+
 		"root info": c.rootInfo,
 		"root tree": c.rootTree,
 		"last root": c.lastRoot,
 
+		"object get":    c.objectGet,
+		"roots list":    c.rootsList,
+		"registry show": c.registryShow,
+		"refs page":     c.refsPage,
+
+		"generate feed keypair": c.generateFeedKeypair,
+		"add feed with key":     c.addFeedWithKey,
+		"list feed keys":        c.listFeedKeys,
+		"unlock keys":           c.unlockKeys,
+		"publish":               c.publish,
+
+		"retention show": c.retentionShow,
+		"retention set":  c.retentionSet,
+		"retention del":  c.retentionDel,
+		"retain":         c.retain,
+
+		"acl show": c.aclShow,
+		"acl set":  c.aclSet,
+		"acl del":  c.aclDel,
+		"acl list": c.aclList,
+
 		"stat": c.stat,
 
+		"backup": c.backup,
+
 		"help": c.help,
 
 		"quit": c.quit,
@@ -527,6 +661,19 @@ func (c *client) tcpUnsubscribe(in []string) (err error) {
 	return c.r.TCP().Unsubscribe(cf.Address, cf.Feed)
 }
 
+func (c *client) tcpStat(in []string) (err error) {
+	var address string
+	if address, err = c.argsAddress(in); err != nil {
+		return
+	}
+	var cs node.ConnStat
+	if cs, err = c.r.TCP().Stat(address); err != nil {
+		return
+	}
+	printConnStat(cs)
+	return
+}
+
 func (c *client) tcpAddress(in []string) (err error) {
 	if err = c.argsNo(in); err != nil {
 		return
@@ -579,6 +726,19 @@ func (c *client) udpUnsubscribe(in []string) (err error) {
 	return c.r.UDP().Unsubscribe(cf.Address, cf.Feed)
 }
 
+func (c *client) udpStat(in []string) (err error) {
+	var address string
+	if address, err = c.argsAddress(in); err != nil {
+		return
+	}
+	var cs node.ConnStat
+	if cs, err = c.r.UDP().Stat(address); err != nil {
+		return
+	}
+	printConnStat(cs)
+	return
+}
+
 func (c *client) udpAddress(in []string) (err error) {
 	if err = c.argsNo(in); err != nil {
 		return
@@ -599,6 +759,20 @@ func (c *client) udpAddress(in []string) (err error) {
 // connections
 //
 
+func printConnStat(cs node.ConnStat) {
+	var codec = cs.Codec
+	if codec == "" {
+		codec = "not compressed"
+	}
+	fmt.Fprintln(out, "  protocol:", cs.Protocol)
+	fmt.Fprintln(out, "  caps:    ", strings.Join(cs.Caps, ", "))
+	fmt.Fprintln(out, "  codec:   ", codec)
+	fmt.Fprintf(out, "  sent:     %d (%d on wire, ratio %.2f)\n",
+		cs.Sent, cs.SentWire, cs.SendRatio())
+	fmt.Fprintf(out, "  received: %d (%d on wire, ratio %.2f)\n",
+		cs.Received, cs.ReceivedWire, cs.ReceiveRatio())
+}
+
 func printConnections(cs []string) {
 	if len(cs) == 0 {
 		fmt.Fprintln(out, "  no connections")
@@ -634,6 +808,196 @@ func (c *client) connectionsOfFeed(in []string) (err error) {
 	return
 }
 
+// address of a known peer, "tcp://" or "udp://"
+// prefix is optional, default is tcp
+func peerAddress(a string) (address string, isTCP bool) {
+	switch {
+	case strings.HasPrefix(a, "udp://"):
+		return strings.TrimPrefix(a, "udp://"), false
+	default:
+		return strings.TrimPrefix(a, "tcp://"), true
+	}
+}
+
+func (c *client) peers(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var ps []node.PeerInfo
+	if ps, err = c.r.Node().Peers(); err != nil {
+		return
+	}
+	if len(ps) == 0 {
+		fmt.Fprintln(out, "  no known peers")
+		return
+	}
+	for _, p := range ps {
+		var scheme = "udp://"
+		if p.TCP == true {
+			scheme = "tcp://"
+		}
+		fmt.Fprintf(out, "  %s%s (%s)\n", scheme, p.Address, p.State)
+		if p.State == node.PeerDisconnected && p.Attempts > 0 {
+			fmt.Fprintf(out, "    attempts: %d, next: %s\n", p.Attempts,
+				p.Next.Format(time.Stamp))
+		}
+		if p.LastError != "" {
+			fmt.Fprintln(out, "    last error:", p.LastError)
+		}
+		for _, pk := range p.Feeds {
+			fmt.Fprintln(out, "    -", pk.Hex())
+		}
+	}
+	return
+}
+
+func (c *client) addPeer(in []string) (err error) {
+	if len(in) == 0 {
+		return errors.New("missing address")
+	}
+	var (
+		address, isTCP = peerAddress(in[0])
+		feeds          = make([]cipher.PubKey, 0, len(in)-1)
+	)
+	for _, hex := range in[1:] {
+		var pk cipher.PubKey
+		if pk, err = pubKeyFromHex(hex); err != nil {
+			return
+		}
+		feeds = append(feeds, pk)
+	}
+	return c.r.Node().AddPeer(address, isTCP, feeds...)
+}
+
+func (c *client) delPeer(in []string) (err error) {
+	var a string
+	if a, err = c.argsAddress(in); err != nil {
+		return
+	}
+	var address, isTCP = peerAddress(a)
+	return c.r.Node().DelPeer(address, isTCP)
+}
+
+// PeerID or IP address
+func banTarget(s string) (id cipher.PubKey, ip string, err error) {
+	if id, err = cipher.PubKeyFromHex(s); err == nil {
+		return
+	}
+	if net.ParseIP(s) == nil {
+		err = fmt.Errorf("%q is not a peer id or ip address", s)
+		return
+	}
+	return id, s, nil
+}
+
+func (c *client) bans(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var bans []node.Ban
+	if bans, err = c.r.Node().Bans(); err != nil {
+		return
+	}
+	if len(bans) == 0 {
+		fmt.Fprintln(out, "  no bans")
+		return
+	}
+	for _, b := range bans {
+		var until = "permanent"
+		if b.IsPermanent() == false {
+			until = "until " + b.Until.Format(time.Stamp)
+		}
+		if b.PeerID != (cipher.PubKey{}) {
+			fmt.Fprintln(out, "  id:", b.PeerID.Hex())
+		}
+		if b.IP != "" {
+			fmt.Fprintln(out, "  ip:", b.IP)
+		}
+		fmt.Fprintf(out, "    %s: %s\n", until, b.Reason)
+	}
+	return
+}
+
+func (c *client) ban(in []string) (err error) {
+	if len(in) == 0 {
+		return errors.New("missing peer id or ip address")
+	}
+	var (
+		id     cipher.PubKey
+		ip     string
+		d      time.Duration
+		reason = "banned manually"
+	)
+	if id, ip, err = banTarget(in[0]); err != nil {
+		return
+	}
+	if len(in) > 1 {
+		if d, err = time.ParseDuration(in[1]); err != nil {
+			return
+		}
+	}
+	if len(in) > 2 {
+		reason = strings.Join(in[2:], " ")
+	}
+	return c.r.Node().Ban(id, ip, d, reason)
+}
+
+func (c *client) unban(in []string) (err error) {
+	var one string
+	if one, err = c.argsOne(in, "peer id or ip address"); err != nil {
+		return
+	}
+	var (
+		id cipher.PubKey
+		ip string
+	)
+	if id, ip, err = banTarget(one); err != nil {
+		return
+	}
+	return c.r.Node().Unban(id, ip)
+}
+
+func (c *client) score(in []string) (err error) {
+	var id cipher.PubKey
+	if id, err = c.argsFeed(in); err != nil {
+		return
+	}
+	var score int
+	if score, err = c.r.Node().Score(id); err != nil {
+		return
+	}
+	fmt.Fprintln(out, " ", score)
+	return
+}
+
+//
+// identity
+//
+
+func (c *client) id(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var id cipher.PubKey
+	if id, err = c.r.Node().ID(); err != nil {
+		return
+	}
+	fmt.Fprintln(out, " ", id.Hex())
+	return
+}
+
+func (c *client) rotateID(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var id cipher.PubKey
+	if id, err = c.r.Node().RotateID(); err != nil {
+		return
+	}
+	fmt.Fprintln(out, "  new id:", id.Hex())
+	return
+}
+
 //
 // root objects
 //
@@ -645,6 +1009,12 @@ func (c *client) printRoot(z *registry.Root) {
 		refs = append(refs, dr.Short())
 	}
 
+	var merged = make([]string, 0, len(z.Merged))
+
+	for _, hash := range z.Merged {
+		merged = append(merged, hash.Hex()[:7])
+	}
+
 	fmt.Fprintf(out, `  root  %s
 
     refs:       %#v
@@ -660,6 +1030,7 @@ func (c *client) printRoot(z *registry.Root) {
 
 	sig:        %s
 	prev:       %s
+	merged:     %v
 
 `,
 		z.Hash.Hex(),
@@ -672,6 +1043,7 @@ func (c *client) printRoot(z *registry.Root) {
 		time.Unix(0, z.Time),
 		z.Sig.Hex(),
 		z.Prev.Hex(),
+		merged,
 	)
 }
 
@@ -714,6 +1086,508 @@ func (c *client) lastRoot(in []string) (err error) {
 	return
 }
 
+func hashFromHex(hs string) (hash cipher.SHA256, err error) {
+	return cipher.SHA256FromHex(hs)
+}
+
+func argsUint(s, name string) (u uint64, err error) {
+	if u, err = strconv.ParseUint(s, 10, 64); err != nil {
+		err = fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return
+}
+
+func argsInt(s, name string) (i int, err error) {
+	if i, err = strconv.Atoi(s); err != nil {
+		err = fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return
+}
+
+// object get <hash> [<registry> <schema> [depth]]
+func (c *client) objectGet(in []string) (err error) {
+
+	const expected = "expected hash [registry schema [depth]]"
+
+	switch len(in) {
+	case 0:
+		return errors.New("missing arguments: " + expected)
+	case 1, 3, 4:
+	default:
+		return errors.New("wrong number of arguments: " + expected)
+	}
+
+	var key cipher.SHA256
+	if key, err = hashFromHex(in[0]); err != nil {
+		return
+	}
+
+	if len(in) == 1 {
+		var val []byte
+		if val, err = c.r.Root().Object(key); err != nil {
+			return
+		}
+		fmt.Fprintf(out, "  %d bytes\n  %s\n", len(val), hex.EncodeToString(val))
+		return
+	}
+
+	var reg, sch cipher.SHA256
+	if reg, err = hashFromHex(in[1]); err != nil {
+		return
+	}
+	if sch, err = hashFromHex(in[2]); err != nil {
+		return
+	}
+
+	var depth int
+	if len(in) == 4 {
+		if depth, err = argsInt(in[3], "depth"); err != nil {
+			return
+		}
+	}
+
+	var doc string
+	doc, err = c.r.Root().Decode(registry.RegistryRef(reg),
+		registry.SchemaRef(sch), key, depth)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, doc)
+	return
+}
+
+// roots list <public key> [<nonce> [from [limit [desc]]]]
+func (c *client) rootsList(in []string) (err error) {
+
+	const expected = "expected public key [nonce [from [limit [desc]]]]"
+
+	if len(in) == 0 {
+		return errors.New("missing arguments: " + expected)
+	} else if len(in) > 5 {
+		return errors.New("too many arguments: " + expected)
+	}
+
+	var ra node.RootsArgs
+	if ra.Feed, err = pubKeyFromHex(in[0]); err != nil {
+		return
+	}
+
+	if len(in) == 1 {
+		var heads []uint64
+		if heads, err = c.r.Root().Heads(ra.Feed); err != nil {
+			return
+		}
+		fmt.Fprintln(out, "  heads:")
+		for _, nonce := range heads {
+			fmt.Fprintln(out, "   ", nonce)
+		}
+		return
+	}
+
+	if ra.Nonce, err = argsUint(in[1], "nonce"); err != nil {
+		return
+	}
+
+	if len(in) > 2 {
+		if ra.From, err = argsUint(in[2], "seq"); err != nil {
+			return
+		}
+	}
+
+	if len(in) > 3 {
+		if ra.Limit, err = argsInt(in[3], "limit"); err != nil {
+			return
+		}
+	}
+
+	if len(in) > 4 {
+		if in[4] != "desc" {
+			return fmt.Errorf("unexpected argument %q, expected desc", in[4])
+		}
+		ra.Descend = true
+	}
+
+	var rs []registry.Root
+	if rs, err = c.r.Root().Roots(ra); err != nil {
+		return
+	}
+
+	for _, r := range rs {
+		fmt.Fprintf(out, "  %d %s %v\n", r.Seq, r.Hash.Hex()[:7],
+			time.Unix(0, r.Time))
+	}
+
+	return
+}
+
+// registry show <public key> <nonce> <seq>
+func (c *client) registryShow(in []string) (err error) {
+
+	var sl node.RootSelector
+	if sl, err = c.argsRoot(in); err != nil {
+		return
+	}
+
+	var ri *node.RegistryInfo
+	if ri, err = c.r.Root().Registry(sl.Feed, sl.Nonce, sl.Seq); err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, "  registry", ri.Ref.String())
+
+	for _, si := range ri.Schemas {
+		fmt.Fprintf(out, "\n    %s %s (%s)\n", si.Name, si.Ref.String(), si.Kind)
+		for _, fl := range si.Fields {
+			fmt.Fprintln(out, "      ", fl)
+		}
+	}
+
+	fmt.Fprintln(out)
+	return
+}
+
+// refs page <registry> <hash> <from> <to> [<schema> [depth]]
+func (c *client) refsPage(in []string) (err error) {
+
+	const expected = "expected registry, hash, from, to [schema [depth]]"
+
+	if len(in) < 4 {
+		return errors.New("missing arguments: " + expected)
+	} else if len(in) > 6 {
+		return errors.New("too many arguments: " + expected)
+	}
+
+	var (
+		ra  node.RefsArgs
+		reg cipher.SHA256
+	)
+
+	if reg, err = hashFromHex(in[0]); err != nil {
+		return
+	}
+	ra.Registry = registry.RegistryRef(reg)
+
+	if ra.Hash, err = hashFromHex(in[1]); err != nil {
+		return
+	}
+
+	if ra.From, err = argsInt(in[2], "from"); err != nil {
+		return
+	}
+
+	if ra.To, err = argsInt(in[3], "to"); err != nil {
+		return
+	}
+
+	if len(in) > 4 {
+		var sch cipher.SHA256
+		if sch, err = hashFromHex(in[4]); err != nil {
+			return
+		}
+		ra.Schema = registry.SchemaRef(sch)
+	}
+
+	if len(in) > 5 {
+		if ra.Depth, err = argsInt(in[5], "depth"); err != nil {
+			return
+		}
+	}
+
+	var rp *node.RefsPage
+	if rp, err = c.r.Root().Refs(ra); err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, "  length:", rp.Length)
+
+	for _, el := range rp.Elements {
+		fmt.Fprintf(out, "  %d %s %s\n", el.Index, el.Hash.Hex()[:7], el.Value)
+	}
+
+	return
+}
+
+//
+// publishing
+//
+
+func (c *client) generateFeedKeypair(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var pk, sk = cipher.GenerateKeyPair()
+	fmt.Fprintln(out, "  public key:", pk.Hex())
+	fmt.Fprintln(out, "  secret key:", sk.Hex())
+	return
+}
+
+func (c *client) addFeedWithKey(in []string) (err error) {
+
+	var one string
+	if one, err = c.argsOne(in, "secret key"); err != nil {
+		return
+	}
+
+	var sk cipher.SecKey
+	if sk, err = cipher.SecKeyFromHex(one); err != nil {
+		return
+	}
+
+	var pk cipher.PubKey
+	if pk, err = c.r.Node().AddFeedKey(sk); err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, "  added", pk.Hex())
+	return
+}
+
+func (c *client) unlockKeys(in []string) (err error) {
+
+	var passphrase string
+
+	if c.line == nil {
+		if passphrase, err = c.argsOne(in, "passphrase"); err != nil {
+			return
+		}
+	} else {
+		if err = c.argsNo(in); err != nil {
+			return
+		}
+		if passphrase, err = c.line.PasswordPrompt("passphrase: "); err != nil {
+			return
+		}
+	}
+
+	if err = c.r.Node().UnlockKeys([]byte(passphrase)); err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, "  unlocked")
+	return
+}
+
+func (c *client) listFeedKeys(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var pks []cipher.PubKey
+	if pks, err = c.r.Node().FeedKeys(); err != nil {
+		return
+	}
+	if len(pks) == 0 {
+		fmt.Fprintln(out, "  no feed keys")
+		return
+	}
+	for _, pk := range pks {
+		fmt.Fprintln(out, " ", pk.Hex())
+	}
+	return
+}
+
+func (c *client) retentionShow(in []string) (err error) {
+	var pk cipher.PubKey
+	if pk, err = c.argsFeed(in); err != nil {
+		return
+	}
+	var (
+		rt  skyobject.Retention
+		own bool
+	)
+	if rt, own, err = c.r.Node().Retention(pk); err != nil {
+		return
+	}
+	var policy = rt.String()
+	if policy == "" {
+		policy = "all"
+	}
+	if own == false {
+		policy += " (default)"
+	}
+	fmt.Fprintln(out, " ", policy)
+	return
+}
+
+func (c *client) retentionSet(in []string) (err error) {
+	if len(in) != 2 {
+		return errors.New("expected public key and policy")
+	}
+	var pk cipher.PubKey
+	if pk, err = pubKeyFromHex(in[0]); err != nil {
+		return
+	}
+	var rt skyobject.Retention
+	if rt, err = skyobject.ParseRetention(in[1]); err != nil {
+		return
+	}
+	return c.r.Node().SetRetention(pk, rt)
+}
+
+func (c *client) retentionDel(in []string) (err error) {
+	var pk cipher.PubKey
+	if pk, err = c.argsFeed(in); err != nil {
+		return
+	}
+	return c.r.Node().DelRetention(pk)
+}
+
+func (c *client) retain(in []string) (err error) {
+	var pk cipher.PubKey
+	if pk, err = c.argsFeed(in); err != nil {
+		return
+	}
+	var removed int
+	if removed, err = c.r.Node().Retain(pk); err != nil {
+		return
+	}
+	fmt.Fprintln(out, "  removed", removed, "Root objects")
+	return
+}
+
+func (c *client) aclShow(in []string) (err error) {
+	var pk cipher.PubKey
+	if pk, err = c.argsFeed(in); err != nil {
+		return
+	}
+	var (
+		acl node.ACL
+		own bool
+	)
+	if acl, own, err = c.r.Node().ACL(pk); err != nil {
+		return
+	}
+	if own == false {
+		fmt.Fprintln(out, "  open to any peer")
+		return
+	}
+	fmt.Fprintln(out, "  subscribe:", acl.Subscribe)
+	fmt.Fprintln(out, "  preview:  ", acl.Preview)
+	fmt.Fprintln(out, "  fetch:    ", acl.Fetch)
+	return
+}
+
+func (c *client) aclSet(in []string) (err error) {
+	if len(in) != 2 {
+		return errors.New("expected public key and ACL")
+	}
+	var pk cipher.PubKey
+	if pk, err = pubKeyFromHex(in[0]); err != nil {
+		return
+	}
+	var acl node.ACL
+	if acl, err = node.ParseACL(in[1]); err != nil {
+		return
+	}
+	return c.r.Node().SetACL(pk, acl)
+}
+
+func (c *client) aclDel(in []string) (err error) {
+	var pk cipher.PubKey
+	if pk, err = c.argsFeed(in); err != nil {
+		return
+	}
+	return c.r.Node().DelACL(pk)
+}
+
+func (c *client) aclList(in []string) (err error) {
+	if err = c.argsNo(in); err != nil {
+		return
+	}
+	var acls node.ACLs
+	if acls, err = c.r.Node().ACLs(); err != nil {
+		return
+	}
+	if len(acls) == 0 {
+		fmt.Fprintln(out, "  no ACLs, all feeds are open")
+		return
+	}
+	var feeds = make([]cipher.PubKey, 0, len(acls))
+	for pk := range acls {
+		feeds = append(feeds, pk)
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		return feeds[i].Hex() < feeds[j].Hex()
+	})
+	for _, pk := range feeds {
+		fmt.Fprintln(out, " ", pk.Hex())
+		fmt.Fprintln(out, "   ", acls[pk])
+	}
+	return
+}
+
+// JSON description of a Root to publish
+type publishDoc struct {
+	Nonce      uint64   `json:"nonce"`
+	Descriptor string   `json:"descriptor"`
+	Readers    []string `json:"readers"`
+	Refs       []struct {
+		Schema string          `json:"schema"`
+		Value  json.RawMessage `json:"value"`
+	} `json:"refs"`
+}
+
+// publish <public key> <registry> <JSON | @file>
+func (c *client) publish(in []string) (err error) {
+
+	const expected = "expected public key, registry and JSON or @file"
+
+	if len(in) < 3 {
+		return errors.New("missing arguments: " + expected)
+	}
+
+	var pa node.PublishArgs
+	if pa.Feed, err = pubKeyFromHex(in[0]); err != nil {
+		return
+	}
+
+	pa.Registry = in[1]
+
+	var doc []byte
+
+	if strings.HasPrefix(in[2], "@") == true {
+		if len(in) > 3 {
+			return errors.New("too many arguments: " + expected)
+		}
+		if doc, err = ioutil.ReadFile(in[2][1:]); err != nil {
+			return
+		}
+	} else {
+		doc = []byte(strings.Join(in[2:], " "))
+	}
+
+	var pd publishDoc
+	if err = json.Unmarshal(doc, &pd); err != nil {
+		return
+	}
+
+	pa.Nonce = pd.Nonce
+	pa.Descriptor = []byte(pd.Descriptor)
+
+	for _, hex := range pd.Readers {
+		var pk cipher.PubKey
+		if pk, err = pubKeyFromHex(hex); err != nil {
+			return
+		}
+		pa.Readers = append(pa.Readers, pk)
+	}
+
+	for _, ref := range pd.Refs {
+		pa.Refs = append(pa.Refs, node.JSONRef{
+			Schema: ref.Schema,
+			Value:  string(ref.Value),
+		})
+	}
+
+	var z *registry.Root
+	if z, err = c.r.Node().Publish(pa); err != nil {
+		return
+	}
+
+	c.printRoot(z)
+	return
+}
+
 //
 // stat
 //
@@ -800,6 +1674,74 @@ func (c *client) stat(in []string) (err error) {
 	return
 }
 
+//
+// backup
+//
+
+func (c *client) backup(in []string) (err error) {
+
+	if len(in) == 0 {
+		return errors.New("missing argument: expected path")
+	}
+
+	var feeds = make([]cipher.PubKey, 0, len(in)-1)
+
+	for _, pks := range in[1:] {
+		var pk cipher.PubKey
+		if pk, err = pubKeyFromHex(pks); err != nil {
+			return
+		}
+		feeds = append(feeds, pk)
+	}
+
+	if err = c.r.Node().Backup(in[0], feeds...); err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, "  saved to", in[0])
+	return
+}
+
+// restore given backup and exit (no RPC required)
+func restoreBackup(conf *skyobject.Config, path string) (err error) {
+
+	var fl *os.File
+	if fl, err = os.Open(path); err != nil {
+		return
+	}
+	defer fl.Close()
+
+	if err = skyobject.Restore(conf, fl); err != nil {
+		return
+	}
+
+	fmt.Fprintln(out, "restored")
+	return
+}
+
+// check DB and exit (no RPC required)
+func checkDB(conf *skyobject.Config, fix bool) (err error) {
+
+	var db *data.DB
+	if db, err = skyobject.OpenDB(conf); err != nil {
+		return
+	}
+	defer db.Close()
+
+	var rep *cxoutils.FsckReport
+	if rep, err = cxoutils.Fsck(db, fix); err != nil {
+		return
+	}
+
+	rep.Print(out)
+
+	if rep.OK() == false && fix == false {
+		err = errors.New("DB has errors, use -fsck-fix to fix them")
+	}
+
+	return
+}
+
 func (c *client) help(in []string) (err error) {
 	fmt.Fprint(out, `
 
@@ -818,6 +1760,9 @@ func (c *client) help(in []string) (err error) {
     subscribe to feed of peer
   tcp unsubscribe <connection address> <public key>
     unsubscribe from feed of peer
+  tcp stat <connection address>
+    show protocol, capabilities, compression codec
+    and compression ratio of connection
   tcp address
     tcp listening address
 
@@ -829,6 +1774,9 @@ func (c *client) help(in []string) (err error) {
     subscribe to feed of peer
   udp unsubscribe <connection address> <public key>
     unsubscribe from feed of peer
+  udp stat <connection address>
+    show protocol, capabilities, compression codec
+    and compression ratio of connection
   udp address
     udp listening address
 
@@ -839,6 +1787,37 @@ func (c *client) help(in []string) (err error) {
     show connections of given feed
 
 
+  peers
+    show known peers the node keeps connections to
+  add peer <address> [public keys]
+    add known peer, the node connects to the peer and
+    reconnects if connection drops, subscribing to given
+    feeds; the address can be prefixed with tcp:// or
+    udp://, default is tcp; known peers are saved
+  del peer <address>
+    remove known peer, the node stops reconnecting to the
+    peer, but current connection is not closed
+
+
+  bans
+    show banned peers
+  ban <peer id or ip> [duration] [reason]
+    ban peer by its id or ip address, connections of the
+    peer will be closed; zero or missing duration means
+    permanent ban, e.g. 'ban 127.0.0.1 24h flood'
+  unban <peer id or ip>
+    remove ban
+  score <peer id>
+    show score of violations of a peer
+
+
+  id
+    show id (public key) of the node
+  rotate id
+    generate new id of the node and save it; existing
+    connections are not affected
+
+
   root info <public key> <nonce> <seq>
     show info of selected Root
 
@@ -848,11 +1827,85 @@ func (c *client) help(in []string) (err error) {
   last root <public key>
     show info about last Root of given feed
 
+  object get <hash> [<registry> <schema> [depth]]
+    print encoded object, or decode it with given
+    registry and schema, the depth is depth of
+    references expansion (-1 for all)
+  roots list <public key> [<nonce> [from [limit [desc]]]]
+    list heads of given feed, or Root objects of given
+    head starting from given seq (descending if desc,
+    the 0 is the last Root in this case)
+  registry show <public key> <nonce> <seq>
+    show registry of given Root with its schemas
+  refs page <registry> <hash> <from> <to> [<schema> [depth]]
+    show elements of given Refs in [from, to) range,
+    if schema of elements is set, then values decoded
+
+
+  generate feed keypair
+    generate and print new public and secret keys
+  add feed with key <secret key>
+    add secret key of a feed to key store of the node,
+    the node starts sharing the feed and can publish its
+    Root objects after that
+  list feed keys
+    list feeds the node has secret keys of
+  unlock keys
+    unlock key store of the node, the cli asks for passphrase
+    (use 'unlock keys <passphrase>' with -e flag), the key
+    store will be created if it doesn't exist
+  publish <public key> <registry> <JSON or @file>
+    publish new Root of given feed, the registry is name
+    of registry added to the node or hex reference of a
+    registry stored in DB; the JSON (or JSON file) is
+      {"nonce": 0, "descriptor": "", "readers": [],
+       "refs": [{"schema": "name", "value": {...}}]}
+    where zero nonce means active or new head; if the
+    readers (hex public keys) is not empty, then the Root
+    is confidential and only the readers can read objects
+
+
+  retention show <public key>
+    show Retention policy of given feed
+  retention set <public key> <policy>
+    set Retention policy of given feed, the policy is 'all'
+    or comma separated 'last=n', 'for=duration' and 'full',
+    e.g. 'last=10,for=24h'; the policy is lost after restart
+    of the node, use -retention flag of the node instead
+  retention del <public key>
+    use default Retention policy for given feed
+  retain <public key>
+    remove old Root objects of given feed by its Retention
+    policy right now
+
+
+  acl show <public key>
+    show access control list of given feed
+  acl set <public key> <acl>
+    set access control list of given feed, the acl is
+    comma separated 'subscribe=', 'preview=' and 'fetch='
+    with 'any', 'none' or peer ids joined by '+', e.g.
+    'subscribe=<id>+<id>,preview=none,fetch=<id>+<id>';
+    omitted actions are not allowed to anyone; the acl is
+    lost after restart of the node, use -acl flag of the
+    node instead
+  acl del <public key>
+    remove access control list of given feed, the feed
+    will be open to any peer
+  acl list
+    show all access control lists
+
 
   stat
     show statistic of node
 
 
+  backup <path> [public keys]
+    write backup of given feeds (or all feeds) to given path
+    on machine of the node, use -restore flag of the cli to
+    load the backup to fresh DB
+
+
   help
     show this help messege
 
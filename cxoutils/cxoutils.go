@@ -12,6 +12,15 @@
 // And the same for Root objects. The CXO keeps all
 // Root objects. But who interest old, replaced Root
 // objects?
+//
+// The skyobject.Container has its own garbage collector
+// that does the same incrementally and in background
+// (see GC related fields of the skyobject.Config). Use
+// the collector for long-running nodes instead of this
+// package.
+//
+// The Fsck function checks consistency of databases of a
+// stopped node and fixes references counters
 package cxoutils
 
 import (
@@ -94,5 +103,3 @@ func RemoveObjects(c *skyobject.Container) (err error) {
 
 	return
 }
-
-// TODO (kostyarin): RemoveObjects with "down to" or "timeout" feature
@@ -0,0 +1,587 @@
+package cxoutils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// ErrReadOnly returned by Pack of the Fsck
+// if someone is trying to save an object
+var ErrReadOnly = errors.New("read only")
+
+var errCorrupted = errors.New("corrupted object")
+
+// A FsckRoot points to a Root object in IdxDB
+type FsckRoot struct {
+	Pub   cipher.PubKey // feed
+	Nonce uint64        // head
+	Seq   uint64        // seq number
+}
+
+// String implements fmt.Stringer interface
+func (f FsckRoot) String() string {
+	return fmt.Sprintf("%s/%d/%d", f.Pub.Hex()[:7], f.Nonce, f.Seq)
+}
+
+// A FsckMissing represents object missing
+// in CXDS and first Root the object belongs to
+type FsckMissing struct {
+	Key  cipher.SHA256 // missing object
+	Root FsckRoot      // first Root that refers to the object
+}
+
+// A FsckRC represents wrong references counter
+type FsckRC struct {
+	Key  cipher.SHA256 // object
+	RC   uint32        // saved (wrong) references counter
+	Want uint32        // actual references counter
+}
+
+// A FsckBroken represents a Root that can't be used,
+// or broken chain of Root objects of a head
+type FsckBroken struct {
+	Root   FsckRoot // the Root
+	Reason string   // what is wrong
+}
+
+// A FsckReport is result of the Fsck
+type FsckReport struct {
+	Roots   int // Root objects checked
+	Objects int // objects in CXDS
+
+	Missing   []FsckMissing   // missing objects
+	Corrupted []cipher.SHA256 // objects with wrong hash
+	Orphaned  []cipher.SHA256 // objects with rc > 0 not used by Root objects
+	WrongRCs  []FsckRC        // wrong references counters of used objects
+	Broken    []FsckBroken    // broken Root objects and chains
+
+	// Incomplete Root objects, that have missing
+	// or corrupted objects, or can't be used
+	// (see Broken) for some reasons
+	Incomplete []FsckRoot
+
+	Fixed   int // fixed references counters
+	Removed int // removed incomplete Root objects
+}
+
+// OK returns true if no problems found
+func (f *FsckReport) OK() bool {
+	return len(f.Missing) == 0 &&
+		len(f.Corrupted) == 0 &&
+		len(f.Orphaned) == 0 &&
+		len(f.WrongRCs) == 0 &&
+		len(f.Broken) == 0 &&
+		len(f.Incomplete) == 0
+}
+
+// Print the report to given io.Writer in human readable form
+func (f *FsckReport) Print(w io.Writer) {
+
+	fmt.Fprintf(w, "checked %d Root objects and %d objects\n",
+		f.Roots, f.Objects)
+
+	for _, m := range f.Missing {
+		fmt.Fprintf(w, "  missing object %s of Root %s\n", m.Key.Hex()[:7],
+			m.Root)
+	}
+
+	for _, key := range f.Corrupted {
+		fmt.Fprintf(w, "  corrupted object %s\n", key.Hex()[:7])
+	}
+
+	for _, key := range f.Orphaned {
+		fmt.Fprintf(w, "  orphaned object %s\n", key.Hex()[:7])
+	}
+
+	for _, wr := range f.WrongRCs {
+		fmt.Fprintf(w, "  wrong rc of object %s: %d, want %d\n",
+			wr.Key.Hex()[:7], wr.RC, wr.Want)
+	}
+
+	for _, b := range f.Broken {
+		fmt.Fprintf(w, "  broken Root %s: %s\n", b.Root, b.Reason)
+	}
+
+	for _, r := range f.Incomplete {
+		fmt.Fprintf(w, "  incomplete Root %s\n", r)
+	}
+
+	if f.Fixed > 0 || f.Removed > 0 {
+		fmt.Fprintf(w, "fixed %d references counters, removed %d Root objects\n",
+			f.Fixed, f.Removed)
+	}
+
+	if f.OK() == true {
+		fmt.Fprintln(w, "ok")
+	}
+
+}
+
+// Fsck checks given DB. The Fsck walks all Root objects
+// through their registries, recomputes references counters
+// and reports missing, corrupted and orphaned objects,
+// wrong references counters and broken chains of Root
+// objects. If the fix argument is true, then the Fsck
+// removes incomplete Root objects from IdxDB and fixes
+// references counters. The DB must not be used by a
+// Container during the Fsck (see skyobject.OpenDB). The
+// Fsck keeps all keys of the CXDS in memory
+func Fsck(db *data.DB, fix bool) (rep *FsckReport, err error) {
+
+	var f = fsck{
+		db:     db,
+		rep:    new(FsckReport),
+		stored: make(map[cipher.SHA256]uint32),
+		bad:    make(map[cipher.SHA256]struct{}),
+		regs:   make(map[registry.RegistryRef]*registry.Registry),
+	}
+
+	if err = f.objects(); err != nil {
+		return
+	}
+
+	if err = f.load(); err != nil {
+		return
+	}
+
+	f.count()
+	f.incomplete()
+
+	if fix == true && len(f.rep.Incomplete) > 0 {
+		if err = f.removeIncomplete(); err != nil {
+			return
+		}
+		f.count() // again, without removed Root objects
+	}
+
+	if err = f.rcs(fix); err != nil {
+		return
+	}
+
+	return f.rep, nil
+}
+
+// a Root to check
+type fsckRoot struct {
+	FsckRoot
+
+	r *registry.Root // decoded or nil
+}
+
+type fsck struct {
+	db  *data.DB
+	rep *FsckReport
+
+	stored map[cipher.SHA256]uint32 // saved rcs
+	actual map[cipher.SHA256]uint32 // actual rcs
+	bad    map[cipher.SHA256]struct{}
+
+	regs map[registry.RegistryRef]*registry.Registry
+
+	roots []*fsckRoot
+	inc   map[FsckRoot]struct{} // incomplete
+}
+
+// iterate all objects of the CXDS checking hashes
+func (f *fsck) objects() (err error) {
+
+	return f.db.CXDS().Iterate(
+		func(key cipher.SHA256, rc uint32, val []byte) (_ error) {
+
+			f.rep.Objects++
+			f.stored[key] = rc
+
+			if cipher.SumSHA256(val) != key {
+				f.rep.Corrupted = append(f.rep.Corrupted, key)
+				f.bad[key] = struct{}{}
+			}
+
+			return
+		})
+
+}
+
+func (f *fsck) broken(fr FsckRoot, format string, args ...interface{}) {
+	f.rep.Broken = append(f.rep.Broken, FsckBroken{
+		Root:   fr,
+		Reason: fmt.Sprintf(format, args...),
+	})
+}
+
+// load Root objects from IdxDB checking chains
+func (f *fsck) load() (err error) {
+
+	err = f.db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+
+		return fs.Iterate(func(pk cipher.PubKey) (err error) {
+
+			var hs data.Heads
+			if hs, err = fs.Heads(pk); err != nil {
+				return
+			}
+
+			return hs.Iterate(func(nonce uint64) (err error) {
+
+				var rs data.Roots
+				if rs, err = hs.Roots(nonce); err != nil {
+					return
+				}
+
+				var prev data.Root // the dr can be reused by the Ascend
+
+				return rs.Ascend(func(dr *data.Root) (_ error) {
+
+					var fr = &fsckRoot{
+						FsckRoot: FsckRoot{Pub: pk, Nonce: nonce, Seq: dr.Seq},
+					}
+
+					f.roots = append(f.roots, fr)
+					f.root(fr, dr)
+
+					if prev.Hash != (cipher.SHA256{}) &&
+						prev.Seq+1 == dr.Seq && prev.Hash != dr.Prev {
+
+						f.broken(fr.FsckRoot, "broken chain, Prev %s, want %s",
+							dr.Prev.Hex()[:7], prev.Hash.Hex()[:7])
+					}
+
+					prev = *dr
+					return
+				})
+
+			})
+
+		})
+
+	})
+
+	f.rep.Roots = len(f.roots)
+	return
+}
+
+// check and decode Root
+func (f *fsck) root(fr *fsckRoot, dr *data.Root) {
+
+	if err := dr.Validate(); err != nil {
+		f.broken(fr.FsckRoot, "%v", err)
+		return
+	}
+
+	var val, _, err = f.db.CXDS().Get(dr.Hash, 0)
+
+	if err == data.ErrNotFound {
+		f.missing(dr.Hash, fr.FsckRoot)
+		return
+	} else if err != nil {
+		f.broken(fr.FsckRoot, "%v", err)
+		return
+	}
+
+	if _, ok := f.bad[dr.Hash]; ok == true {
+		return // corrupted
+	}
+
+	var r *registry.Root
+	if r, err = registry.DecodeRoot(val); err != nil {
+		f.broken(fr.FsckRoot, "decoding: %v", err)
+		return
+	}
+
+	r.Hash = dr.Hash
+	r.Sig = dr.Sig
+
+	if r.Pub != fr.Pub || r.Nonce != fr.Nonce || r.Seq != fr.Seq ||
+		r.Prev != dr.Prev {
+
+		f.broken(fr.FsckRoot, "Root doesn't match its meta information")
+		return
+	}
+
+	// owner or delegate (see registry.DelegationSet)
+	if _, _, err = r.VerifySignature(); err != nil {
+		f.broken(fr.FsckRoot, "invalid signature: %v", err)
+		return
+	}
+
+	fr.r = r
+}
+
+func (f *fsck) missing(key cipher.SHA256, fr FsckRoot) {
+
+	if _, ok := f.bad[key]; ok == false {
+		f.rep.Missing = append(f.rep.Missing, FsckMissing{key, fr})
+		f.bad[key] = struct{}{}
+	}
+
+}
+
+// pack of the Fsck reads objects from CXDS, it
+// reports missing and corrupted objects, because
+// the Refs load its nodes before walking
+type fsckPack struct {
+	reg *registry.Registry
+	f   *fsck
+	fr  FsckRoot
+
+	complete bool // no missing or corrupted objects found
+}
+
+func (f *fsckPack) Registry() *registry.Registry {
+	return f.reg
+}
+
+func (f *fsckPack) Get(key cipher.SHA256) (val []byte, err error) {
+
+	if _, ok := f.f.bad[key]; ok == true {
+		f.complete = false
+		return nil, errCorrupted
+	}
+
+	if val, _, err = f.f.db.CXDS().Get(key, 0); err == data.ErrNotFound {
+		f.f.missing(key, f.fr)
+		f.complete = false
+	}
+
+	return
+}
+
+func (f *fsckPack) Set(cipher.SHA256, []byte) error {
+	return ErrReadOnly
+}
+
+func (f *fsckPack) Add([]byte) (cipher.SHA256, error) {
+	return cipher.SHA256{}, ErrReadOnly
+}
+
+func (f *fsckPack) Degree() registry.Degree {
+	return skyobject.Degree
+}
+
+func (f *fsckPack) SetDegree(registry.Degree) error {
+	return ErrReadOnly
+}
+
+func (f *fsckPack) Flags() registry.Flags     { return 0 }
+func (f *fsckPack) AddFlags(registry.Flags)   {}
+func (f *fsckPack) ClearFlags(registry.Flags) {}
+
+// walk given Root; the walkFunc never called for
+// missing and corrupted objects, the walk reports
+// them and returns false if the Root is incomplete
+func (f *fsck) walk(
+	fr *fsckRoot,
+	walkFunc registry.WalkFunc,
+) (
+	complete bool,
+) {
+
+	if fr.r == nil {
+		return false // broken
+	}
+
+	var (
+		r    = fr.r
+		pack = &fsckPack{f: f, fr: fr.FsckRoot, complete: true}
+	)
+
+	var wf = func(key cipher.SHA256, depth int) (deepper bool, err error) {
+
+		if _, ok := f.bad[key]; ok == true {
+			pack.complete = false
+			return
+		}
+
+		if _, ok := f.stored[key]; ok == false {
+			f.missing(key, fr.FsckRoot)
+			pack.complete = false
+			return
+		}
+
+		return walkFunc(key, depth)
+	}
+
+	// the Root and the Registry (ignore deepper)
+
+	wf(r.Hash, 0)
+	wf(cipher.SHA256(r.Reg), 0)
+
+	if pack.complete == false {
+		return false
+	}
+
+	var ok bool
+	if pack.reg, ok = f.regs[r.Reg]; ok == false {
+
+		var val, _, err = f.db.CXDS().Get(cipher.SHA256(r.Reg), 0)
+		if err != nil {
+			f.broken(fr.FsckRoot, "%v", err)
+			return false
+		}
+
+		if pack.reg, err = registry.DecodeRegistry(val); err != nil {
+			f.broken(fr.FsckRoot, "decoding Registry: %v", err)
+			return false
+		}
+
+		f.regs[r.Reg] = pack.reg
+	}
+
+	var err = r.Walk(pack, wf)
+
+	if pack.complete == false {
+		return false // missing or corrupted
+	}
+
+	if err != nil {
+		f.broken(fr.FsckRoot, "walking: %v", err)
+		return false
+	}
+
+	return true
+}
+
+// recompute rcs walking all usable Root objects
+func (f *fsck) count() {
+
+	f.actual = make(map[cipher.SHA256]uint32, len(f.stored))
+
+	for _, fr := range f.roots {
+		f.walk(fr, func(key cipher.SHA256, _ int) (deepper bool, _ error) {
+			f.actual[key]++
+			return f.actual[key] == 1, nil // first look
+		})
+	}
+
+}
+
+// find out incomplete Root objects
+func (f *fsck) incomplete() {
+
+	f.inc = make(map[FsckRoot]struct{})
+
+	for _, fr := range f.roots {
+
+		if fr.r == nil {
+			f.inc[fr.FsckRoot] = struct{}{}
+			continue
+		}
+
+		if len(f.bad) == 0 {
+			continue // all objects are in place
+		}
+
+		// walk every Root separately, because they
+		// can share missing or corrupted objects
+
+		var seen = make(map[cipher.SHA256]struct{})
+
+		var complete = f.walk(fr,
+			func(key cipher.SHA256, _ int) (deepper bool, _ error) {
+				if _, ok := seen[key]; ok == true {
+					return
+				}
+				seen[key] = struct{}{}
+				return true, nil
+			})
+
+		if complete == false {
+			f.inc[fr.FsckRoot] = struct{}{}
+		}
+
+	}
+
+	for _, fr := range f.roots {
+		if _, ok := f.inc[fr.FsckRoot]; ok == true {
+			f.rep.Incomplete = append(f.rep.Incomplete, fr.FsckRoot)
+		}
+	}
+
+}
+
+// remove incomplete Root objects from IdxDB
+func (f *fsck) removeIncomplete() (err error) {
+
+	err = f.db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+
+		for _, fr := range f.rep.Incomplete {
+
+			var hs data.Heads
+			if hs, err = fs.Heads(fr.Pub); err != nil {
+				return
+			}
+
+			var rs data.Roots
+			if rs, err = hs.Roots(fr.Nonce); err != nil {
+				return
+			}
+
+			if err = rs.Del(fr.Seq); err != nil {
+				return
+			}
+
+		}
+
+		return
+	})
+
+	if err != nil {
+		return
+	}
+
+	f.rep.Removed = len(f.rep.Incomplete)
+
+	// exclude removed
+
+	var roots = f.roots[:0]
+
+	for _, fr := range f.roots {
+		if _, ok := f.inc[fr.FsckRoot]; ok == false {
+			roots = append(roots, fr)
+		}
+	}
+
+	f.roots = roots
+	return
+}
+
+// compare rcs, and fix them if the fix is true
+func (f *fsck) rcs(fix bool) (err error) {
+
+	for key, rc := range f.stored {
+
+		if _, ok := f.bad[key]; ok == true {
+			continue // corrupted
+		}
+
+		var want = f.actual[key]
+
+		if rc == want {
+			continue
+		}
+
+		if want == 0 {
+			f.rep.Orphaned = append(f.rep.Orphaned, key)
+		} else {
+			f.rep.WrongRCs = append(f.rep.WrongRCs, FsckRC{key, rc, want})
+		}
+
+		if fix == false {
+			continue
+		}
+
+		if _, err = f.db.CXDS().Inc(key, int(want)-int(rc)); err != nil {
+			return
+		}
+
+		f.rep.Fixed++
+	}
+
+	return
+}
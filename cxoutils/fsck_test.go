@@ -0,0 +1,207 @@
+package cxoutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+type Feed struct {
+	Posts registry.Refs `skyobject:"schema=test.Post"`
+}
+
+type Post struct {
+	Head string
+	Body string
+}
+
+func assertNil(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// create DB with n Root objects and return its configs
+// and last Root
+func testFsckDB(t *testing.T, n int) (conf *skyobject.Config, r *registry.Root) {
+	t.Helper()
+
+	var dir, err = ioutil.TempDir("", "cxo-fsck")
+	assertNil(t, err)
+
+	conf = skyobject.NewConfig()
+	conf.DataDir = dir
+
+	var c *skyobject.Container
+	if c, err = skyobject.NewContainer(conf); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var (
+		pk, sk = cipher.GenerateKeyPair()
+
+		reg = registry.NewRegistry(func(r *registry.Reg) {
+			r.Register("test.Post", Post{})
+			r.Register("test.Feed", Feed{})
+		})
+
+		up   *skyobject.Unpack
+		feed Feed
+	)
+
+	assertNil(t, c.AddFeed(pk))
+
+	up, err = c.Unpack(sk, reg)
+	assertNil(t, err)
+
+	var sch registry.Schema
+	sch, err = reg.SchemaByName("test.Feed")
+	assertNil(t, err)
+
+	r = new(registry.Root)
+	r.Pub = pk
+	r.Nonce = 9021
+	r.Refs = []registry.Dynamic{{Schema: sch.Reference()}}
+
+	for i := 0; i < n; i++ {
+
+		assertNil(t, feed.Posts.AppendValues(up, Post{
+			Head: fmt.Sprintf("Head #%d", i),
+			Body: fmt.Sprintf("Body #%d", i),
+		}))
+
+		assertNil(t, r.Refs[0].SetValue(up, &feed))
+		assertNil(t, c.Save(up, r))
+	}
+
+	return
+}
+
+func testFsck(t *testing.T, conf *skyobject.Config, fix bool) *FsckReport {
+	t.Helper()
+
+	var db, err = skyobject.OpenDB(conf)
+	assertNil(t, err)
+	defer db.Close()
+
+	var rep *FsckReport
+	rep, err = Fsck(db, fix)
+	assertNil(t, err)
+
+	return rep
+}
+
+func TestFsck(t *testing.T) {
+
+	var conf, r = testFsckDB(t, 5)
+	defer os.RemoveAll(conf.DataDir)
+
+	var rep = testFsck(t, conf, false)
+
+	if rep.OK() == false || rep.Roots != 5 || rep.Objects == 0 {
+		t.Fatalf("unexpected report %#v", rep)
+	}
+
+	// break the DB: remove a Post of the last Root, and
+	// change rc of the Registry
+
+	var db, err = skyobject.OpenDB(conf)
+	assertNil(t, err)
+
+	var val []byte
+	val, _, err = db.CXDS().Get(r.Hash, 0)
+	assertNil(t, err)
+
+	var dr *registry.Root
+	dr, err = registry.DecodeRoot(val)
+	assertNil(t, err)
+
+	var feed Feed
+	val, _, err = db.CXDS().Get(dr.Refs[0].Hash, 0)
+	assertNil(t, err)
+	assertNil(t, encoder.DeserializeRaw(val, &feed))
+
+	var last = feed.Posts.Hash // Refs with all Posts, used by last Root only
+	assertNil(t, db.CXDS().Del(last))
+
+	_, err = db.CXDS().Inc(cipher.SHA256(r.Reg), 10)
+	assertNil(t, err)
+
+	assertNil(t, db.Close())
+
+	rep = testFsck(t, conf, false)
+
+	if len(rep.Missing) != 1 || rep.Missing[0].Key != last {
+		t.Errorf("wrong missing objects %v", rep.Missing)
+	}
+
+	if len(rep.Incomplete) != 1 || rep.Incomplete[0].Seq != 4 {
+		t.Errorf("wrong incomplete Root objects %v", rep.Incomplete)
+	}
+
+	var regRC bool
+	for _, wr := range rep.WrongRCs {
+		if wr.Key == cipher.SHA256(r.Reg) {
+			regRC = wr.RC == 15 && wr.Want == 5
+		}
+	}
+
+	if regRC == false {
+		t.Errorf("wrong rc of Registry is not found %v", rep.WrongRCs)
+	}
+
+	// fix
+
+	rep = testFsck(t, conf, true)
+
+	if rep.Removed != 1 || rep.Fixed == 0 {
+		t.Errorf("not fixed %#v", rep)
+	}
+
+	if rep = testFsck(t, conf, false); rep.OK() == false || rep.Roots != 4 {
+		t.Errorf("unexpected report %#v", rep)
+	}
+
+	// broken chain
+
+	db, err = skyobject.OpenDB(conf)
+	assertNil(t, err)
+
+	err = db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+		var hs data.Heads
+		if hs, err = fs.Heads(r.Pub); err != nil {
+			return
+		}
+		var rs data.Roots
+		if rs, err = hs.Roots(r.Nonce); err != nil {
+			return
+		}
+		var dr *data.Root
+		if dr, err = rs.Get(2); err != nil {
+			return
+		}
+		if err = rs.Del(2); err != nil {
+			return
+		}
+		dr.Prev = cipher.SumSHA256([]byte("something else"))
+		return rs.Set(dr)
+	})
+	assertNil(t, err)
+	assertNil(t, db.Close())
+
+	if rep = testFsck(t, conf, false); len(rep.Broken) != 2 {
+		t.Errorf("wrong broken Root objects %v", rep.Broken)
+	}
+
+}
@@ -14,11 +14,28 @@ import (
 	"errors"
 
 	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
 )
 
 // Version of the CXDS API and data representation
 const Version int = 2 // previous is 1
 
+// names of drivers (see data.RegisterCXDS)
+const (
+	DriveDriver  string = "bolt"   // NewDriveCXDS
+	LSMDriver    string = "lsm"    // NewLSMCXDS
+	MemoryDriver string = "memory" // NewMemoryCXDS
+)
+
+func init() {
+	data.RegisterCXDS(DriveDriver, NewDriveCXDS)
+	data.RegisterCXDS(LSMDriver, NewLSMCXDS)
+	data.RegisterCXDS(MemoryDriver, func(string) (data.CXDS, error) {
+		return NewMemoryCXDS(), nil // the path ignored
+	})
+}
+
 // comon errors
 var (
 	ErrEmptyValue = errors.New("empty value")
@@ -27,6 +44,8 @@ var (
 
 	ErrMissingMetaInfo = errors.New("missing meta information")
 
+	ErrCorrupted = errors.New("corrupted data")
+
 	ErrMissingVersion = errors.New("missing version in meta")
 	ErrOldVersion     = errors.New("db file of old version")      // cxodbfix
 	ErrNewVersion     = errors.New("db file newer then this CXO") // go get
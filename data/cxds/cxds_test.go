@@ -8,7 +8,10 @@ import (
 	"github.com/skycoin/cxo/data/tests"
 )
 
-const testFileName = "test.db.go.ignore"
+const (
+	testFileName = "test.db.go.ignore"
+	testDirName  = "test.lsm.go.ignore"
+)
 
 func testShouldNotPanic(t *testing.T) {
 	if pc := recover(); pc != nil {
@@ -24,6 +27,14 @@ func testDriveDS(t *testing.T) (ds data.CXDS) {
 	return
 }
 
+func testLSMDS(t *testing.T) (ds data.CXDS) {
+	var err error
+	if ds, err = NewLSMCXDS(testDirName); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
 func TestNewDriveCXDS(t *testing.T) {
 	// NewDriveCXDS(filePath string) (ds *DriveCXDS, err error)
 
@@ -31,6 +42,14 @@ func TestNewDriveCXDS(t *testing.T) {
 	defer ds.Close()
 }
 
+func TestNewLSMCXDS(t *testing.T) {
+	// NewLSMCXDS(dir string) (ds data.CXDS, err error)
+
+	ds := testLSMDS(t)
+	defer os.RemoveAll(testDirName)
+	defer ds.Close()
+}
+
 func TestNewMemoryCXDS(t *testing.T) {
 	// NewMemoryCXDS() (ds *MemoryCXDS, err error)
 
@@ -51,6 +70,13 @@ func TestCXDS_Get(t *testing.T) {
 		defer ds.Close()
 		tests.CXDSGet(t, ds)
 	})
+
+	t.Run("lsm", func(t *testing.T) {
+		ds := testLSMDS(t)
+		defer os.RemoveAll(testDirName)
+		defer ds.Close()
+		tests.CXDSGet(t, ds)
+	})
 }
 
 func TestCXDS_Set(t *testing.T) {
@@ -66,6 +92,13 @@ func TestCXDS_Set(t *testing.T) {
 		defer ds.Close()
 		tests.CXDSSet(t, ds)
 	})
+
+	t.Run("lsm", func(t *testing.T) {
+		ds := testLSMDS(t)
+		defer os.RemoveAll(testDirName)
+		defer ds.Close()
+		tests.CXDSSet(t, ds)
+	})
 }
 
 func TestCXDS_Inc(t *testing.T) {
@@ -81,6 +114,35 @@ func TestCXDS_Inc(t *testing.T) {
 		defer ds.Close()
 		tests.CXDSInc(t, ds)
 	})
+
+	t.Run("lsm", func(t *testing.T) {
+		ds := testLSMDS(t)
+		defer os.RemoveAll(testDirName)
+		defer ds.Close()
+		tests.CXDSInc(t, ds)
+	})
+}
+
+func TestCXDS_Del(t *testing.T) {
+	// Del(key cipher.SHA256) (err error)
+
+	t.Run("memory", func(t *testing.T) {
+		tests.CXDSDel(t, NewMemoryCXDS())
+	})
+
+	t.Run("drive", func(t *testing.T) {
+		ds := testDriveDS(t)
+		defer os.Remove(testFileName)
+		defer ds.Close()
+		tests.CXDSDel(t, ds)
+	})
+
+	t.Run("lsm", func(t *testing.T) {
+		ds := testLSMDS(t)
+		defer os.RemoveAll(testDirName)
+		defer ds.Close()
+		tests.CXDSDel(t, ds)
+	})
 }
 
 func TestCXDS_Close(t *testing.T) {
@@ -96,4 +158,11 @@ func TestCXDS_Close(t *testing.T) {
 		defer ds.Close()
 		tests.CXDSClose(t, ds)
 	})
+
+	t.Run("lsm", func(t *testing.T) {
+		ds := testLSMDS(t)
+		defer os.RemoveAll(testDirName)
+		defer ds.Close()
+		tests.CXDSClose(t, ds)
+	})
 }
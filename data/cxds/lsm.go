@@ -0,0 +1,1474 @@
+package cxds
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+)
+
+// LSM CXDS is a directory
+//
+//     MANIFEST     version, stat and list of tables
+//     wal          write-ahead log of the memtable
+//     000001.sst   sorted tables
+//
+// All changes are written to the wal and to the memtable.
+// If volume of the memtable exceeds the lsmMemLimit, then
+// the memtable flushed to new table and the wal truncated.
+// Tables are never changed. Compaction merges newest tables
+// in background, if size of a table is not greater then
+// doubled total size of newer tables (size-tiered compaction).
+// Thus every table is more then twice bigger then all newer
+// tables together. E.g.
+// there are log(N) tables, and every object rewritten log(N)
+// times. Deleted objects are dropped when the oldest table
+// merged
+//
+// wal record
+//
+//     [ 1 op ][ 32 key ][ 4 rc ][ 4 len ][ value ][ 4 crc32 ]
+//
+// table
+//
+//     [ values ][ index ][ 8 index offset ][ 8 count ][ 4 crc32 ][ 4 magic ]
+//
+// index entry
+//
+//     [ 32 key ][ 1 deleted ][ 4 rc ][ 4 len ][ 8 offset of the value ]
+//
+// Indices of tables are kept in memory, thus a Get requires
+// one read from disk at most. There is not a file lock, and
+// the LSM CXDS must not be opened by many processes at the
+// same time
+
+// LSMVersion is version of the LSM CXDS
+const LSMVersion int = 1
+
+const (
+	lsmManifest = "MANIFEST"
+	lsmWAL      = "wal"
+	lsmTableExt = ".sst"
+
+	lsmMemLimit = 4 * 1024 * 1024 // flush the memtable after 4M
+
+	lsmManifestMagic = "CXLM"
+	lsmTableMagic    = "CXLT"
+
+	lsmWALHead   = 1 + 32 + 4 + 4 // op, key, rc, len
+	lsmIndexSize = 32 + 1 + 4 + 4 + 8
+	lsmFooter    = 8 + 8 + 4 + 4
+)
+
+// wal operations
+const (
+	lsmPut byte = 1 + iota // put value and rc
+	lsmDel                 // delete
+	lsmRC                  // change rc only
+)
+
+// entry of the memtable, the val is nil for deleted
+type lsmEntry struct {
+	rc  uint32
+	val []byte
+}
+
+// entry of index of a table
+type lsmIndexEntry struct {
+	key cipher.SHA256
+	del bool
+	rc  uint32
+	len uint32
+	off uint64
+}
+
+// a sorted table
+type lsmTable struct {
+	num  uint64          // number of the table
+	path string          // path to file
+	f    *os.File        // the file
+	size int64           // size of the file
+	idx  []lsmIndexEntry // sorted by key
+	refs int32           // references (atomic)
+	obs  int32           // obsolete, replaced by compaction (atomic)
+}
+
+// find entry by key
+func (t *lsmTable) find(key cipher.SHA256) (e *lsmIndexEntry, ok bool) {
+
+	var i = sort.Search(len(t.idx), func(i int) bool {
+		return bytes.Compare(t.idx[i].key[:], key[:]) >= 0
+	})
+
+	if i < len(t.idx) && t.idx[i].key == key {
+		return &t.idx[i], true
+	}
+
+	return
+}
+
+// read value of given entry
+func (t *lsmTable) value(e *lsmIndexEntry) (val []byte, err error) {
+	val = make([]byte, e.len)
+	_, err = t.f.ReadAt(val, int64(e.off))
+	return
+}
+
+func (t *lsmTable) incr() {
+	atomic.AddInt32(&t.refs, 1)
+}
+
+// close the table if it's not used anymore,
+// and remove it if it's obsolete
+func (t *lsmTable) release() {
+	if atomic.AddInt32(&t.refs, -1) == 0 {
+		t.f.Close()
+		if atomic.LoadInt32(&t.obs) == 1 {
+			os.Remove(t.path)
+		}
+	}
+}
+
+// mark the table as obsolete and release it
+func (t *lsmTable) drop() {
+	atomic.StoreInt32(&t.obs, 1)
+	t.release()
+}
+
+type lsmCXDS struct {
+	mx sync.RWMutex
+
+	dir string // directory
+
+	mem      map[cipher.SHA256]lsmEntry // memtable
+	memVol   int                        // volume of the memtable
+	memLimit int                        // flush the memtable after
+
+	wal    *os.File    // write-ahead log
+	tables []*lsmTable // oldest first
+	next   uint64      // next table number
+
+	amountAll  int
+	amountUsed int
+	volumeAll  int
+	volumeUsed int
+
+	// compaction
+	cmx    sync.Mutex    // one compaction at a time
+	cerr   error         // compaction failure
+	cq     chan struct{} // trigger compaction
+	quit   chan struct{} // stop compaction
+	done   chan struct{} // compaction goroutine done
+	closed bool
+}
+
+// NewLSMCXDS opens existing CXDS-database or
+// creates new in given directory. The database
+// is log-structured merge-tree, that designed
+// for write-heavy workloads and large volumes.
+// E.g. this stores data on disk
+func NewLSMCXDS(dir string) (ds data.CXDS, err error) {
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+
+	var l = &lsmCXDS{
+		dir:      dir,
+		mem:      make(map[cipher.SHA256]lsmEntry),
+		memLimit: lsmMemLimit,
+		next:     1,
+		cq:       make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if err = l.loadManifest(); err != nil {
+		l.releaseTables()
+		return
+	}
+
+	if err = l.replayWAL(); err != nil {
+		l.releaseTables()
+		return
+	}
+
+	l.removeOrphans()
+
+	go l.compaction()
+
+	ds = l
+	return
+}
+
+func (l *lsmCXDS) path(name string) string {
+	return filepath.Join(l.dir, name)
+}
+
+func (l *lsmCXDS) tablePath(num uint64) string {
+	return l.path(fmt.Sprintf("%06d%s", num, lsmTableExt))
+}
+
+func (l *lsmCXDS) releaseTables() {
+	for _, t := range l.tables {
+		t.release()
+	}
+	l.tables = nil
+}
+
+//
+// manifest
+//
+
+// [ 4 magic ][ 4 version ][ 8 next ]
+// [ 8 amount all ][ 8 amount used ][ 8 volume all ][ 8 volume used ]
+// [ 4 tables ][ 8 table number ]... [ 4 crc32 ]
+
+func (l *lsmCXDS) loadManifest() (err error) {
+
+	var mf []byte
+
+	if mf, err = ioutil.ReadFile(l.path(lsmManifest)); err != nil {
+		if os.IsNotExist(err) == true {
+			return l.saveManifest() // new
+		}
+		return
+	}
+
+	if len(mf) < 4+4+8+8*4+4+4 || string(mf[:4]) != lsmManifestMagic {
+		return ErrMissingMetaInfo
+	}
+
+	var body, sum = mf[:len(mf)-4], binary.BigEndian.Uint32(mf[len(mf)-4:])
+
+	if crc32.ChecksumIEEE(body) != sum {
+		return ErrCorrupted
+	}
+
+	switch vers := int(binary.BigEndian.Uint32(mf[4:])); {
+	case vers < LSMVersion:
+		return ErrOldVersion
+	case vers > LSMVersion:
+		return ErrNewVersion
+	}
+
+	var p = mf[8:]
+
+	l.next = binary.BigEndian.Uint64(p)
+	l.amountAll = int(binary.BigEndian.Uint64(p[8:]))
+	l.amountUsed = int(binary.BigEndian.Uint64(p[16:]))
+	l.volumeAll = int(binary.BigEndian.Uint64(p[24:]))
+	l.volumeUsed = int(binary.BigEndian.Uint64(p[32:]))
+
+	var n = int(binary.BigEndian.Uint32(p[40:]))
+
+	if p = p[44 : len(p)-4]; len(p) != n*8 {
+		return ErrCorrupted
+	}
+
+	for i := 0; i < n; i++ {
+
+		var t *lsmTable
+		if t, err = openLSMTable(l.tablePath(binary.BigEndian.Uint64(p[i*8:]))); err != nil {
+			return
+		}
+
+		l.tables = append(l.tables, t)
+	}
+
+	return
+}
+
+// write to temporary file and rename
+func (l *lsmCXDS) saveManifest() (err error) {
+
+	var mf = make([]byte, 0, 4+4+8+8*4+4+len(l.tables)*8+4)
+
+	mf = append(mf, lsmManifestMagic...)
+	mf = appendUint32(mf, uint32(LSMVersion))
+	mf = appendUint64(mf, l.next)
+	mf = appendUint64(mf, uint64(l.amountAll))
+	mf = appendUint64(mf, uint64(l.amountUsed))
+	mf = appendUint64(mf, uint64(l.volumeAll))
+	mf = appendUint64(mf, uint64(l.volumeUsed))
+	mf = appendUint32(mf, uint32(len(l.tables)))
+
+	for _, t := range l.tables {
+		mf = appendUint64(mf, t.num)
+	}
+
+	mf = appendUint32(mf, crc32.ChecksumIEEE(mf))
+
+	var tmp = l.path(lsmManifest + ".tmp")
+
+	if err = writeFileSync(tmp, mf); err != nil {
+		return
+	}
+
+	return os.Rename(tmp, l.path(lsmManifest))
+}
+
+// remove tables that are not in the manifest (crash
+// after creating a table, but before saving manifest)
+func (l *lsmCXDS) removeOrphans() {
+
+	var names, _ = filepath.Glob(l.path("*" + lsmTableExt))
+
+	var known = make(map[string]struct{}, len(l.tables))
+
+	for _, t := range l.tables {
+		known[t.path] = struct{}{}
+	}
+
+	for _, name := range names {
+		if _, ok := known[name]; ok == false {
+			os.Remove(name)
+		}
+	}
+
+	os.Remove(l.path(lsmManifest + ".tmp"))
+}
+
+//
+// wal
+//
+
+func (l *lsmCXDS) replayWAL() (err error) {
+
+	var f *os.File
+	f, err = os.OpenFile(l.path(lsmWAL), os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+		return
+	}
+
+	var (
+		r    = bufio.NewReader(f)
+		good int64 // end of last valid record
+		head = make([]byte, lsmWALHead)
+	)
+
+	for {
+
+		if _, err = io.ReadFull(r, head); err != nil {
+			break
+		}
+
+		var (
+			op  = head[0]
+			key cipher.SHA256
+			rc  = binary.BigEndian.Uint32(head[33:])
+			ln  = binary.BigEndian.Uint32(head[37:])
+			val []byte
+			sum = make([]byte, 4)
+		)
+
+		copy(key[:], head[1:33])
+
+		if ln > 0 {
+			val = make([]byte, ln)
+			if _, err = io.ReadFull(r, val); err != nil {
+				break
+			}
+		}
+
+		if _, err = io.ReadFull(r, sum); err != nil {
+			break
+		}
+
+		var crc = crc32.NewIEEE()
+		crc.Write(head)
+		crc.Write(val)
+
+		if crc.Sum32() != binary.BigEndian.Uint32(sum) {
+			break // torn write
+		}
+
+		if err = l.apply(op, key, rc, val); err != nil {
+			f.Close()
+			return
+		}
+
+		good += int64(lsmWALHead + len(val) + 4)
+	}
+
+	// drop torn tail of the wal if any
+
+	if err = f.Truncate(good); err != nil {
+		f.Close()
+		return
+	}
+
+	if _, err = f.Seek(good, io.SeekStart); err != nil {
+		f.Close()
+		return
+	}
+
+	l.wal = f
+	return
+}
+
+// apply a record of the wal
+func (l *lsmCXDS) apply(
+	op byte,
+	key cipher.SHA256,
+	rc uint32,
+	val []byte,
+) (
+	err error,
+) {
+
+	var (
+		prev lsmEntry
+		old  bool
+	)
+
+	if prev, old, err = l.lookup(key); err != nil {
+		return
+	}
+
+	switch op {
+	case lsmPut:
+		if old == true {
+			l.av(prev.rc, rc, len(val))
+		} else {
+			l.created(rc, len(val))
+		}
+		l.memPut(key, lsmEntry{rc, val})
+	case lsmRC:
+		if old == false {
+			return ErrCorrupted
+		}
+		l.av(prev.rc, rc, len(prev.val))
+		l.memPut(key, lsmEntry{rc, prev.val})
+	case lsmDel:
+		if old == true {
+			l.deleted(prev.rc, len(prev.val))
+		}
+		l.memPut(key, lsmEntry{})
+	default:
+		return ErrCorrupted
+	}
+
+	return
+}
+
+// write a record to the wal
+func (l *lsmCXDS) log(
+	op byte,
+	key cipher.SHA256,
+	rc uint32,
+	val []byte,
+) (
+	err error,
+) {
+
+	var rec = make([]byte, 0, lsmWALHead+len(val)+4)
+
+	rec = append(rec, op)
+	rec = append(rec, key[:]...)
+	rec = appendUint32(rec, rc)
+	rec = appendUint32(rec, uint32(len(val)))
+	rec = append(rec, val...)
+	rec = appendUint32(rec, crc32.ChecksumIEEE(rec))
+
+	_, err = l.wal.Write(rec)
+	return
+}
+
+//
+// stat (under lock)
+//
+
+func (l *lsmCXDS) av(rc, nrc uint32, vol int) {
+
+	if rc == 0 { // was dead
+		if nrc > 0 { // an be resurrected
+			l.amountUsed++
+			l.volumeUsed += vol
+		}
+		return // else -> as is
+	}
+
+	// rc > 0 (was alive)
+
+	if nrc == 0 { // and be killed
+		l.amountUsed--
+		l.volumeUsed -= vol
+	}
+
+}
+
+func (l *lsmCXDS) created(rc uint32, vol int) {
+
+	l.amountAll++
+	l.volumeAll += vol
+
+	if rc > 0 {
+		l.amountUsed++
+		l.volumeUsed += vol
+	}
+}
+
+func (l *lsmCXDS) deleted(rc uint32, vol int) {
+
+	if rc > 0 {
+		l.amountUsed--
+		l.volumeUsed -= vol
+	}
+
+	l.amountAll--
+	l.volumeAll -= vol
+}
+
+//
+// memtable and tables (under lock)
+//
+
+func (l *lsmCXDS) memPut(key cipher.SHA256, e lsmEntry) {
+
+	if prev, ok := l.mem[key]; ok == true {
+		l.memVol -= len(prev.val)
+	} else {
+		l.memVol += lsmIndexSize
+	}
+
+	l.mem[key] = e
+	l.memVol += len(e.val)
+}
+
+// lookup an object in the memtable and in the tables
+func (l *lsmCXDS) lookup(
+	key cipher.SHA256,
+) (
+	e lsmEntry,
+	ok bool,
+	err error,
+) {
+
+	if e, ok = l.mem[key]; ok == true {
+		ok = (e.val != nil) // deleted
+		return
+	}
+
+	for i := len(l.tables) - 1; i >= 0; i-- {
+
+		var t = l.tables[i]
+
+		var ie, found = t.find(key)
+
+		if found == false {
+			continue
+		}
+
+		if ie.del == true {
+			return // deleted
+		}
+
+		if e.val, err = t.value(ie); err != nil {
+			return
+		}
+
+		e.rc, ok = ie.rc, true
+		return
+	}
+
+	return // not found
+}
+
+// change rc of existing object
+func (l *lsmCXDS) incr(
+	key cipher.SHA256,
+	e lsmEntry,
+	inc int,
+) (
+	nrc uint32,
+	err error,
+) {
+
+	switch {
+	case inc == 0:
+		return e.rc, nil // no changes
+	case inc < 0:
+		inc = -inc // change the sign
+		if uinc := uint32(inc); uinc >= e.rc {
+			nrc = 0
+		} else {
+			nrc = e.rc - uinc
+		}
+	case inc > 0:
+		nrc = e.rc + uint32(inc)
+	}
+
+	if nrc == e.rc {
+		return // the same
+	}
+
+	if err = l.log(lsmRC, key, nrc, nil); err != nil {
+		return
+	}
+
+	l.av(e.rc, nrc, len(e.val))
+	l.memPut(key, lsmEntry{nrc, e.val})
+
+	err = l.flushIfNeed()
+	return
+}
+
+func (l *lsmCXDS) flushIfNeed() (err error) {
+
+	if l.memVol < l.memLimit {
+		return
+	}
+
+	return l.flush()
+}
+
+// write the memtable to new table
+func (l *lsmCXDS) flush() (err error) {
+
+	if len(l.mem) == 0 {
+		return
+	}
+
+	var keys = make([]cipher.SHA256, 0, len(l.mem))
+
+	for key := range l.mem {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+
+	var t *lsmTable
+
+	t, err = createLSMTable(l.tablePath(l.next),
+		func(add func(ie lsmIndexEntry, val []byte) error) (err error) {
+
+			for _, key := range keys {
+
+				var e = l.mem[key]
+
+				err = add(lsmIndexEntry{
+					key: key,
+					del: e.val == nil,
+					rc:  e.rc,
+				}, e.val)
+
+				if err != nil {
+					return
+				}
+
+			}
+
+			return
+		})
+
+	if err != nil {
+		return
+	}
+
+	l.next++
+	l.tables = append(l.tables, t)
+
+	if err = l.saveManifest(); err != nil {
+		return
+	}
+
+	// the memtable is saved, truncate the wal
+
+	if err = l.wal.Truncate(0); err != nil {
+		return
+	}
+
+	if _, err = l.wal.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	l.mem = make(map[cipher.SHA256]lsmEntry)
+	l.memVol = 0
+
+	select {
+	case l.cq <- struct{}{}: // trigger compaction
+	default:
+	}
+
+	return
+}
+
+//
+// compaction
+//
+
+func (l *lsmCXDS) compaction() {
+
+	defer close(l.done)
+
+	for {
+
+		select {
+		case <-l.cq:
+		case <-l.quit:
+			return
+		}
+
+		for {
+
+			var merged, err = l.compact()
+
+			if err != nil {
+				l.mx.Lock()
+				l.cerr = err
+				l.mx.Unlock()
+				return // stop compaction
+			}
+
+			if merged == false {
+				break
+			}
+
+		}
+
+	}
+
+}
+
+// select newest tables to merge; a table merged if its
+// size is not greater then doubled total size of newer
+// tables (the doubling is for merged tables that become
+// smaller because of removed and replaced objects)
+func (l *lsmCXDS) toCompact() (ts []*lsmTable, oldest bool) {
+
+	l.mx.RLock()
+	defer l.mx.RUnlock()
+
+	if len(l.tables) < 2 {
+		return
+	}
+
+	var (
+		i     = len(l.tables) - 1
+		total = l.tables[i].size
+	)
+
+	for i > 0 && l.tables[i-1].size <= 2*total {
+		i--
+		total += l.tables[i].size
+	}
+
+	if i == len(l.tables)-1 {
+		return // nothing to merge
+	}
+
+	ts = append(ts, l.tables[i:]...)
+
+	for _, t := range ts {
+		t.incr()
+	}
+
+	return ts, i == 0
+}
+
+func (l *lsmCXDS) compact() (merged bool, err error) {
+
+	l.cmx.Lock()
+	defer l.cmx.Unlock()
+
+	var ts, oldest = l.toCompact()
+
+	if len(ts) == 0 {
+		return
+	}
+
+	defer func() {
+		for _, t := range ts {
+			t.release()
+		}
+	}()
+
+	// the next table number reserved under lock
+
+	l.mx.Lock()
+	var num = l.next
+	l.next++
+	l.mx.Unlock()
+
+	var runs = make([]*lsmRun, 0, len(ts))
+
+	for i := len(ts) - 1; i >= 0; i-- {
+		runs = append(runs, &lsmRun{idx: ts[i].idx, t: ts[i]})
+	}
+
+	var t *lsmTable
+
+	t, err = createLSMTable(l.tablePath(num),
+		func(add func(ie lsmIndexEntry, val []byte) error) error {
+
+			return lsmMerge(runs, func(r *lsmRun, i int) (err error) {
+
+				var ie = r.idx[i]
+
+				if ie.del == true {
+					if oldest == true {
+						return // drop deleted
+					}
+					return add(ie, nil)
+				}
+
+				var val []byte
+				if val, err = r.value(i); err != nil {
+					return
+				}
+
+				return add(ie, val)
+			})
+
+		})
+
+	if err != nil {
+		return
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.closed == true {
+		t.drop()
+		return
+	}
+
+	// the ts are the newest tables, but new tables
+	// can be appended after the ts by flushing
+
+	var start = -1
+
+	for i, lt := range l.tables {
+		if lt == ts[0] {
+			start = i
+			break
+		}
+	}
+
+	if start < 0 {
+		t.drop()
+		return false, ErrCorrupted // never happens
+	}
+
+	var tables = make([]*lsmTable, 0, len(l.tables)-len(ts)+1)
+
+	tables = append(tables, l.tables[:start]...)
+	tables = append(tables, t)
+	tables = append(tables, l.tables[start+len(ts):]...)
+
+	var prev = l.tables
+	l.tables = tables
+
+	if err = l.saveManifest(); err != nil {
+		l.tables = prev
+		t.drop()
+		return
+	}
+
+	// references of the list
+	for _, t := range ts {
+		t.drop()
+	}
+
+	return true, nil
+}
+
+//
+// merging
+//
+
+// a sorted run of records: memtable or table
+type lsmRun struct {
+	idx []lsmIndexEntry
+	val [][]byte  // values of the memtable
+	t   *lsmTable // or table
+}
+
+func (r *lsmRun) value(i int) (val []byte, err error) {
+	if r.t == nil {
+		return r.val[i], nil
+	}
+	return r.t.value(&r.idx[i])
+}
+
+// lsmMerge calls given function for every key of given
+// runs in ascending order; the runs are newest first, and
+// for every key the newest record used, including deleted
+func lsmMerge(
+	runs []*lsmRun,
+	fn func(r *lsmRun, i int) error,
+) (
+	err error,
+) {
+
+	var pos = make([]int, len(runs))
+
+	for {
+
+		var (
+			min cipher.SHA256
+			mr  = -1 // run of the min
+		)
+
+		for ri, r := range runs {
+
+			if pos[ri] >= len(r.idx) {
+				continue
+			}
+
+			var key = r.idx[pos[ri]].key
+
+			if mr < 0 || bytes.Compare(key[:], min[:]) < 0 {
+				min, mr = key, ri
+			}
+
+		}
+
+		if mr < 0 {
+			return // done
+		}
+
+		if err = fn(runs[mr], pos[mr]); err != nil {
+			return
+		}
+
+		// skip the key in all runs (older records)
+
+		for ri, r := range runs {
+			if pos[ri] < len(r.idx) && r.idx[pos[ri]].key == min {
+				pos[ri]++
+			}
+		}
+
+	}
+
+}
+
+//
+// tables
+//
+
+// create table using given function to add records
+// in ascending order, the table opened for reading
+func createLSMTable(
+	path string,
+	write func(add func(ie lsmIndexEntry, val []byte) error) error,
+) (
+	t *lsmTable,
+	err error,
+) {
+
+	var f *os.File
+	if f, err = os.Create(path); err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(path)
+		}
+	}()
+
+	var (
+		w   = bufio.NewWriter(f)
+		off uint64
+		idx []lsmIndexEntry
+	)
+
+	err = write(func(ie lsmIndexEntry, val []byte) (err error) {
+
+		ie.len, ie.off = uint32(len(val)), off
+
+		if _, err = w.Write(val); err != nil {
+			return
+		}
+
+		off += uint64(len(val))
+		idx = append(idx, ie)
+		return
+	})
+
+	if err != nil {
+		return
+	}
+
+	var (
+		crc = crc32.NewIEEE()
+		buf = make([]byte, 0, lsmIndexSize)
+	)
+
+	for _, ie := range idx {
+
+		buf = append(buf[:0], ie.key[:]...)
+
+		if ie.del == true {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+
+		buf = appendUint32(buf, ie.rc)
+		buf = appendUint32(buf, ie.len)
+		buf = appendUint64(buf, ie.off)
+
+		crc.Write(buf)
+
+		if _, err = w.Write(buf); err != nil {
+			return
+		}
+
+	}
+
+	buf = appendUint64(buf[:0], off)
+	buf = appendUint64(buf, uint64(len(idx)))
+	buf = appendUint32(buf, crc.Sum32())
+	buf = append(buf, lsmTableMagic...)
+
+	if _, err = w.Write(buf); err != nil {
+		return
+	}
+
+	if err = w.Flush(); err != nil {
+		return
+	}
+
+	if err = f.Sync(); err != nil {
+		return
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	return openLSMTable(path)
+}
+
+func openLSMTable(path string) (t *lsmTable, err error) {
+
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+
+	var fi os.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		return
+	}
+
+	if fi.Size() < lsmFooter {
+		return nil, ErrCorrupted
+	}
+
+	var footer = make([]byte, lsmFooter)
+
+	if _, err = f.ReadAt(footer, fi.Size()-lsmFooter); err != nil {
+		return
+	}
+
+	if string(footer[20:]) != lsmTableMagic {
+		return nil, ErrCorrupted
+	}
+
+	var (
+		ioff  = binary.BigEndian.Uint64(footer)
+		count = binary.BigEndian.Uint64(footer[8:])
+		sum   = binary.BigEndian.Uint32(footer[16:])
+	)
+
+	if int64(ioff)+int64(count)*lsmIndexSize+lsmFooter != fi.Size() {
+		return nil, ErrCorrupted
+	}
+
+	var raw = make([]byte, count*lsmIndexSize)
+
+	if _, err = f.ReadAt(raw, int64(ioff)); err != nil {
+		return
+	}
+
+	if crc32.ChecksumIEEE(raw) != sum {
+		return nil, ErrCorrupted
+	}
+
+	t = &lsmTable{
+		path: path,
+		f:    f,
+		size: fi.Size(),
+		idx:  make([]lsmIndexEntry, count),
+		refs: 1, // the list
+	}
+
+	var name = strings.TrimSuffix(filepath.Base(path), lsmTableExt)
+
+	if _, err = fmt.Sscanf(name, "%d", &t.num); err != nil {
+		return nil, ErrCorrupted
+	}
+
+	for i := range t.idx {
+
+		var p, ie = raw[i*lsmIndexSize:], &t.idx[i]
+
+		copy(ie.key[:], p)
+		ie.del = p[32] == 1
+		ie.rc = binary.BigEndian.Uint32(p[33:])
+		ie.len = binary.BigEndian.Uint32(p[37:])
+		ie.off = binary.BigEndian.Uint64(p[41:])
+
+		if ie.off+uint64(ie.len) > ioff {
+			return nil, ErrCorrupted
+		}
+
+	}
+
+	return
+}
+
+//
+// data.CXDS
+//
+
+// Get value and change rc
+func (l *lsmCXDS) Get(
+	key cipher.SHA256,
+	inc int,
+) (
+	val []byte,
+	rc uint32,
+	err error,
+) {
+
+	if inc == 0 { // read only
+		l.mx.RLock()
+		defer l.mx.RUnlock()
+	} else { // read-write
+		l.mx.Lock()
+		defer l.mx.Unlock()
+	}
+
+	var (
+		e  lsmEntry
+		ok bool
+	)
+
+	if e, ok, err = l.lookup(key); err != nil {
+		return
+	}
+
+	if ok == false {
+		err = data.ErrNotFound
+		return
+	}
+
+	val = e.val
+	rc, err = l.incr(key, e, inc)
+	return
+}
+
+// Set value and change rc
+func (l *lsmCXDS) Set(
+	key cipher.SHA256,
+	val []byte,
+	inc int,
+) (
+	rc uint32,
+	err error,
+) {
+
+	if inc <= 0 {
+		panicf("invalid inc argument is Set: %d", inc)
+	}
+
+	if len(val) == 0 {
+		err = ErrEmptyValue
+		return
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	var (
+		e  lsmEntry
+		ok bool
+	)
+
+	if e, ok, err = l.lookup(key); err != nil {
+		return
+	}
+
+	if ok == true {
+		return l.incr(key, e, inc)
+	}
+
+	// created
+
+	rc = uint32(inc)
+	val = copySlice(val)
+
+	if err = l.log(lsmPut, key, rc, val); err != nil {
+		return
+	}
+
+	l.created(rc, len(val))
+	l.memPut(key, lsmEntry{rc, val})
+
+	err = l.flushIfNeed()
+	return
+}
+
+// Inc changes rc
+func (l *lsmCXDS) Inc(
+	key cipher.SHA256,
+	inc int,
+) (
+	rc uint32,
+	err error,
+) {
+
+	_, rc, err = l.Get(key, inc)
+	return
+}
+
+// Del deletes value unconditionally
+func (l *lsmCXDS) Del(key cipher.SHA256) (err error) {
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	var (
+		e  lsmEntry
+		ok bool
+	)
+
+	if e, ok, err = l.lookup(key); err != nil || ok == false {
+		return // not found or error
+	}
+
+	if err = l.log(lsmDel, key, 0, nil); err != nil {
+		return
+	}
+
+	l.deleted(e.rc, len(e.val))
+	l.memPut(key, lsmEntry{})
+
+	err = l.flushIfNeed()
+	return
+}
+
+// snapshot of the memtable and the tables, the
+// tables should be released after using
+func (l *lsmCXDS) snapshot() (runs []*lsmRun, ts []*lsmTable) {
+
+	l.mx.RLock()
+	defer l.mx.RUnlock()
+
+	var mr = &lsmRun{
+		idx: make([]lsmIndexEntry, 0, len(l.mem)),
+	}
+
+	for key, e := range l.mem {
+		mr.idx = append(mr.idx, lsmIndexEntry{
+			key: key,
+			del: e.val == nil,
+			rc:  e.rc,
+		})
+	}
+
+	sort.Slice(mr.idx, func(i, j int) bool {
+		return bytes.Compare(mr.idx[i].key[:], mr.idx[j].key[:]) < 0
+	})
+
+	mr.val = make([][]byte, len(mr.idx))
+
+	for i, ie := range mr.idx {
+		mr.val[i] = l.mem[ie.key].val
+	}
+
+	runs = append(runs, mr)
+
+	for i := len(l.tables) - 1; i >= 0; i-- {
+		var t = l.tables[i]
+		t.incr()
+		ts = append(ts, t)
+		runs = append(runs, &lsmRun{idx: t.idx, t: t})
+	}
+
+	return
+}
+
+// Iterate all keys in ascending order. The Iterate
+// walks through a snapshot of the CXDS, thus it's
+// possible to change the CXDS inside the iterateFunc
+func (l *lsmCXDS) Iterate(iterateFunc data.IterateObjectsFunc) (err error) {
+
+	var runs, ts = l.snapshot()
+
+	defer func() {
+		for _, t := range ts {
+			t.release()
+		}
+	}()
+
+	err = lsmMerge(runs, func(r *lsmRun, i int) (err error) {
+
+		var ie = &r.idx[i]
+
+		if ie.del == true {
+			return // deleted
+		}
+
+		var val []byte
+		if val, err = r.value(i); err != nil {
+			return
+		}
+
+		return iterateFunc(ie.key, ie.rc, val)
+	})
+
+	if err == data.ErrStopIteration {
+		err = nil
+	}
+
+	return
+}
+
+// IterateDel all keys deleting
+func (l *lsmCXDS) IterateDel(
+	iterateFunc data.IterateObjectsDelFunc,
+) (
+	err error,
+) {
+
+	return l.Iterate(func(
+		key cipher.SHA256,
+		rc uint32,
+		val []byte,
+	) (
+		err error,
+	) {
+
+		var del bool
+		if del, err = iterateFunc(key, rc, val); err != nil {
+			return
+		}
+
+		if del == true {
+			err = l.Del(key)
+		}
+
+		return
+	})
+
+}
+
+// Amount of objects
+func (l *lsmCXDS) Amount() (all, used int) {
+	l.mx.RLock()
+	defer l.mx.RUnlock()
+
+	return l.amountAll, l.amountUsed
+}
+
+// Volume of objects (only values)
+func (l *lsmCXDS) Volume() (all, used int) {
+	l.mx.RLock()
+	defer l.mx.RUnlock()
+
+	return l.volumeAll, l.volumeUsed
+}
+
+// Close DB flushing the memtable
+func (l *lsmCXDS) Close() (err error) {
+
+	l.mx.Lock()
+
+	if l.closed == true {
+		l.mx.Unlock()
+		return
+	}
+
+	l.closed = true
+	l.mx.Unlock()
+
+	close(l.quit)
+	<-l.done
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if err = l.flush(); err == nil {
+		err = l.saveManifest() // stat
+	}
+
+	if werr := l.wal.Close(); err == nil {
+		err = werr
+	}
+
+	l.releaseTables()
+	l.mem = nil
+
+	if err == nil {
+		err = l.cerr
+	}
+
+	return
+}
+
+//
+// helpers
+//
+
+func appendUint32(b []byte, u uint32) []byte {
+	var ub [4]byte
+	binary.BigEndian.PutUint32(ub[:], u)
+	return append(b, ub[:]...)
+}
+
+func appendUint64(b []byte, u uint64) []byte {
+	var ub [8]byte
+	binary.BigEndian.PutUint64(ub[:], u)
+	return append(b, ub[:]...)
+}
+
+func writeFileSync(path string, val []byte) (err error) {
+
+	var f *os.File
+	if f, err = os.Create(path); err != nil {
+		return
+	}
+
+	if _, err = f.Write(val); err != nil {
+		f.Close()
+		return
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,274 @@
+package cxds
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+)
+
+func testLSMValue(i int) (key cipher.SHA256, val []byte) {
+	val = []byte(fmt.Sprintf("value #%d %s", i, bytes.Repeat([]byte{'x'}, i%64)))
+	key = cipher.SumSHA256(val)
+	return
+}
+
+// fill the CXDS with n objects, every third object
+// has zero rc, and every fifth object removed
+func testLSMFill(t *testing.T, ds data.CXDS, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+
+		var key, val = testLSMValue(i)
+
+		if _, err := ds.Set(key, val, 2); err != nil {
+			t.Fatal(err)
+		}
+
+		if i%3 == 0 {
+			if _, err := ds.Inc(key, -2); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if i%5 == 0 {
+			if err := ds.Del(key); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+	}
+}
+
+func testLSMCheck(t *testing.T, ds data.CXDS, n int) {
+	t.Helper()
+
+	var (
+		amount, used int
+		volume, vu   int
+	)
+
+	for i := 0; i < n; i++ {
+
+		var key, val = testLSMValue(i)
+		var gval, rc, err = ds.Get(key, 0)
+
+		if i%5 == 0 {
+			if err != data.ErrNotFound {
+				t.Fatalf("removed object %d: %v", i, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("object %d: %v", i, err)
+		}
+
+		if bytes.Equal(gval, val) == false {
+			t.Fatalf("wrong value of object %d", i)
+		}
+
+		var want uint32 = 2
+		if i%3 == 0 {
+			want = 0
+		}
+
+		if rc != want {
+			t.Fatalf("wrong rc of object %d: %d, want %d", i, rc, want)
+		}
+
+		amount++
+		volume += len(val)
+
+		if rc > 0 {
+			used++
+			vu += len(val)
+		}
+
+	}
+
+	if all, u := ds.Amount(); all != amount || u != used {
+		t.Errorf("wrong amount %d/%d, want %d/%d", all, u, amount, used)
+	}
+
+	if all, u := ds.Volume(); all != volume || u != vu {
+		t.Errorf("wrong volume %d/%d, want %d/%d", all, u, volume, vu)
+	}
+
+	// iterate in ascending order
+
+	var (
+		prev  cipher.SHA256
+		count int
+	)
+
+	err := ds.Iterate(func(key cipher.SHA256, _ uint32, _ []byte) (_ error) {
+		if count > 0 && bytes.Compare(prev[:], key[:]) >= 0 {
+			t.Fatal("wrong order")
+		}
+		prev = key
+		count++
+		return
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count != amount {
+		t.Fatalf("wrong number of iterated objects %d, want %d", count, amount)
+	}
+
+}
+
+// close without flushing
+func testLSMCrash(l *lsmCXDS) {
+
+	l.mx.Lock()
+	l.closed = true
+	l.mx.Unlock()
+
+	close(l.quit)
+	<-l.done
+
+	l.wal.Close()
+	l.releaseTables()
+}
+
+func TestLSMCXDS_reopen(t *testing.T) {
+
+	defer os.RemoveAll(testDirName)
+
+	const n = 300
+
+	var ds = testLSMDS(t)
+	testLSMFill(t, ds, n)
+	testLSMCheck(t, ds, n)
+
+	// the memtable is not flushed, replay the wal
+
+	testLSMCrash(ds.(*lsmCXDS))
+
+	ds = testLSMDS(t)
+	testLSMCheck(t, ds, n)
+
+	// flushed by the Close
+
+	if err := ds.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ds = testLSMDS(t)
+	defer ds.Close()
+
+	testLSMCheck(t, ds, n)
+}
+
+func TestLSMCXDS_compaction(t *testing.T) {
+
+	defer os.RemoveAll(testDirName)
+
+	const n = 3000
+
+	var ds = testLSMDS(t)
+	var l = ds.(*lsmCXDS)
+
+	l.mx.Lock()
+	l.memLimit = 4096 // flush often
+	l.mx.Unlock()
+
+	testLSMFill(t, ds, n)
+	testLSMCheck(t, ds, n)
+
+	// wait for compaction
+
+	for {
+		var merged, err = l.compact()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merged == false {
+			break
+		}
+	}
+
+	l.mx.RLock()
+	var tables = len(l.tables)
+	l.mx.RUnlock()
+
+	// log(N) tables
+
+	if tables > 16 {
+		t.Error("too many tables:", tables)
+	}
+
+	testLSMCheck(t, ds, n)
+
+	if err := ds.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ds = testLSMDS(t)
+	defer ds.Close()
+
+	testLSMCheck(t, ds, n)
+
+	// obsolete tables are removed
+
+	var names, _ = filepath.Glob(filepath.Join(testDirName, "*"+lsmTableExt))
+
+	if l = ds.(*lsmCXDS); len(names) != len(l.tables) {
+		t.Errorf("wrong number of files %d, want %d", len(names), len(l.tables))
+	}
+}
+
+func TestLSMCXDS_tornWAL(t *testing.T) {
+
+	defer os.RemoveAll(testDirName)
+
+	var ds = testLSMDS(t)
+	testLSMFill(t, ds, 10)
+
+	var l = ds.(*lsmCXDS)
+
+	// write a half of a record
+
+	var key, val = testLSMValue(100)
+
+	if _, err := ds.Set(key, val, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var fi, err = l.wal.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = l.wal.Truncate(fi.Size() - 5); err != nil {
+		t.Fatal(err)
+	}
+
+	testLSMCrash(l)
+
+	ds = testLSMDS(t)
+	defer ds.Close()
+
+	testLSMCheck(t, ds, 10)
+
+	if _, _, err = ds.Get(key, 0); err != data.ErrNotFound {
+		t.Error("unexpected object of torn record:", err)
+	}
+
+	// the wal is usable
+
+	if _, err = ds.Set(key, val, 1); err != nil {
+		t.Fatal(err)
+	}
+
+}
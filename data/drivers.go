@@ -0,0 +1,138 @@
+package data
+
+import (
+	"sort"
+	"sync"
+)
+
+// An OpenCXDSFunc opens existing or creates new CXDS
+// using given path. The path is path to file or to
+// directory, it depends on implementation. An in-memory
+// implementation can ignore the path
+type OpenCXDSFunc func(path string) (CXDS, error)
+
+// An OpenIdxDBFunc opens existing or creates new IdxDB
+// using given path (see OpenCXDSFunc)
+type OpenIdxDBFunc func(path string) (IdxDB, error)
+
+// registered drivers
+var drivers = struct {
+	mx    sync.Mutex
+	cxds  map[string]OpenCXDSFunc
+	idxdb map[string]OpenIdxDBFunc
+}{
+	cxds:  make(map[string]OpenCXDSFunc),
+	idxdb: make(map[string]OpenIdxDBFunc),
+}
+
+// RegisterCXDS makes a CXDS driver available by given
+// name. The RegisterCXDS used by packages that implement
+// the CXDS, in their init functions. The data/cxds package
+// registers "bolt", "lsm" and "memory" drivers. The
+// RegisterCXDS panics if the name is empty, the open
+// function is nil or a driver with the same name
+// already registered
+func RegisterCXDS(name string, open OpenCXDSFunc) {
+
+	if name == "" {
+		panic("data.RegisterCXDS: empty name")
+	}
+
+	if open == nil {
+		panic("data.RegisterCXDS: nil open function of " + name)
+	}
+
+	drivers.mx.Lock()
+	defer drivers.mx.Unlock()
+
+	if _, ok := drivers.cxds[name]; ok == true {
+		panic("data.RegisterCXDS: register twice " + name)
+	}
+
+	drivers.cxds[name] = open
+}
+
+// RegisterIdxDB makes an IdxDB driver available by given
+// name. The data/idxdb package registers "bolt" and
+// "memory" drivers. See also RegisterCXDS
+func RegisterIdxDB(name string, open OpenIdxDBFunc) {
+
+	if name == "" {
+		panic("data.RegisterIdxDB: empty name")
+	}
+
+	if open == nil {
+		panic("data.RegisterIdxDB: nil open function of " + name)
+	}
+
+	drivers.mx.Lock()
+	defer drivers.mx.Unlock()
+
+	if _, ok := drivers.idxdb[name]; ok == true {
+		panic("data.RegisterIdxDB: register twice " + name)
+	}
+
+	drivers.idxdb[name] = open
+}
+
+// OpenCXDS opens CXDS using registered driver
+// with given name. It returns ErrNoSuchDriver
+// if the driver has not been registered
+func OpenCXDS(name, path string) (ds CXDS, err error) {
+
+	drivers.mx.Lock()
+	var open, ok = drivers.cxds[name]
+	drivers.mx.Unlock()
+
+	if ok == false {
+		return nil, ErrNoSuchDriver
+	}
+
+	return open(path)
+}
+
+// OpenIdxDB opens IdxDB using registered driver
+// with given name. It returns ErrNoSuchDriver
+// if the driver has not been registered
+func OpenIdxDB(name, path string) (idx IdxDB, err error) {
+
+	drivers.mx.Lock()
+	var open, ok = drivers.idxdb[name]
+	drivers.mx.Unlock()
+
+	if ok == false {
+		return nil, ErrNoSuchDriver
+	}
+
+	return open(path)
+}
+
+// CXDSDrivers returns sorted list of
+// names of registered CXDS drivers
+func CXDSDrivers() (names []string) {
+
+	drivers.mx.Lock()
+	defer drivers.mx.Unlock()
+
+	for name := range drivers.cxds {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// IdxDBDrivers returns sorted list of
+// names of registered IdxDB drivers
+func IdxDBDrivers() (names []string) {
+
+	drivers.mx.Lock()
+	defer drivers.mx.Unlock()
+
+	for name := range drivers.idxdb {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
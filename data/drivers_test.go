@@ -0,0 +1,59 @@
+package data
+
+import (
+	"testing"
+)
+
+func TestRegisterCXDS(t *testing.T) {
+
+	var open = func(string) (CXDS, error) { return nil, ErrNotFound }
+
+	RegisterCXDS("test", open)
+
+	if _, err := OpenCXDS("test", ""); err != ErrNotFound {
+		t.Error("wrong error", err)
+	}
+
+	if _, err := OpenCXDS("unknown", ""); err != ErrNoSuchDriver {
+		t.Error("wrong error", err)
+	}
+
+	if names := CXDSDrivers(); len(names) != 1 || names[0] != "test" {
+		t.Error("wrong list of drivers", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("missing panic")
+		}
+	}()
+
+	RegisterCXDS("test", open) // twice
+}
+
+func TestRegisterIdxDB(t *testing.T) {
+
+	var open = func(string) (IdxDB, error) { return nil, ErrNotFound }
+
+	RegisterIdxDB("test", open)
+
+	if _, err := OpenIdxDB("test", ""); err != ErrNotFound {
+		t.Error("wrong error", err)
+	}
+
+	if _, err := OpenIdxDB("unknown", ""); err != ErrNoSuchDriver {
+		t.Error("wrong error", err)
+	}
+
+	if names := IdxDBDrivers(); len(names) != 1 || names[0] != "test" {
+		t.Error("wrong list of drivers", names)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("missing panic")
+		}
+	}()
+
+	RegisterIdxDB("test", nil) // nil
+}
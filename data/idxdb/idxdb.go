@@ -3,11 +3,26 @@ package idxdb
 import (
 	"encoding/binary"
 	"errors"
+
+	"github.com/skycoin/cxo/data"
 )
 
 // Version of the IdxDB API and data representation
 const Version = 2 // previous is 0
 
+// names of drivers (see data.RegisterIdxDB)
+const (
+	DriveDriver  string = "bolt"   // NewDriveIdxDB
+	MemoryDriver string = "memory" // NewMemeoryDB
+)
+
+func init() {
+	data.RegisterIdxDB(DriveDriver, NewDriveIdxDB)
+	data.RegisterIdxDB(MemoryDriver, func(string) (data.IdxDB, error) {
+		return NewMemeoryDB(), nil // the path ignored
+	})
+}
+
 // common errors
 var (
 	ErrInvalidSize = errors.New("invalid size of encoded object")
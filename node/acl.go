@@ -0,0 +1,323 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// An Access represents peers allowed to do something
+// with a feed (see ACL). PeerID of a not encrypted
+// connection is not proved, and such peers are
+// allowed only if the Any is true
+type Access struct {
+	Any   bool            // any peer
+	Peers []cipher.PubKey // allowed peers, if not Any
+}
+
+// AnyPeer is Access that allows any peer
+var AnyPeer = Access{Any: true}
+
+// is given peer allowed
+func (a *Access) allows(id cipher.PubKey, proved bool) bool {
+
+	if a.Any == true {
+		return true
+	}
+
+	if proved == false {
+		return false
+	}
+
+	for _, pk := range a.Peers {
+		if pk == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String implements fmt.Stringer interface. The
+// String returns "any", "none" or hex-encoded
+// PeerIDs joined by "+"
+func (a Access) String() string {
+
+	if a.Any == true {
+		return "any"
+	}
+
+	if len(a.Peers) == 0 {
+		return "none"
+	}
+
+	var ps = make([]string, 0, len(a.Peers))
+
+	for _, pk := range a.Peers {
+		ps = append(ps, pk.Hex())
+	}
+
+	return strings.Join(ps, "+")
+}
+
+// ParseAccess parses Access from string
+// (see String method of the Access)
+func ParseAccess(s string) (a Access, err error) {
+
+	switch s {
+	case "any":
+		return AnyPeer, nil
+	case "none", "":
+		return
+	}
+
+	for _, hex := range strings.Split(s, "+") {
+		var pk cipher.PubKey
+		if pk, err = cipher.PubKeyFromHex(hex); err != nil {
+			return
+		}
+		a.Peers = append(a.Peers, pk)
+	}
+
+	return
+}
+
+// An ACL represents access control list of a feed.
+// The ACL defines which peers can subscribe to the
+// feed, which peers can preview it (see (*Conn).Preview)
+// and which peers can fetch objects of the feed. A
+// feed without ACL is open to any peer.
+//
+// Objects are not bound to feeds, since the same
+// object can belong to many feeds. Thus, a peer can
+// fetch objects only if it's subscribed to, or has
+// previewed, a feed that allows it to fetch. If the
+// Node has not ACLs at all, then any peer can fetch
+// objects
+type ACL struct {
+	Subscribe Access // who can subscribe to the feed
+	Preview   Access // who can preview the feed
+	Fetch     Access // who can fetch objects of the feed
+}
+
+// String implements fmt.Stringer interface. The
+// String returns the ACL in format of the ACLs
+// flag (without feed)
+func (a ACL) String() string {
+	return "subscribe=" + a.Subscribe.String() +
+		",preview=" + a.Preview.String() +
+		",fetch=" + a.Fetch.String()
+}
+
+// ParseACL parses ACL from string like
+// "subscribe=any,preview=none,fetch=pk1+pk2",
+// where the pk1 and pk2 are hex-encoded PeerIDs.
+// Omitted actions are not allowed to anyone
+func ParseACL(s string) (a ACL, err error) {
+
+	if s == "" {
+		return
+	}
+
+	for _, p := range strings.Split(s, ",") {
+
+		var kv = strings.SplitN(p, "=", 2)
+
+		if len(kv) != 2 {
+			return a, fmt.Errorf("invalid ACL entry %q", p)
+		}
+
+		switch kv[0] {
+		case "subscribe":
+			a.Subscribe, err = ParseAccess(kv[1])
+		case "preview":
+			a.Preview, err = ParseAccess(kv[1])
+		case "fetch":
+			a.Fetch, err = ParseAccess(kv[1])
+		default:
+			err = fmt.Errorf("unknown ACL action %q", kv[0])
+		}
+
+		if err != nil {
+			return
+		}
+
+	}
+
+	return
+}
+
+// ACLs is ACL per feed. The ACLs implements flag.Value
+// interface. Format of a flag is "feed:acl", where the
+// feed is hex-encoded public key and the acl is
+// described in the ParseACL function
+type ACLs map[cipher.PubKey]ACL
+
+// String implements flag.Value interface
+func (a *ACLs) String() string {
+
+	var ps []string
+
+	for pk, acl := range *a {
+		ps = append(ps, pk.Hex()+":"+acl.String())
+	}
+
+	return strings.Join(ps, " ")
+}
+
+// Set implements flag.Value interface
+func (a *ACLs) Set(s string) (err error) {
+
+	var fa = strings.SplitN(s, ":", 2)
+
+	if len(fa) != 2 {
+		return fmt.Errorf("invalid ACL %q, want feed:acl", s)
+	}
+
+	var pk cipher.PubKey
+	if pk, err = cipher.PubKeyFromHex(fa[0]); err != nil {
+		return
+	}
+
+	var acl ACL
+	if acl, err = ParseACL(fa[1]); err != nil {
+		return
+	}
+
+	if *a == nil {
+		*a = make(ACLs)
+	}
+
+	(*a)[pk] = acl
+	return
+}
+
+// actions of the ACL
+type aclAction int
+
+const (
+	aclSubscribe aclAction = iota
+	aclPreview
+	aclFetch
+)
+
+// ACLs of the Node
+type nodeACL struct {
+	mx   sync.Mutex
+	acls ACLs
+}
+
+func newNodeACL(acls ACLs) (a *nodeACL) {
+	a = new(nodeACL)
+	a.acls = make(ACLs, len(acls))
+	for pk, acl := range acls {
+		a.acls[pk] = acl
+	}
+	return
+}
+
+// is the action allowed for peer of given connection
+func (a *nodeACL) allowed(c *Conn, feed cipher.PubKey, act aclAction) bool {
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	var acl, ok = a.acls[feed]
+
+	if ok == false {
+		return true // open
+	}
+
+	var access *Access
+
+	switch act {
+	case aclSubscribe:
+		access = &acl.Subscribe
+	case aclPreview:
+		access = &acl.Preview
+	default:
+		access = &acl.Fetch
+	}
+
+	return access.allows(c.peerID, c.IsEncrypted())
+}
+
+// has not ACLs
+func (a *nodeACL) isEmpty() bool {
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	return len(a.acls) == 0
+}
+
+// can peer of given connection fetch objects, the
+// feeds are feeds the connection subscribed to or
+// previewed
+func (a *nodeACL) canFetch(c *Conn, feeds []cipher.PubKey) bool {
+
+	for _, pk := range feeds {
+		if a.allowed(c, pk, aclFetch) == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ACL returns ACL of given feed. The ok
+// is false if the feed has not ACL and
+// is open to any peer
+func (n *Node) ACL(feed cipher.PubKey) (acl ACL, ok bool) {
+
+	n.as.mx.Lock()
+	defer n.as.mx.Unlock()
+
+	acl, ok = n.as.acls[feed]
+	return
+}
+
+// SetACL sets ACL of given feed. The ACL is checked
+// when a peer subscribes to the feed, previews it,
+// or requests objects. Established subscriptions are
+// not affected. The SetACL doesn't change the Config
+// and changes are not saved
+func (n *Node) SetACL(feed cipher.PubKey, acl ACL) (err error) {
+
+	if feed == (cipher.PubKey{}) {
+		return ErrBlankFeed
+	}
+
+	n.as.mx.Lock()
+	defer n.as.mx.Unlock()
+
+	n.as.acls[feed] = acl
+	return
+}
+
+// DelACL removes ACL of given feed. The
+// feed is open to any peer after
+func (n *Node) DelACL(feed cipher.PubKey) {
+
+	n.as.mx.Lock()
+	defer n.as.mx.Unlock()
+
+	delete(n.as.acls, feed)
+}
+
+// ACLs returns copy of all ACLs set
+func (n *Node) ACLs() (acls ACLs) {
+
+	n.as.mx.Lock()
+	defer n.as.mx.Unlock()
+
+	acls = make(ACLs, len(n.as.acls))
+
+	for pk, acl := range n.as.acls {
+		acls[pk] = acl
+	}
+
+	return
+}
@@ -0,0 +1,138 @@
+package node
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestParseACL(t *testing.T) {
+
+	var (
+		pk1, _ = cipher.GenerateKeyPair()
+		pk2, _ = cipher.GenerateKeyPair()
+	)
+
+	var acl, err = ParseACL("subscribe=any,fetch=" + pk1.Hex() + "+" +
+		pk2.Hex())
+	assertNil(t, err)
+
+	assertTrue(t, acl.Subscribe.Any == true, "wrong Subscribe")
+	assertTrue(t, acl.Preview.Any == false && len(acl.Preview.Peers) == 0,
+		"wrong Preview")
+	assertTrue(t, len(acl.Fetch.Peers) == 2 && acl.Fetch.Peers[0] == pk1 &&
+		acl.Fetch.Peers[1] == pk2, "wrong Fetch")
+
+	var back ACL
+	back, err = ParseACL(acl.String())
+	assertNil(t, err)
+
+	assertTrue(t, back.String() == acl.String(), "wrong String")
+
+	for _, invalid := range []string{
+		"subscribe",
+		"read=any",
+		"fetch=xyz",
+	} {
+		if _, err = ParseACL(invalid); err == nil {
+			t.Error("missing error", invalid)
+		}
+	}
+
+}
+
+// find connection of the Node to given peer
+func getTestConnOfPeer(t *testing.T, n *Node, id cipher.PubKey) (c *Conn) {
+
+	t.Helper()
+
+	for _, c = range n.Connections() {
+		if c.PeerID() == id {
+			return
+		}
+	}
+
+	t.Fatal("no connection to peer")
+	return
+}
+
+func TestNode_SetACL(t *testing.T) {
+
+	var (
+		sn = getTestNode("server")
+		cn = getTestNodeNotListen("client")
+		on = getTestNodeNotListen("other")
+
+		pk, _ = cipher.GenerateKeyPair()
+	)
+
+	defer sn.Close()
+	defer cn.Close()
+	defer on.Close()
+
+	assertNil(t, sn.Share(pk))
+
+	assertNil(t, sn.SetACL(pk, ACL{
+		Subscribe: Access{Peers: []cipher.PubKey{cn.ID()}},
+		Preview:   Access{Peers: []cipher.PubKey{cn.ID()}},
+		Fetch:     Access{Peers: []cipher.PubKey{cn.ID()}},
+	}))
+
+	var cc, oc *Conn
+	var err error
+
+	cc, err = cn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	oc, err = on.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	waitTestConnections(t, sn, 2)
+
+	// subscribe
+
+	assertNil(t, cc.Subscribe(pk))
+
+	if err = oc.Subscribe(pk); err == nil {
+		t.Error("missing error")
+	} else if strings.Contains(err.Error(), ErrAccessDenied.Error()) == false {
+		t.Error("unexpected error", err)
+	}
+
+	// preview
+
+	err = oc.Preview(pk, func(registry.Pack, *registry.Root) bool {
+		return false
+	})
+
+	if err == nil {
+		t.Error("missing error")
+	} else if strings.Contains(err.Error(), ErrAccessDenied.Error()) == false {
+		t.Error("unexpected error", err)
+	}
+
+	// fetch
+
+	var (
+		scc = getTestConnOfPeer(t, sn, cn.ID())
+		soc = getTestConnOfPeer(t, sn, on.ID())
+	)
+
+	assertTrue(t, scc.canFetch() == true, "can't fetch")
+	assertTrue(t, soc.canFetch() == false, "can fetch")
+
+	if _, err = oc.getter().Get(cipher.SumSHA256([]byte("x"))); err == nil {
+		t.Error("missing error")
+	}
+
+	// open
+
+	sn.DelACL(pk)
+
+	assertTrue(t, soc.canFetch() == true, "can't fetch")
+	assertNil(t, oc.Subscribe(pk))
+
+}
@@ -0,0 +1,501 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// errors of bans
+var (
+	ErrBlankBan  = errors.New("blank peer id and ip")
+	ErrNotBanned = errors.New("not banned")
+)
+
+// A Violation represents misbehavior of a peer.
+// Every Violation has its penalty. If total
+// penalty of a peer (its score) for the ScoreTime
+// reaches the BanScore, then the peer is banned
+// (see Config)
+type Violation int
+
+// violations
+const (
+	ViolationProtocol Violation = iota // invalid messege
+	ViolationRoot                      // invalid Root (signature, encoding)
+	ViolationObject                    // object doesn't match requested key
+	ViolationTimeout                   // a request timed out
+	ViolationFlood                     // too many Root objects
+)
+
+// String implements fmt.Stringer interface
+func (v Violation) String() string {
+	switch v {
+	case ViolationProtocol:
+		return "protocol"
+	case ViolationRoot:
+		return "invalid Root"
+	case ViolationObject:
+		return "invalid object"
+	case ViolationTimeout:
+		return "timeout"
+	case ViolationFlood:
+		return "flood"
+	}
+	return fmt.Sprintf("Violation<%d>", v)
+}
+
+// Penalty of the Violation
+func (v Violation) Penalty() int {
+	switch v {
+	case ViolationProtocol:
+		return 100
+	case ViolationRoot, ViolationObject:
+		return 50
+	case ViolationFlood:
+		return 25
+	case ViolationTimeout:
+		return 2
+	}
+	return 0
+}
+
+// A Ban represents banned peer. A Ban can have
+// PeerID and IP address both, or one of them
+type Ban struct {
+	PeerID cipher.PubKey // banned PeerID or blank
+	IP     string        // banned IP address or empty
+	Reason string        // reason of the ban
+	Until  time.Time     // end of the ban, zero for permanent
+}
+
+// IsPermanent returns true if the Ban never expires
+func (b *Ban) IsPermanent() bool {
+	return b.Until.IsZero()
+}
+
+func (b *Ban) isExpired(now time.Time) bool {
+	return b.IsPermanent() == false && now.After(b.Until)
+}
+
+// bans file format
+type banEntry struct {
+	PeerID string    `json:"peer_id,omitempty"`
+	IP     string    `json:"ip,omitempty"`
+	Reason string    `json:"reason"`
+	Until  time.Time `json:"until"`
+}
+
+type violationEntry struct {
+	at      time.Time
+	penalty int
+}
+
+// bans and scores of peers
+type nodeBans struct {
+	n *Node
+
+	mx   sync.Mutex
+	path string // empty for in-memory
+
+	ids map[cipher.PubKey]*Ban // banned PeerIDs
+	ips map[string]*Ban        // banned IPs
+
+	scores map[cipher.PubKey][]violationEntry // not expired violations
+}
+
+func newNodeBans(n *Node, path string) (b *nodeBans) {
+	b = new(nodeBans)
+	b.n = n
+	b.path = path
+	b.ids = make(map[cipher.PubKey]*Ban)
+	b.ips = make(map[string]*Ban)
+	b.scores = make(map[cipher.PubKey][]violationEntry)
+	return
+}
+
+// IP address of the Conn
+func connIP(c *Conn) (ip string) {
+	var err error
+	if ip, _, err = net.SplitHostPort(c.Address()); err != nil {
+		return c.Address()
+	}
+	return
+}
+
+// load bans from file
+func (b *nodeBans) load() (err error) {
+
+	if b.path == "" {
+		return // in-memory
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(b.path); err != nil {
+		if os.IsNotExist(err) == true {
+			err = nil // no bans yet
+		}
+		return
+	}
+
+	var ents []banEntry
+	if err = json.Unmarshal(data, &ents); err != nil {
+		return
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	for _, ent := range ents {
+
+		var ban = &Ban{IP: ent.IP, Reason: ent.Reason, Until: ent.Until}
+
+		if ent.PeerID != "" {
+			if ban.PeerID, err = cipher.PubKeyFromHex(ent.PeerID); err != nil {
+				return
+			}
+		}
+
+		b.add(ban)
+	}
+
+	return
+}
+
+// unique bans, call under lock
+func (b *nodeBans) list() (bans []*Ban) {
+
+	var seen = make(map[*Ban]struct{}, len(b.ids)+len(b.ips))
+
+	for _, ban := range b.ids {
+		seen[ban] = struct{}{}
+	}
+
+	for _, ban := range b.ips {
+		seen[ban] = struct{}{}
+	}
+
+	bans = make([]*Ban, 0, len(seen))
+
+	for ban := range seen {
+		bans = append(bans, ban)
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].PeerID != bans[j].PeerID {
+			return bans[i].PeerID.Hex() < bans[j].PeerID.Hex()
+		}
+		return bans[i].IP < bans[j].IP
+	})
+
+	return
+}
+
+// save bans, call under lock
+func (b *nodeBans) save() (err error) {
+
+	if b.path == "" {
+		return // in-memory
+	}
+
+	var (
+		bans = b.list()
+		ents = make([]banEntry, 0, len(bans))
+	)
+
+	for _, ban := range bans {
+
+		var ent = banEntry{IP: ban.IP, Reason: ban.Reason, Until: ban.Until}
+
+		if ban.PeerID != (cipher.PubKey{}) {
+			ent.PeerID = ban.PeerID.Hex()
+		}
+
+		ents = append(ents, ent)
+	}
+
+	var data []byte
+	if data, err = json.MarshalIndent(ents, "", "  "); err != nil {
+		return
+	}
+
+	// write to temporary file and rename after
+
+	var tmp = b.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+
+	if err = os.Rename(tmp, b.path); err != nil {
+		os.Remove(tmp)
+	}
+
+	return
+}
+
+// add a ban, call under lock
+func (b *nodeBans) add(ban *Ban) {
+
+	if ban.PeerID != (cipher.PubKey{}) {
+		b.ids[ban.PeerID] = ban
+	}
+
+	if ban.IP != "" {
+		b.ips[ban.IP] = ban
+	}
+
+}
+
+// remove a ban, call under lock
+func (b *nodeBans) del(ban *Ban) {
+
+	if b.ids[ban.PeerID] == ban {
+		delete(b.ids, ban.PeerID)
+	}
+
+	if b.ips[ban.IP] == ban {
+		delete(b.ips, ban.IP)
+	}
+
+}
+
+// find active ban of given PeerID or IP,
+// call under lock
+func (b *nodeBans) find(id cipher.PubKey, ip string) (ban *Ban) {
+
+	var now = time.Now()
+
+	for _, ban = range []*Ban{b.ids[id], b.ips[ip]} {
+
+		if ban == nil {
+			continue
+		}
+
+		if ban.isExpired(now) == true {
+			b.del(ban)
+			if err := b.save(); err != nil {
+				b.n.Printf("[ERR] can't save bans: %v", err)
+			}
+			continue
+		}
+
+		return
+	}
+
+	return nil
+}
+
+// is given IP banned
+func (b *nodeBans) isBannedIP(ip string) (yep bool) {
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	return b.find(cipher.PubKey{}, ip) != nil
+}
+
+// check established connection, the PeerID of
+// a not encrypted connection is ignored, since
+// it can be spoofed
+func (b *nodeBans) check(c *Conn) (err error) {
+
+	var id cipher.PubKey
+
+	if c.IsEncrypted() == true {
+		id = c.peerID
+	}
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	if b.find(id, connIP(c)) != nil {
+		err = ErrBanned
+	}
+
+	return
+}
+
+// record a violation of the peer of given connection;
+// the violation method closes connection of the peer
+// if the peer is banned by the violation
+func (b *nodeBans) violation(c *Conn, v Violation, reason error) {
+
+	var conf = b.n.config
+
+	b.n.Debugf(BanPin, "[%s] violation %s: %v", c.String(), v, reason)
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	var (
+		now   = time.Now()
+		vs    = b.scores[c.peerID]
+		score = v.Penalty()
+		i     int
+	)
+
+	// forget old violations
+
+	for _, ve := range vs {
+
+		if conf.ScoreTime > 0 && now.Sub(ve.at) > conf.ScoreTime {
+			continue
+		}
+
+		vs[i] = ve
+		score += ve.penalty
+		i++
+	}
+
+	b.scores[c.peerID] = append(vs[:i], violationEntry{now, v.Penalty()})
+
+	if conf.BanScore == 0 || score < conf.BanScore {
+		return
+	}
+
+	// ban
+
+	var ban = &Ban{
+		IP:     connIP(c),
+		Reason: fmt.Sprintf("%s: %v", v, reason),
+		Until:  now.Add(conf.BanTime),
+	}
+
+	if c.IsEncrypted() == true {
+		ban.PeerID = c.peerID // proved
+	}
+
+	b.add(ban)
+	delete(b.scores, c.peerID)
+
+	if err := b.save(); err != nil {
+		b.n.Printf("[ERR] can't save bans: %v", err)
+	}
+
+	b.n.Printf("[%s] banned until %s: %s", c.String(),
+		ban.Until.Format(time.Stamp), ban.Reason)
+
+	go b.n.closeBanned(ban)
+}
+
+// close connections of given Ban
+func (n *Node) closeBanned(ban *Ban) {
+
+	for _, c := range n.Connections() {
+
+		var byID = c.IsEncrypted() == true && ban.PeerID != (cipher.PubKey{}) &&
+			c.peerID == ban.PeerID
+
+		if byID == true || (ban.IP != "" && connIP(c) == ban.IP) {
+			c.close(ErrBanned)
+		}
+
+	}
+
+}
+
+// Ban given peer by PeerID and (or) by IP address.
+// If given duration is zero, then the ban is
+// permanent. The Ban closes connections of the
+// peer and the Node rejects the peer until the
+// ban expires. Bans are saved near the DB (see
+// BanScore of the Config)
+func (n *Node) Ban(
+	id cipher.PubKey, // : PeerID or blank
+	ip string, //        : IP address or empty
+	d time.Duration, //  : duration, zero for permanent
+	reason string, //    : reason of the ban
+) (
+	err error, //        : an error
+) {
+
+	if id == (cipher.PubKey{}) && ip == "" {
+		return ErrBlankBan
+	}
+
+	var ban = &Ban{PeerID: id, IP: ip, Reason: reason}
+
+	if d > 0 {
+		ban.Until = time.Now().Add(d)
+	}
+
+	n.bs.mx.Lock()
+	n.bs.add(ban)
+	err = n.bs.save()
+	n.bs.mx.Unlock()
+
+	n.closeBanned(ban)
+	return
+}
+
+// Unban removes bans of given PeerID and (or) given
+// IP address. It returns ErrNotBanned if there are
+// not such bans
+func (n *Node) Unban(id cipher.PubKey, ip string) (err error) {
+
+	n.bs.mx.Lock()
+	defer n.bs.mx.Unlock()
+
+	var found bool
+
+	if ban, ok := n.bs.ids[id]; ok == true && id != (cipher.PubKey{}) {
+		n.bs.del(ban)
+		found = true
+	}
+
+	if ban, ok := n.bs.ips[ip]; ok == true && ip != "" {
+		n.bs.del(ban)
+		found = true
+	}
+
+	if found == false {
+		return ErrNotBanned
+	}
+
+	delete(n.bs.scores, id)
+	return n.bs.save()
+}
+
+// Bans returns list of active bans
+func (n *Node) Bans() (bans []Ban) {
+
+	n.bs.mx.Lock()
+	defer n.bs.mx.Unlock()
+
+	var now = time.Now()
+
+	for _, ban := range n.bs.list() {
+		if ban.isExpired(now) == false {
+			bans = append(bans, *ban)
+		}
+	}
+
+	return
+}
+
+// Score returns current score of violations
+// of a peer with given PeerID (see Violation)
+func (n *Node) Score(id cipher.PubKey) (score int) {
+
+	n.bs.mx.Lock()
+	defer n.bs.mx.Unlock()
+
+	var now = time.Now()
+
+	for _, ve := range n.bs.scores[id] {
+		if n.config.ScoreTime == 0 || now.Sub(ve.at) <= n.config.ScoreTime {
+			score += ve.penalty
+		}
+	}
+
+	return
+}
@@ -0,0 +1,159 @@
+package node
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// wait for connections of the Node
+func waitTestConnections(t *testing.T, n *Node, want int) {
+
+	t.Helper()
+
+	var deadline = time.Now().Add(10 * TM)
+
+	for time.Now().Before(deadline) {
+		if len(n.Connections()) == want {
+			return
+		}
+		time.Sleep(TM / 50)
+	}
+
+	t.Fatal("wrong number of connections")
+}
+
+func TestNode_Ban(t *testing.T) {
+
+	var (
+		sn = getTestNode("server")
+		cn = getTestNodeNotListen("client")
+	)
+
+	defer sn.Close()
+	defer cn.Close()
+
+	var dir, err = ioutil.TempDir("", "cxo-bans")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	sn.bs.path = filepath.Join(dir, "bans.json")
+
+	_, err = cn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	waitTestConnections(t, sn, 1)
+
+	assertNil(t, sn.Ban(cn.ID(), "", 0, "test"))
+
+	waitTestConnections(t, sn, 0)
+	waitTestConnections(t, cn, 0)
+
+	if _, err = cn.TCP().Connect(sn.TCP().Address()); err == nil {
+		t.Error("banned peer connected")
+	}
+
+	var bans = sn.Bans()
+
+	assertTrue(t, len(bans) == 1, "wrong number of bans")
+	assertTrue(t, bans[0].PeerID == cn.ID(), "wrong PeerID")
+	assertTrue(t, bans[0].IsPermanent() == true, "not permanent")
+
+	// restore
+
+	var rn = getTestNodeNotListen("restored")
+	defer rn.Close()
+
+	rn.bs.path = sn.bs.path
+	assertNil(t, rn.bs.load())
+
+	bans = rn.Bans()
+	assertTrue(t, len(bans) == 1 && bans[0].PeerID == cn.ID(), "not restored")
+
+	// unban
+
+	assertNil(t, sn.Unban(cn.ID(), ""))
+	assertTrue(t, len(sn.Bans()) == 0, "not unbanned")
+
+	if err = sn.Unban(cn.ID(), ""); err != ErrNotBanned {
+		t.Error("unexpected error", err)
+	}
+
+	_, err = cn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+}
+
+func TestNode_violation(t *testing.T) {
+
+	var (
+		conf = getTestConfig("server")
+		cn   = getTestNodeNotListen("client")
+	)
+
+	conf.BanScore = 100
+	conf.MaxRootsPerSecond = 2
+
+	var sn, err = NewNode(conf)
+	assertNil(t, err)
+
+	defer sn.Close()
+	defer cn.Close()
+
+	_, err = cn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	waitTestConnections(t, sn, 1)
+
+	var c = sn.Connections()[0]
+
+	// flood
+
+	for i := 0; i < 2; i++ {
+		assertTrue(t, c.isFlood() == false, "unexpected flood")
+	}
+
+	assertTrue(t, c.isFlood() == true, "missing flood")
+	assertTrue(t, c.isFlood() == true, "missing flood")
+
+	var score = ViolationFlood.Penalty()
+	assertTrue(t, sn.Score(cn.ID()) == score, "wrong score")
+
+	// ban
+
+	sn.bs.violation(c, ViolationRoot, errors.New("invalid Root"))
+	assertTrue(t, sn.Score(cn.ID()) == score+ViolationRoot.Penalty(),
+		"wrong score")
+
+	sn.bs.violation(c, ViolationRoot, errors.New("invalid Root"))
+
+	waitTestConnections(t, sn, 0)
+	waitTestConnections(t, cn, 0)
+
+	var bans = sn.Bans()
+
+	assertTrue(t, len(bans) == 1, "wrong number of bans")
+	assertTrue(t, bans[0].PeerID == cn.ID(), "wrong PeerID")
+	assertTrue(t, bans[0].IP == "127.0.0.1", "wrong IP")
+	assertTrue(t, bans[0].IsPermanent() == false, "permanent")
+
+	// banned by IP too
+
+	var on = getTestNodeNotListen("other")
+	defer on.Close()
+
+	if _, err = on.TCP().Connect(sn.TCP().Address()); err == nil {
+		t.Error("banned IP connected")
+	}
+
+	assertNil(t, sn.Unban(cipher.PubKey{}, "127.0.0.1"))
+
+	_, err = on.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+}
@@ -0,0 +1,151 @@
+package node
+
+import (
+	"fmt"
+
+	"github.com/skycoin/cxo/node/msg"
+)
+
+// capabilities
+//
+// The initiator of a handshake sends range of protocol
+// versions and list of capabilities it supports in the
+// Syn. Accepting side chooses greatest version both
+// sides support, and capabilities both sides support,
+// and sends them back in the Ack. A Conn sends messeges
+// of a capability only if the capability has been
+// negotiated, and messeges of a capability that has
+// not been negotiated are protocol violations. Thus,
+// new features can be rolled out without breaking
+// old peers. Peers of the msg.LegacyVersion have not
+// capabilities
+
+// capabilities of the Node
+func (n *Node) capabilities() (caps []string) {
+
+	caps = make([]string, 0, len(msg.Caps))
+
+	for _, cp := range msg.Caps {
+		if cp == msg.CapCompression && n.config.Compression == false {
+			continue
+		}
+		caps = append(caps, cp)
+	}
+
+	return
+}
+
+// has given list given capability
+func hasCap(caps []string, cp string) bool {
+	for _, c := range caps {
+		if c == cp {
+			return true
+		}
+	}
+	return false
+}
+
+// copy of given list without given capability
+func withoutCap(caps []string, cp string) (wc []string) {
+	for _, c := range caps {
+		if c != cp {
+			wc = append(wc, c)
+		}
+	}
+	return
+}
+
+// negotiate protocol version and capabilities
+// for given Syn (accepting side of handshake)
+func (n *Node) negotiate(
+	syn *msg.Syn, //          : the Syn
+) (
+	protocol uint16, //       : negotiated version
+	caps []string, //         : negotiated capabilities
+	err error, //             : incompatible versions
+) {
+
+	if syn.Protocol < msg.MinVersion || syn.MinProtocol > msg.Version ||
+		syn.MinProtocol > syn.Protocol {
+
+		err = fmt.Errorf("incompatible protocol versions: %d-%d, want %d-%d",
+			syn.MinProtocol, syn.Protocol, msg.MinVersion, msg.Version)
+		return
+	}
+
+	if protocol = syn.Protocol; protocol > msg.Version {
+		protocol = msg.Version
+	}
+
+	var own = n.capabilities()
+
+	for _, cp := range syn.Caps {
+		if hasCap(own, cp) == true && hasCap(caps, cp) == false {
+			caps = append(caps, cp)
+		}
+	}
+
+	return
+}
+
+// check protocol version and capabilities chosen
+// by peer (initiating side of handshake)
+func checkNegotiated(syn *msg.Syn, ack *msg.Ack) (err error) {
+
+	if ack.Protocol < msg.MinVersion || ack.Protocol > syn.Protocol {
+		return fmt.Errorf("protocol version %d chosen by peer is not"+
+			" supported", ack.Protocol)
+	}
+
+	for _, cp := range ack.Caps {
+		if hasCap(syn.Caps, cp) == false {
+			return fmt.Errorf("capability %q chosen by peer is not supported",
+				cp)
+		}
+	}
+
+	if ack.Codec == "" {
+		return
+	}
+
+	if hasCap(ack.Caps, msg.CapCompression) == false {
+		return fmt.Errorf("codec %q chosen by peer, but compression"+
+			" is not negotiated", ack.Codec)
+	}
+
+	if _, ok := codecs[ack.Codec]; ok == false {
+		return fmt.Errorf("unsupported codec %q chosen by peer", ack.Codec)
+	}
+
+	return
+}
+
+// check capability of given messege
+func (c *Conn) checkCap(m msg.Msg) (err error) {
+
+	if cp := msg.CapOf(m.Type()); cp != "" && c.HasCap(cp) == false {
+		err = fmt.Errorf("capability %q of messege %T is not negotiated",
+			cp, m)
+	}
+
+	return
+}
+
+// Protocol returns protocol version negotiated
+// during handshake
+func (c *Conn) Protocol() uint16 {
+	return c.protocol
+}
+
+// Caps returns capabilities negotiated during
+// handshake (see msg.Caps). Peers of legacy
+// protocol have not capabilities
+func (c *Conn) Caps() (caps []string) {
+	return append(caps, c.caps...)
+}
+
+// HasCap returns true if given capability has
+// been negotiated during handshake
+func (c *Conn) HasCap(cp string) bool {
+	return hasCap(c.caps, cp)
+}
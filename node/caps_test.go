@@ -0,0 +1,118 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/skycoin/cxo/node/msg"
+)
+
+func TestNode_negotiate(t *testing.T) {
+
+	var n = getTestNodeNotListen("test")
+	defer n.Close()
+
+	// newer peer
+
+	var protocol, caps, err = n.negotiate(&msg.Syn{
+		Protocol:    msg.Version + 2,
+		MinProtocol: msg.MinVersion,
+		Caps:        []string{"teleport", msg.CapHistory, msg.CapBatch},
+	})
+	assertNil(t, err)
+
+	assertTrue(t, protocol == msg.Version, "wrong protocol")
+	assertTrue(t, len(caps) == 2 && caps[0] == msg.CapHistory &&
+		caps[1] == msg.CapBatch, "wrong caps")
+
+	// incompatible
+
+	for _, syn := range []*msg.Syn{
+		{Protocol: msg.Version + 2, MinProtocol: msg.Version + 1},
+		{Protocol: msg.MinVersion - 1, MinProtocol: msg.MinVersion - 1},
+	} {
+		if _, _, err = n.negotiate(syn); err == nil {
+			t.Error("missing error", syn.MinProtocol, syn.Protocol)
+		}
+	}
+
+	// compression turned off
+
+	n.config.Compression = false
+
+	_, caps, err = n.negotiate(&msg.Syn{
+		Protocol:    msg.Version,
+		MinProtocol: msg.MinVersion,
+		Caps:        msg.Caps,
+	})
+	assertNil(t, err)
+
+	assertTrue(t, hasCap(caps, msg.CapCompression) == false,
+		"compression negotiated")
+
+}
+
+func Test_checkNegotiated(t *testing.T) {
+
+	var syn = &msg.Syn{
+		Protocol:    msg.Version,
+		MinProtocol: msg.MinVersion,
+		Caps:        []string{msg.CapBatch},
+	}
+
+	assertNil(t, checkNegotiated(syn, &msg.Ack{
+		Protocol: msg.Version,
+		Caps:     []string{msg.CapBatch},
+	}))
+
+	for _, ack := range []*msg.Ack{
+		{Protocol: msg.Version + 1},
+		{Protocol: msg.Version, Caps: []string{msg.CapDelta}},
+		{Protocol: msg.Version, Codec: msg.CodecFlate},
+	} {
+		if err := checkNegotiated(syn, ack); err == nil {
+			t.Error("missing error", ack.Protocol, ack.Caps, ack.Codec)
+		}
+	}
+
+}
+
+func TestConn_Caps(t *testing.T) {
+
+	var (
+		sn = getTestNode("server")
+		cc = getTestConfigNotListen("client")
+	)
+
+	cc.Compression = false
+
+	var cn, err = NewNode(cc)
+	assertNil(t, err)
+
+	defer sn.Close()
+	defer cn.Close()
+
+	var c *Conn
+	c, err = cn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	var sc = getTestConnOfPeer(t, sn, cn.ID())
+
+	for _, x := range []*Conn{c, sc} {
+
+		assertTrue(t, x.Protocol() == msg.Version, "wrong protocol")
+
+		assertTrue(t, x.HasCap(msg.CapBatch) == true, "missing batch")
+		assertTrue(t, x.HasCap(msg.CapDelta) == true, "missing delta")
+		assertTrue(t, x.HasCap(msg.CapHistory) == true, "missing history")
+		assertTrue(t, x.HasCap(msg.CapCompression) == false,
+			"unexpected compression")
+
+		assertNil(t, x.checkCap(&msg.RootDelta{}))
+
+		if err = x.checkCap(&msg.Compressed{}); err == nil {
+			t.Error("missing error")
+		}
+
+	}
+
+}
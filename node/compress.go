@@ -0,0 +1,249 @@
+package node
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+
+	"github.com/skycoin/cxo/node/msg"
+)
+
+// compression
+//
+// The initiator of a handshake sends list of codecs
+// it supports in the Syn, and accepting side chooses
+// first of them it supports too. The chosen codec sent
+// back in the Ack. Both sides compress messeges greater
+// than Config.CompressionThreshold using the codec,
+// wrapping them in the msg.Compressed. If there is not
+// a codec both sides support, then the connection is
+// not compressed
+
+// type and length prefix of msg.Compressed
+const msgCompressedOverhead = 1 + 4
+
+// a codec compresses and decompresses encoded messeges
+type codec interface {
+	compress(p []byte) (c []byte, err error)
+	decompress(c []byte, limit int) (p []byte, err error)
+}
+
+// supported codecs by names
+var codecs = map[string]codec{
+	msg.CodecFlate: flateCodec{},
+}
+
+// supported codecs in order of preference
+var codecsOrder = []string{
+	msg.CodecFlate,
+}
+
+// DEFLATE
+type flateCodec struct{}
+
+func (flateCodec) compress(p []byte) (c []byte, err error) {
+
+	var (
+		buf bytes.Buffer
+		fw  *flate.Writer
+	)
+
+	if fw, err = flate.NewWriter(&buf, flate.BestSpeed); err != nil {
+		return
+	}
+
+	if _, err = fw.Write(p); err != nil {
+		return
+	}
+
+	if err = fw.Close(); err != nil {
+		return
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (flateCodec) decompress(c []byte, limit int) (p []byte, err error) {
+
+	var fr = flate.NewReader(bytes.NewReader(c))
+	defer fr.Close()
+
+	// read one byte more to detect messeges greater than the limit
+	var lr = io.LimitReader(fr, int64(limit)+1)
+
+	if p, err = ioutil.ReadAll(lr); err != nil {
+		return
+	}
+
+	if len(p) > limit {
+		return nil, errors.New("decompressed messege is too big")
+	}
+
+	return
+}
+
+// codecs of the Node for the Syn
+func (n *Node) codecs() (names []string) {
+
+	if n.config.Compression == false {
+		return
+	}
+
+	return codecsOrder
+}
+
+// choose codec from given list of codecs of
+// the Syn, the result is empty string if there
+// is not a suitable codec
+func (n *Node) chooseCodec(names []string) (name string) {
+
+	if n.config.Compression == false {
+		return
+	}
+
+	for _, name = range names {
+		if _, ok := codecs[name]; ok == true {
+			return
+		}
+	}
+
+	return ""
+}
+
+// set codec chosen during handshake
+func (c *Conn) setCodec(name string) (err error) {
+
+	if name == "" {
+		return // not compressed
+	}
+
+	var cd, ok = codecs[name]
+
+	if ok == false {
+		return fmt.Errorf("unsupported codec %q chosen by peer", name)
+	}
+
+	c.codecName, c.codec = name, cd
+	return
+}
+
+// compress encoded messege if it's big enough
+// and the Conn has a codec, the em is returned
+// as is otherwise
+func (c *Conn) compress(em []byte) (cm []byte) {
+
+	cm = em
+
+	if c.codec != nil && len(em) > c.n.config.CompressionThreshold {
+
+		var cv, err = c.codec.compress(em)
+
+		if err != nil {
+			c.n.Printf("[ERR] [%s] can't compress messege: %v",
+				c.String(), err)
+		} else if len(cv)+msgCompressedOverhead < len(em) {
+			cm = (&msg.Compressed{Value: cv}).Encode()
+		}
+
+	}
+
+	atomic.AddUint64(&c.sent, uint64(len(em)))
+	atomic.AddUint64(&c.sentWire, uint64(len(cm)))
+
+	return
+}
+
+// decompress given messege if it's msg.Compressed,
+// the wire is length of the received messege
+func (c *Conn) decompress(m msg.Msg, wire int) (dm msg.Msg, err error) {
+
+	var cm, ok = m.(*msg.Compressed)
+
+	if ok == false {
+		atomic.AddUint64(&c.received, uint64(wire))
+		atomic.AddUint64(&c.receivedWire, uint64(wire))
+		return m, nil
+	}
+
+	if c.codec == nil {
+		return nil, errors.New("compressed messege received, but" +
+			" compression is not negotiated")
+	}
+
+	var em []byte
+	if em, err = c.codec.decompress(cm.Value, maxMsgVolume); err != nil {
+		return
+	}
+
+	if dm, err = msg.Decode(em); err != nil {
+		return
+	}
+
+	if _, ok = dm.(*msg.Compressed); ok == true {
+		return nil, errors.New("nested compressed messege received")
+	}
+
+	atomic.AddUint64(&c.received, uint64(len(em)))
+	atomic.AddUint64(&c.receivedWire, uint64(wire))
+
+	return
+}
+
+// A ConnStat represents statistic of a Conn. Sizes
+// are sizes of encoded messeges, excluding encryption
+// and transport overhead
+type ConnStat struct {
+	Protocol     uint16   // negotiated protocol version
+	Caps         []string // negotiated capabilities
+	Codec        string   // codec chosen during handshake, or empty
+	Sent         uint64   // sent, before compression
+	SentWire     uint64   // sent, after compression
+	Received     uint64   // received, after decompression
+	ReceivedWire uint64   // received, before decompression
+}
+
+// ratio of given sizes
+func compressionRatio(raw, wire uint64) float64 {
+
+	if wire == 0 {
+		return 1
+	}
+
+	return float64(raw) / float64(wire)
+}
+
+// SendRatio returns compression ratio of sent messeges
+func (c ConnStat) SendRatio() float64 {
+	return compressionRatio(c.Sent, c.SentWire)
+}
+
+// ReceiveRatio returns compression ratio of received messeges
+func (c ConnStat) ReceiveRatio() float64 {
+	return compressionRatio(c.Received, c.ReceivedWire)
+}
+
+// Codec returns name of codec chosen during handshake.
+// The Codec returns empty string if the Conn is not
+// compressed
+func (c *Conn) Codec() string {
+	return c.codecName
+}
+
+// Stat returns statistic of the Conn
+func (c *Conn) Stat() (cs ConnStat) {
+
+	cs.Protocol = c.protocol
+	cs.Caps = c.Caps()
+	cs.Codec = c.codecName
+
+	cs.Sent = atomic.LoadUint64(&c.sent)
+	cs.SentWire = atomic.LoadUint64(&c.sentWire)
+	cs.Received = atomic.LoadUint64(&c.received)
+	cs.ReceivedWire = atomic.LoadUint64(&c.receivedWire)
+
+	return
+}
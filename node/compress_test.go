@@ -0,0 +1,115 @@
+package node
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skycoin/cxo/node/msg"
+)
+
+func TestConn_Codec(t *testing.T) {
+
+	t.Run("compressed", func(t *testing.T) {
+
+		var (
+			sn = getTestNode("server")
+			cn = getTestNodeNotListen("client")
+		)
+
+		defer sn.Close()
+		defer cn.Close()
+
+		var c, err = cn.TCP().Connect(sn.TCP().Address())
+		assertNil(t, err)
+
+		var sc = getTestConnOfPeer(t, sn, cn.ID())
+
+		assertTrue(t, c.Codec() == msg.CodecFlate, "wrong codec")
+		assertTrue(t, sc.Codec() == msg.CodecFlate, "wrong codec")
+
+	})
+
+	t.Run("not compressed", func(t *testing.T) {
+
+		var (
+			sn = getTestNode("server")
+			cc = getTestConfigNotListen("client")
+		)
+
+		cc.Compression = false
+
+		var cn, err = NewNode(cc)
+		assertNil(t, err)
+
+		defer sn.Close()
+		defer cn.Close()
+
+		var c *Conn
+		c, err = cn.TCP().Connect(sn.TCP().Address())
+		assertNil(t, err)
+
+		var sc = getTestConnOfPeer(t, sn, cn.ID())
+
+		assertTrue(t, c.Codec() == "", "compressed")
+		assertTrue(t, sc.Codec() == "", "compressed")
+
+	})
+
+}
+
+func TestConn_compress(t *testing.T) {
+
+	var (
+		sn = getTestNode("server")
+		cn = getTestNodeNotListen("client")
+	)
+
+	defer sn.Close()
+	defer cn.Close()
+
+	var c, err = cn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	var (
+		sc = getTestConnOfPeer(t, sn, cn.ID())
+
+		obj = &msg.Object{
+			Value: bytes.Repeat([]byte("hey-ho! "), 1024),
+		}
+
+		em = obj.Encode()
+		cm = c.compress(em)
+	)
+
+	assertTrue(t, len(cm) < len(em), "not compressed")
+
+	var m msg.Msg
+	m, err = msg.Decode(cm)
+	assertNil(t, err)
+
+	_, isCompressed := m.(*msg.Compressed)
+	assertTrue(t, isCompressed == true, "not a Compressed")
+
+	var before = sc.Stat()
+
+	m, err = sc.decompress(m, len(cm))
+	assertNil(t, err)
+
+	var got, isObject = m.(*msg.Object)
+	assertTrue(t, isObject == true, "not an Object")
+	assertTrue(t, bytes.Equal(got.Value, obj.Value), "wrong Object")
+
+	var after = sc.Stat()
+
+	assertTrue(t, after.Received-before.Received == uint64(len(em)),
+		"wrong received")
+	assertTrue(t, after.ReceivedWire-before.ReceivedWire == uint64(len(cm)),
+		"wrong received on wire")
+	assertTrue(t, c.Stat().SendRatio() > 1, "wrong send ratio")
+
+	// small messeges are not compressed
+
+	em = (&msg.Ok{}).Encode()
+	assertTrue(t, bytes.Equal(c.compress(em), em), "compressed")
+
+}
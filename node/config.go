@@ -3,6 +3,7 @@ package node
 import (
 	"flag"
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
@@ -24,6 +25,29 @@ const (
 	ResponseTimeout time.Duration = 59 * time.Second
 	Pings           time.Duration = 118 * time.Second
 	Public          bool          = false
+
+	MaxRequestObjects int = 128             // objects per request
+	MaxResponseVolume int = 8 * 1024        // 8K
+	MaxPrefetchVolume int = 8 * 1024 * 1024 // 8M
+	MaxHistory        int = 1024            // Root objects per SubFrom
+
+	ReconnectDelay    time.Duration = time.Second
+	MaxReconnectDelay time.Duration = 5 * time.Minute
+
+	Peers string = "peers.json" // default file name of known peers
+	Bans  string = "bans.json"  // default file name of bans
+
+	Identity string = "id.json" // default file name of identity of the Node
+
+	BanScore          int           = 100
+	BanTime           time.Duration = 24 * time.Hour
+	ScoreTime         time.Duration = time.Hour
+	MaxRootsPerSecond int           = 100
+
+	RequireEncryption bool = true
+
+	Compression          bool = true
+	CompressionThreshold int  = 512 // bytes
 )
 
 // Addresses are discovery addresses
@@ -154,6 +178,14 @@ type Config struct {
 	// Use nil for defaults.
 	*skyobject.Config
 
+	// IDSecKey is secret key of identity of the Node
+	// (see (*Node).ID). If it's blank, then identity
+	// generated once and saved under DataDir (see
+	// Identity constant) or near the DBPath. The
+	// identity of a Node with DB in memory is random
+	// and is not saved
+	IDSecKey cipher.SecKey
+
 	// MaxConnections is limit of connections.
 	// Set it to zero to disable the limit.
 	MaxConnections int
@@ -187,10 +219,83 @@ type Config struct {
 	// limit.
 	MaxFillingTime time.Duration
 
+	// MaxRequestObjects is max number of objects the Node
+	// requests from a peer at once filling a Root. If it's
+	// 1, then the Node requests objects one by one. The
+	// MaxRequestObjects must be positive and keys of the
+	// objects must fit max size of a messege of underlying
+	// transport (10K), e.g. it can't be greater then 317
+	MaxRequestObjects int
+
+	// MaxResponseVolume is max total size of objects the
+	// Node sends in a reply. The MaxResponseVolume must be
+	// positive and can't be greater then max size of a
+	// messege of underlying transport (10K)
+	MaxResponseVolume int
+
+	// MaxPrefetchVolume is volume of objects the Node can
+	// prefetch filling a Root the Node has not any Root of
+	// head of which. In this case, the Node requests all
+	// objects of the Root walking it by peer, instead of
+	// requesting them by keys. Thus, it performs fewer
+	// requests. But the prefetching makes sense only if
+	// the Node doesn't have objects of the Root. Otherwise,
+	// it downloads objects the Node already has. The
+	// prefetched objects are kept in memory until they are
+	// used by filler. Set it to zero to turn the prefetching
+	// off
+	MaxPrefetchVolume int
+
+	// MaxHistory is max number of Root objects the Node
+	// pushes in reply to a history request of a peer
+	// (see (*Conn).SubscribeFrom). Set it to zero to
+	// disable the history replay
+	MaxHistory int
+
+	// ReconnectDelay is delay before first attempt to
+	// reconnect to a known peer (see (*Node).AddPeer).
+	// The delay is doubled after every failed attempt
+	// and is randomized a bit. Set it to zero to
+	// reconnect without delays
+	ReconnectDelay time.Duration
+	// MaxReconnectDelay is limit of the doubling
+	// of the ReconnectDelay
+	MaxReconnectDelay time.Duration
+
+	// BanScore is score of violations of a peer (see
+	// Violation) after which the peer is banned by its
+	// PeerID and IP address for the BanTime. Set it to
+	// zero to never ban peers automatically. Bans are
+	// saved near the DB (like known peers) and can be
+	// managed using (*Node).Ban and (*Node).Unban
+	BanScore int
+	// BanTime is duration of automatic bans
+	BanTime time.Duration
+	// ScoreTime is time after which a violation of a
+	// peer is forgotten
+	ScoreTime time.Duration
+	// MaxRootsPerSecond is max number of Root objects a
+	// peer can push through a connection per second.
+	// Excess Root objects are dropped and the flood
+	// is a violation. Set it to zero to turn it off
+	MaxRootsPerSecond int
+
+	// ACL is access control lists of feeds. A feed
+	// without ACL is open to any peer. See ACL for
+	// details. The ACLs can be changed using
+	// (*Node).SetACL and (*Node).DelACL
+	ACL ACLs
+
 	// RPC is RPC listening address. Empty string
 	// disables RPC.
 	RPC string
 
+	// HTTP is listening address of JSON HTTP API (see
+	// http.go for the API). It's the same as the RPC
+	// for non-Go clients. Empty string disables the
+	// HTTP API (default)
+	HTTP string
+
 	//
 	// Networks
 	//
@@ -201,6 +306,27 @@ type Config struct {
 	// UDP configurations
 	UDP NetConfig
 
+	// RequireEncryption used to reject peers that can't
+	// authenticate and encrypt connections. Peers of
+	// current protocol version always authenticate and
+	// encrypt connections. But peers of legacy protocol
+	// version can't. If the RequireEncryption is false,
+	// then the Node accepts connections from legacy
+	// peers. PeerID of such connections is not proved,
+	// and messeges are not encrypted. See also
+	// (*Conn).IsEncrypted
+	RequireEncryption bool
+
+	// Compression turns on compression of connections.
+	// Peers negotiate compression codec during handshake,
+	// and a connection is compressed only if both peers
+	// have the Compression turned on. See (*Conn).Stat
+	// for compression ratio of a connection
+	Compression bool
+	// CompressionThreshold is min size of encoded messege
+	// to compress, smaller messeges are sent as is
+	CompressionThreshold int
+
 	//
 	// Connection callbacks
 	//
@@ -282,6 +408,19 @@ func NewConfig() (c *Config) {
 	c.MaxFillingTime = MaxFillingTime
 	c.MaxHeads = MaxHeads
 
+	c.MaxRequestObjects = MaxRequestObjects
+	c.MaxResponseVolume = MaxResponseVolume
+	c.MaxPrefetchVolume = MaxPrefetchVolume
+	c.MaxHistory = MaxHistory
+
+	c.ReconnectDelay = ReconnectDelay
+	c.MaxReconnectDelay = MaxReconnectDelay
+
+	c.BanScore = BanScore
+	c.BanTime = BanTime
+	c.ScoreTime = ScoreTime
+	c.MaxRootsPerSecond = MaxRootsPerSecond
+
 	c.TCP.Listen = ListenTCP
 	c.TCP.Pings = Pings
 	c.TCP.ResponseTimeout = ResponseTimeout
@@ -292,6 +431,11 @@ func NewConfig() (c *Config) {
 	c.RPC = RPCAddress
 	c.Public = Public
 
+	c.RequireEncryption = RequireEncryption
+
+	c.Compression = Compression
+	c.CompressionThreshold = CompressionThreshold
+
 	return
 
 }
@@ -338,11 +482,71 @@ func (c *Config) FromFlags() {
 		c.MaxHeads,
 		"max heads of a feed allowed")
 
+	flag.IntVar(&c.MaxRequestObjects,
+		"max-request-objects",
+		c.MaxRequestObjects,
+		"max objects requested at once")
+
+	flag.IntVar(&c.MaxResponseVolume,
+		"max-response-volume",
+		c.MaxResponseVolume,
+		"max size of objects of a reply in bytes")
+
+	flag.IntVar(&c.MaxPrefetchVolume,
+		"max-prefetch-volume",
+		c.MaxPrefetchVolume,
+		"max size of prefetched objects of new head in bytes (0 - turned off)")
+
+	flag.IntVar(&c.MaxHistory,
+		"max-history",
+		c.MaxHistory,
+		"max Root objects sent to a peer requesting history (0 - turned off)")
+
+	flag.DurationVar(&c.ReconnectDelay,
+		"reconnect-delay",
+		c.ReconnectDelay,
+		"first delay before reconnection to a known peer")
+
+	flag.DurationVar(&c.MaxReconnectDelay,
+		"max-reconnect-delay",
+		c.MaxReconnectDelay,
+		"max delay before reconnection to a known peer")
+
+	flag.IntVar(&c.BanScore,
+		"ban-score",
+		c.BanScore,
+		"score of violations to ban a peer (0 - turned off)")
+
+	flag.DurationVar(&c.BanTime,
+		"ban-time",
+		c.BanTime,
+		"duration of automatic bans")
+
+	flag.DurationVar(&c.ScoreTime,
+		"score-time",
+		c.ScoreTime,
+		"time after which a violation of a peer is forgotten")
+
+	flag.IntVar(&c.MaxRootsPerSecond,
+		"max-roots-per-second",
+		c.MaxRootsPerSecond,
+		"max Root objects per second from a peer (0 - turned off)")
+
+	flag.Var(&c.ACL,
+		"acl",
+		"access control list of a feed: feed:subscribe=any|none|pk+pk,"+
+			"preview=...,fetch=... (can be repeated)")
+
 	flag.StringVar(&c.RPC,
 		"rpc",
 		c.RPC,
 		"RPC listening address")
 
+	flag.StringVar(&c.HTTP,
+		"http",
+		c.HTTP,
+		"HTTP API listening address (empty - turned off)")
+
 	// TCP
 
 	flag.StringVar(&c.TCP.Listen,
@@ -384,6 +588,25 @@ func (c *Config) FromFlags() {
 		c.Public,
 		"public server")
 
+	// encryption
+
+	flag.BoolVar(&c.RequireEncryption,
+		"require-encryption",
+		c.RequireEncryption,
+		"reject legacy peers that can't encrypt connections")
+
+	// compression
+
+	flag.BoolVar(&c.Compression,
+		"compression",
+		c.Compression,
+		"compress connections if peer supports it")
+
+	flag.IntVar(&c.CompressionThreshold,
+		"compression-threshold",
+		c.CompressionThreshold,
+		"min size of messege to compress in bytes")
+
 }
 
 // Validate configurations. The Validate doesn't
@@ -399,8 +622,110 @@ func (c *Config) Validate() (err error) {
 		}
 	}
 
-	// nothing to validate in the Node configs
+	if c.IDSecKey != (cipher.SecKey{}) {
+		if err = c.IDSecKey.Verify(); err != nil {
+			return fmt.Errorf("node.Config.IDSecKey is invalid: %v", err)
+		}
+	}
+
+	if c.MaxRequestObjects <= 0 {
+		return fmt.Errorf("node.Config.MaxRequestObjects is not positive: %d",
+			c.MaxRequestObjects)
+	}
+
+	if c.MaxRequestObjects > maxRequestObjects {
+		return fmt.Errorf("node.Config.MaxRequestObjects is too big: %d,"+
+			" max is %d", c.MaxRequestObjects, maxRequestObjects)
+	}
+
+	if c.MaxResponseVolume <= 0 {
+		return fmt.Errorf("node.Config.MaxResponseVolume is not positive: %d",
+			c.MaxResponseVolume)
+	}
+
+	if c.MaxResponseVolume > maxMsgVolume {
+		return fmt.Errorf("node.Config.MaxResponseVolume is too big: %d,"+
+			" max is %d", c.MaxResponseVolume, maxMsgVolume)
+	}
+
+	if c.MaxPrefetchVolume < 0 {
+		return fmt.Errorf("node.Config.MaxPrefetchVolume is negative: %d",
+			c.MaxPrefetchVolume)
+	}
+
+	if c.MaxHistory < 0 {
+		return fmt.Errorf("node.Config.MaxHistory is negative: %d",
+			c.MaxHistory)
+	}
+
+	if c.ReconnectDelay < 0 {
+		return fmt.Errorf("node.Config.ReconnectDelay is negative: %s",
+			c.ReconnectDelay)
+	}
+
+	if c.MaxReconnectDelay < c.ReconnectDelay {
+		return fmt.Errorf("node.Config.MaxReconnectDelay (%s) is less then"+
+			" the ReconnectDelay (%s)", c.MaxReconnectDelay, c.ReconnectDelay)
+	}
+
+	if c.BanScore < 0 {
+		return fmt.Errorf("node.Config.BanScore is negative: %d", c.BanScore)
+	}
+
+	if c.BanScore > 0 && c.BanTime <= 0 {
+		return fmt.Errorf("node.Config.BanTime is not positive: %s",
+			c.BanTime)
+	}
+
+	if c.ScoreTime < 0 {
+		return fmt.Errorf("node.Config.ScoreTime is negative: %s",
+			c.ScoreTime)
+	}
+
+	if c.MaxRootsPerSecond < 0 {
+		return fmt.Errorf("node.Config.MaxRootsPerSecond is negative: %d",
+			c.MaxRootsPerSecond)
+	}
+
+	if c.CompressionThreshold < 0 {
+		return fmt.Errorf("node.Config.CompressionThreshold is negative: %d",
+			c.CompressionThreshold)
+	}
 
 	return
 
 }
+
+// path to file of the Node under DataDir or near
+// the DBPath (with given extension), or empty string
+// if the file should not be saved (DB in memory)
+func (c *Config) nodeFile(name, ext string) (path string) {
+
+	switch {
+	case c.Config == nil:
+		return
+	case c.Config.DB != nil || c.InMemoryDB == true:
+		return
+	case c.DBPath != "":
+		path = c.DBPath + ext
+	default:
+		path = filepath.Join(c.DataDir, name)
+	}
+
+	return
+}
+
+// path to file of known peers
+func (c *Config) peers() string {
+	return c.nodeFile(Peers, ".peers")
+}
+
+// path to file of bans
+func (c *Config) bans() string {
+	return c.nodeFile(Bans, ".bans")
+}
+
+// path to file of identity
+func (c *Config) identityFile() string {
+	return c.nodeFile(Identity, ".id")
+}
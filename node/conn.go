@@ -11,6 +11,7 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 
 	"github.com/skycoin/net/factory"
+	netmsg "github.com/skycoin/net/msg"
 
 	"github.com/skycoin/cxo/data"
 	"github.com/skycoin/cxo/node/msg"
@@ -20,6 +21,11 @@ import (
 
 // A Conn represent connection of the Node
 type Conn struct {
+	// stat (see ConnStat), first
+	// for 64-bit alignment
+	sent, sentWire         uint64
+	received, receivedWire uint64
+
 	*factory.Connection
 
 	// lock
@@ -30,6 +36,15 @@ type Conn struct {
 	n      *Node         // back reference
 	peerID cipher.PubKey // peer id
 
+	cc   *connCipher // encryption (nil for legacy peers)
+	hseq uint32      // seq of Auth (accepting handshake)
+
+	protocol uint16   // negotiated version (see caps.go)
+	caps     []string // negotiated capabilities
+
+	codecName string // compression codec (see compress.go)
+	codec     codec  // nil if not compressed
+
 	// request - response
 	seq  uint32                    // messege seq number (for request-response)
 	reqs map[uint32]chan<- msg.Msg // requests
@@ -42,6 +57,13 @@ type Conn struct {
 
 	sendq chan<- []byte // channel from factory.Connection
 
+	// flood protection (see isFlood)
+	rootsTime time.Time // start of current second
+	roots     int       // received Root objects for the second
+
+	// feeds previewed by the peer (see ACL)
+	previews map[cipher.PubKey]struct{}
+
 	await  sync.WaitGroup // wait for receiving loop
 	closeq chan struct{}  //
 	closeo sync.Once      // close once
@@ -62,6 +84,7 @@ func (n *Node) newConnection(
 	c.n = n
 
 	c.reqs = make(map[uint32]chan<- msg.Msg)
+	c.previews = make(map[cipher.PubKey]struct{})
 
 	c.sendq = fc.GetChanOut()
 	c.closeq = make(chan struct{})
@@ -103,11 +126,25 @@ func (c *Conn) decodeRaw(raw []byte) (seq, rseq uint32, m msg.Msg, err error) {
 //
 
 // PeerID is id of remote peer that used
-// for internals and unique
+// for internals and unique. The peer proves
+// that it owns secret key of the id during
+// handshake, if the Conn is encrypted. See
+// IsEncrypted for details
 func (c *Conn) PeerID() (id cipher.PubKey) {
 	return c.peerID
 }
 
+// IsEncrypted returns true if the Conn is
+// encrypted and authenticated. All connections
+// are encrypted, except connections to peers
+// of legacy protocol version (such connections
+// allowed if Config.RequireEncryption is false).
+// The PeerID of a not encrypted Conn is not
+// proved and can be spoofed
+func (c *Conn) IsEncrypted() (ok bool) {
+	return c.cc != nil
+}
+
 // IsIncoming returns true if this Conn is
 // incoming and accepted by listener
 func (c *Conn) IsIncoming() (ok bool) {
@@ -197,8 +234,37 @@ func (c *Conn) RemoteFeeds() (feeds []cipher.PubKey, err error) {
 	return
 }
 
-func (c *Conn) sendRoot(r *registry.Root) {
-	c.sendMsg(c.nextSeq(), 0, &msg.Root{
+// send Root with delta (if it's not empty); the delta
+// is not sent to peers without the msg.CapDelta, and
+// the delta cut if the messege is too big
+func (c *Conn) sendRoot(r *registry.Root, delta []cipher.SHA256) {
+
+	var root = rootMsg(r)
+
+	if len(delta) == 0 || c.HasCap(msg.CapDelta) == false {
+		c.sendMsg(c.nextSeq(), 0, &root)
+		return
+	}
+
+	// [ root ][ 4 length of the delta ][ delta ]
+
+	var max = (maxMsgVolume - len(root.Encode()) - 4) / len(cipher.SHA256{})
+
+	if max <= 0 {
+		c.sendMsg(c.nextSeq(), 0, &root)
+		return
+	}
+
+	if len(delta) > max {
+		delta = delta[:max] // incomplete
+	}
+
+	c.sendMsg(c.nextSeq(), 0, &msg.RootDelta{Root: root, Delta: delta})
+}
+
+// create msg.Root from given Root
+func rootMsg(r *registry.Root) msg.Root {
+	return msg.Root{
 		Feed:  r.Pub,
 		Nonce: r.Nonce,
 		Seq:   r.Seq,
@@ -206,7 +272,7 @@ func (c *Conn) sendRoot(r *registry.Root) {
 		Value: r.Encode(),
 
 		Sig: r.Sig,
-	})
+	}
 }
 
 // send last Root to peer
@@ -218,7 +284,7 @@ func (c *Conn) sendLastRoot(pk cipher.PubKey) {
 	)
 
 	if err == nil {
-		c.sendRoot(r)
+		c.sendRoot(r, nil)
 		return
 	}
 
@@ -234,6 +300,47 @@ func (c *Conn) sendLastRoot(pk cipher.PubKey) {
 // then it probably adds given feed to the Node, but request
 // fails. Or it can returns error of the (*Node).Share
 func (c *Conn) Subscribe(feed cipher.PubKey) (err error) {
+	return c.subscribe(feed, &msg.Sub{Feed: feed})
+}
+
+// SubscribeFrom is the same as the Subscribe, but it
+// requests history of given head of the feed too. The
+// remote peer sends Root objects of the head starting
+// from given seq, or from first Root created after
+// given time if the since is not zero. The Root objects
+// are filled in order, even if the Node has newer Root
+// of the head. The number of Root objects is limited by
+// given limit and by MaxHistory of the remote peer (zero
+// limit means the MaxHistory). The zero nonce means
+// active head of the remote peer. Filled Root objects
+// can be obtained using OnRootFilled callback. The
+// SubscribeFrom returns ErrNotSupported if the peer
+// doesn't support history (see msg.CapHistory)
+func (c *Conn) SubscribeFrom(
+	feed cipher.PubKey, // : feed
+	nonce uint64, //       : head, zero for active
+	seq uint64, //         : from the seq
+	since int64, //        : from the time (unix nano), if not zero
+	limit uint32, //       : max Root objects, zero for default
+) (
+	err error, //          : an error
+) {
+
+	if c.HasCap(msg.CapHistory) == false {
+		return ErrNotSupported
+	}
+
+	return c.subscribe(feed, &msg.SubFrom{
+		Feed:  feed,
+		Nonce: nonce,
+		Seq:   seq,
+		Time:  since,
+		Limit: limit,
+	})
+}
+
+// send Sub or SubFrom
+func (c *Conn) subscribe(feed cipher.PubKey, sub msg.Msg) (err error) {
 
 	// add the feed to node
 
@@ -241,9 +348,25 @@ func (c *Conn) Subscribe(feed cipher.PubKey) (err error) {
 		return
 	}
 
+	// the connection should be subscribed before the request,
+	// because peer pushes last Root right after the Ok, and
+	// the Root is ignored if the connection is not subscribed
+
+	var subscribed = c.n.fs.hasConnFeed(c, feed)
+
+	if subscribed == false {
+		c.n.fs.addConnFeed(c, feed)
+
+		defer func() {
+			if err != nil {
+				c.n.fs.delConnFeed(c, feed) // rollback
+			}
+		}()
+	}
+
 	var reply msg.Msg
 
-	if reply, err = c.sendRequest(&msg.Sub{Feed: feed}); err != nil {
+	if reply, err = c.sendRequest(sub); err != nil {
 		return
 	}
 
@@ -264,7 +387,7 @@ func (c *Conn) Subscribe(feed cipher.PubKey) (err error) {
 		return
 	}
 
-	c.n.fs.addConnFeed(c, feed)
+	c.n.ps.subscribed(c, feed) // remember the feed if the peer is known
 	c.sendLastRoot(feed)
 	return
 }
@@ -279,6 +402,7 @@ func (c *Conn) unsubscribe(pk cipher.PubKey) {
 // Unsubscribe from given feed of remote peer
 func (c *Conn) Unsubscribe(feed cipher.PubKey) {
 	c.n.fs.delConnFeed(c, feed)
+	c.n.ps.unsubscribed(c, feed)
 	c.unsubscribe(feed) // notify peer
 	return
 }
@@ -372,9 +496,10 @@ func (c *Conn) getter() (cg skyobject.Getter) {
 func (c *Conn) close(reason error) error {
 	c.closeo.Do(func() {
 		c.n.delConnection(c)
-		close(c.closeq)      // close the channel
-		c.Connection.Close() // close
-		c.await.Wait()       // wait for goroutines
+		c.n.delTransportConn(c) // allow to connect again
+		close(c.closeq)         // close the channel
+		c.await.Wait()          // wait for goroutines (they can send)
+		c.Connection.Close()    // close
 
 		c.n.onDisconenct(c, reason) // callback
 	})
@@ -390,9 +515,23 @@ func (c *Conn) nextSeq() uint32 {
 	return atomic.AddUint32(&c.seq, 1)
 }
 
+// the factory can't send a messege greater then the
+// netmsg.MAX_MESSAGE_SIZE, messege of the Conn is
+//
+//	[ 8 nonce counter ][ 4 seq ][ 4 rseq ][ 1 type ][ encoded ][ 16 tag ]
+//
+// and every encoded []byte (e.g. object of msg.Objects)
+// has 4 byte length prefix
+const (
+	msgOverhead       = 64                                    // rounded up
+	maxMsgVolume      = netmsg.MAX_MESSAGE_SIZE - msgOverhead // encoded
+	maxRequestObjects = (maxMsgVolume - 4) / len(cipher.SHA256{})
+	objectOverhead    = 4 // length prefix
+)
+
 func (c *Conn) encodeMsg(seq, rseq uint32, m msg.Msg) (raw []byte) {
 
-	var em = m.Encode()
+	var em = c.compress(m.Encode())
 
 	raw = make([]byte, 8, 8+len(em))
 
@@ -414,6 +553,28 @@ func (c *Conn) sendMsg(seq, rseq uint32, m msg.Msg) {
 
 func (c *Conn) sendRaw(raw []byte) {
 
+	if c.cc != nil {
+		c.sendEncrypted(raw)
+		return
+	}
+
+	c.send(raw)
+}
+
+// the factory closes the sendq itself if the connection
+// closed by remote peer or by a transport failure; thus,
+// a handler (that works in separate goroutine) can send
+// to closed channel; the panic is recovered here, and the
+// receiving loop closes the Conn
+func (c *Conn) send(raw []byte) {
+
+	defer func() {
+		if pc := recover(); pc != nil {
+			c.n.Debugf(ConnPin, "[%s] send to closed connection: %v",
+				c.String(), pc)
+		}
+	}()
+
 	select {
 	case c.sendq <- raw:
 	case <-c.closeq:
@@ -421,6 +582,16 @@ func (c *Conn) sendRaw(raw []byte) {
 
 }
 
+// messeges should be encrypted and sent in the same order
+func (c *Conn) sendEncrypted(raw []byte) {
+
+	c.cc.smx.Lock()
+	defer c.cc.smx.Unlock()
+
+	c.send(c.cc.encrypt(raw))
+
+}
+
 func (c *Conn) fatality(args ...interface{}) {
 
 	var err = errors.New(fmt.Sprint(args...))
@@ -458,10 +629,20 @@ func (c *Conn) receiving() {
 				return // closed
 			}
 
+			if c.cc != nil {
+				if raw, err = c.cc.decrypt(raw); err != nil {
+					c.n.bs.violation(c, ViolationProtocol, err)
+					c.fatality("can't decrypt received messege: ", err)
+					return
+				}
+			}
+
 			// [ 4 seq ][ 4 rseq ][ 1 msg type ]
 
 			if len(raw) < 9 {
-				c.fatality("invalid messege received: samll size")
+				err = errors.New("invalid messege received: samll size")
+				c.n.bs.violation(c, ViolationProtocol, err)
+				c.fatality(err)
 				return
 			}
 
@@ -473,7 +654,14 @@ func (c *Conn) receiving() {
 			rseq = binary.LittleEndian.Uint32(raw)
 			raw = raw[4:]
 
-			if m, err = msg.Decode(raw); err != nil {
+			if m, err = msg.Decode(raw); err == nil {
+				if m, err = c.decompress(m, len(raw)); err == nil {
+					err = c.checkCap(m)
+				}
+			}
+
+			if err != nil {
+				c.n.bs.violation(c, ViolationProtocol, err)
 				c.fatality("can't decode received messege: ", err)
 				return
 			}
@@ -487,6 +675,7 @@ func (c *Conn) receiving() {
 			}
 
 			if err = c.handle(seq, m); err != nil {
+				c.n.bs.violation(c, ViolationProtocol, err)
 				c.fatality("error handling messege: ", err)
 				return
 			}
@@ -591,6 +780,9 @@ func (c *Conn) handle(seq uint32, m msg.Msg) (err error) {
 	case *msg.Unsub: // <- Unsub (feed)
 		return c.handleUnsub(seq, x)
 
+	case *msg.SubFrom: // <- SubFrom (feed, nonce, seq, time, limit)
+		return c.handleSubFrom(seq, x)
+
 	// public server features
 
 	case *msg.RqList: // <- RqList ()
@@ -603,6 +795,12 @@ func (c *Conn) handle(seq uint32, m msg.Msg) (err error) {
 	case *msg.Root: // <- Root (feed, nonce, seq, sig, val)
 		return c.handleRoot(x)
 
+	case *msg.RootDelta: // <- RootDelta (root, delta)
+		return c.handleRootDelta(x)
+
+	case *msg.HistoryRoot: // <- HistoryRoot (root)
+		return c.handleHistoryRoot(x)
+
 	// objects
 
 	case *msg.RqObject: // <- RqO (key, prefetch)
@@ -610,6 +808,16 @@ func (c *Conn) handle(seq uint32, m msg.Msg) (err error) {
 		go c.handleRqObject(seq, x)
 		return
 
+	case *msg.RqObjects: // <- RqOs (keys)
+		c.await.Add(1)
+		go c.handleRqObjects(seq, x)
+		return
+
+	case *msg.RqTree: // <- RqT (root, skip, limit)
+		c.await.Add(1)
+		go c.handleRqTree(seq, x)
+		return
+
 	// preview
 
 	case *msg.RqPreview: // -> RqPreview (feed)
@@ -623,6 +831,7 @@ func (c *Conn) handle(seq uint32, m msg.Msg) (err error) {
 	// ErrTimeout and noone waits them
 
 	case *msg.Object: // -> O (delayed)
+	case *msg.Objects: // -> Os (delayed)
 	case *msg.Err: // -> Err (delayed)
 	case *msg.Ok: // -> Ok (delayed)
 	case *msg.List: // -> List (delayed)
@@ -638,25 +847,64 @@ func (c *Conn) handle(seq uint32, m msg.Msg) (err error) {
 }
 
 // subscribe (with reply)
-func (c *Conn) handleSub(seq uint32, sub *msg.Sub) (_ error) {
+func (c *Conn) handleSub(seq uint32, sub *msg.Sub) (err error) {
 
 	c.n.Debugf(MsgReceivePin, "[%s] handleSub %s",
 		c.String(), sub.Feed.Hex()[:7])
 
+	var accepted bool
+	if accepted, err = c.acceptSub(seq, sub.Feed); accepted == true {
+		c.sendLastRoot(sub.Feed) // and push last Root
+	}
+
+	return
+}
+
+// subscribe and send history (with reply)
+func (c *Conn) handleSubFrom(seq uint32, sf *msg.SubFrom) (err error) {
+
+	c.n.Debugf(MsgReceivePin, "[%s] handleSubFrom %s/%d from %d (%d)",
+		c.String(), sf.Feed.Hex()[:7], sf.Nonce, sf.Seq, sf.Time)
+
+	var accepted bool
+	if accepted, err = c.acceptSub(seq, sf.Feed); accepted == true {
+		c.await.Add(1)
+		go c.sendHistory(sf) // history and last Root
+	}
+
+	return
+}
+
+// reply for Sub or SubFrom, the accepted is true if
+// the Ok has been sent and the connection subscribed
+func (c *Conn) acceptSub(
+	seq uint32,
+	feed cipher.PubKey,
+) (
+	accepted bool,
+	err error,
+) {
+
 	// don't allow blank
 
-	if sub.Feed == (cipher.PubKey{}) {
-		return errors.New("blank public key") // fatal (invalid request)
+	if feed == (cipher.PubKey{}) {
+		return false, errors.New("blank public key") // fatal (invalid request)
 	}
 
 	// check first
-	if c.n.fs.hasConnFeed(c, sub.Feed) == true {
+	if c.n.fs.hasConnFeed(c, feed) == true {
 		c.sendOk(seq) // already subscribed
+		return true, nil
+	}
+
+	// reject subscription by ACL of the feed
+	if c.n.as.allowed(c, feed, aclSubscribe) == false {
+		c.sendErr(seq, ErrAccessDenied)
 		return
 	}
 
 	// callback
-	var reject = c.n.onSubscribeRemote(c, sub.Feed)
+	var reject = c.n.onSubscribeRemote(c, feed)
 
 	// reject subscription by callback
 	if reject != nil {
@@ -668,19 +916,88 @@ func (c *Conn) handleSub(seq uint32, sub *msg.Sub) (_ error) {
 	// and anyway we can't subscribe to a feed we don't
 	// share
 
-	if c.n.fs.hasFeed(sub.Feed) == false {
+	if c.n.fs.hasFeed(feed) == false {
 		c.sendErr(seq, errors.New("do not share the feed"))
 		return
 	}
 
 	// ok
 
-	c.n.fs.addConnFeed(c, sub.Feed)
+	c.n.fs.addConnFeed(c, feed)
 	c.sendOk(seq)
 
-	c.sendLastRoot(sub.Feed) // and push last Root
+	return true, nil
+}
+
+// async, push Root objects requested by SubFrom
+// in order, and push last Root after
+func (c *Conn) sendHistory(sf *msg.SubFrom) {
+	defer c.await.Done()
+	defer c.sendLastRoot(sf.Feed)
+
+	var limit = c.n.config.MaxHistory
+
+	if limit == 0 {
+		return // turned off
+	}
+
+	if sf.Limit > 0 && int(sf.Limit) < limit {
+		limit = int(sf.Limit)
+	}
+
+	var (
+		nonce = sf.Nonce
+		seqs  []uint64
+	)
+
+	if nonce == 0 {
+		nonce = c.n.c.ActiveHead(sf.Feed)
+	}
+
+	// collect seq numbers first, the Root method of
+	// the Container can't be used inside the Tx
+
+	var err = c.n.c.DB().IdxDB().Tx(func(fs data.Feeds) (err error) {
+
+		var hs data.Heads
+		if hs, err = fs.Heads(sf.Feed); err != nil {
+			return
+		}
+
+		var roots data.Roots
+		if roots, err = hs.Roots(nonce); err != nil {
+			return
+		}
+
+		return roots.Ascend(func(dr *data.Root) (err error) {
+			if dr.Seq < sf.Seq || dr.Time < sf.Time {
+				return // skip
+			}
+			if len(seqs) == limit {
+				return data.ErrStopIteration
+			}
+			seqs = append(seqs, dr.Seq)
+			return
+		})
+
+	})
+
+	if err != nil {
+		c.n.Debugf(MsgSendPin, "[%s] sendHistory %s/%d: %v", c.String(),
+			sf.Feed.Hex()[:7], nonce, err)
+		return
+	}
+
+	for _, seq := range seqs {
+
+		var r *registry.Root
+		if r, err = c.n.c.Root(sf.Feed, nonce, seq); err != nil {
+			return // removed
+		}
+
+		c.sendMsg(c.nextSeq(), 0, &msg.HistoryRoot{Root: rootMsg(r)})
+	}
 
-	return
 }
 
 // unsubscribe (no reply)
@@ -720,6 +1037,103 @@ func (c *Conn) handleRoot(root *msg.Root) (_ error) {
 	c.n.Debugf(MsgReceivePin, "[%s] handleRoot %s/%d/%d",
 		c.String(), root.Feed.Hex()[:7], root.Nonce, root.Seq)
 
+	return c.receivedRoot(root, nil)
+}
+
+// got Root with delta
+func (c *Conn) handleRootDelta(rd *msg.RootDelta) (_ error) {
+
+	c.n.Debugf(MsgReceivePin, "[%s] handleRootDelta %s/%d/%d, %d keys",
+		c.String(), rd.Root.Feed.Hex()[:7], rd.Root.Nonce, rd.Root.Seq,
+		len(rd.Delta))
+
+	return c.receivedRoot(&rd.Root, rd.Delta)
+}
+
+// got Root requested by SubFrom
+func (c *Conn) handleHistoryRoot(hr *msg.HistoryRoot) (_ error) {
+
+	c.n.Debugf(MsgReceivePin, "[%s] handleHistoryRoot %s/%d/%d",
+		c.String(), hr.Root.Feed.Hex()[:7], hr.Root.Nonce, hr.Root.Seq)
+
+	if c.isFlood() == true {
+		return // drop
+	}
+
+	var r, err = c.n.c.ReceivedRoot(hr.Root.Feed, hr.Root.Sig, hr.Root.Value)
+
+	if err != nil {
+		c.invalidRoot(err)
+		return
+	}
+
+	// do nothing, because the Node already have this Root
+	if r.IsFull == true {
+		return
+	}
+
+	// fill in order
+	c.n.fs.receivedHistoryRoot(c, r)
+	return
+}
+
+// error of received Root
+func (c *Conn) invalidRoot(err error) {
+
+	c.n.Printf("[ERR] [%s] received Root error: %s", c.String(), err)
+
+	switch err {
+	case data.ErrNoSuchFeed:
+		// unsubscribed
+	case skyobject.ErrRevokedDelegation:
+		// can be pushed by an honest peer that
+		// doesn't know about the revocation yet
+	default:
+		c.n.bs.violation(c, ViolationRoot, err)
+	}
+
+}
+
+// check rate of received Root objects, the isFlood
+// returns true if the Root should be dropped; it's
+// called from receiving loop only
+func (c *Conn) isFlood() (flood bool) {
+
+	var limit = c.n.config.MaxRootsPerSecond
+
+	if limit == 0 {
+		return // turned off
+	}
+
+	var now = time.Now()
+
+	if now.Sub(c.rootsTime) >= time.Second {
+		c.rootsTime, c.roots = now, 0
+	}
+
+	if c.roots++; c.roots <= limit {
+		return
+	}
+
+	if c.roots == limit+1 {
+		c.n.bs.violation(c, ViolationFlood,
+			fmt.Errorf("more then %d Root objects per second", limit))
+	}
+
+	return true
+}
+
+func (c *Conn) receivedRoot(
+	root *msg.Root,
+	delta []cipher.SHA256,
+) (
+	_ error,
+) {
+
+	if c.isFlood() == true {
+		return // drop
+	}
+
 	// check seq first (avoid verify-signature for old unwanted Root objects)
 
 	var last, err = c.n.c.LastRootSeq(root.Feed, root.Nonce) // last is full
@@ -746,8 +1160,8 @@ func (c *Conn) handleRoot(root *msg.Root) (_ error) {
 	r, err = c.n.c.ReceivedRoot(root.Feed, root.Sig, root.Value)
 
 	if err != nil {
-		c.n.Printf("[ERR] [%s] received Root error: %s", c.String(), err)
-		return // keep connection ?
+		c.invalidRoot(err)
+		return // keep connection
 	}
 
 	// do nothing, because the Node already have this Root
@@ -757,7 +1171,7 @@ func (c *Conn) handleRoot(root *msg.Root) (_ error) {
 
 	// fill the Root only if the node and the connection
 	// subscribed to feed of the Root
-	c.n.fs.receivedRoot(c, r)
+	c.n.fs.receivedRoot(c, r, delta)
 	return
 }
 
@@ -768,6 +1182,11 @@ func (c *Conn) handleRqObject(seq uint32, rq *msg.RqObject) {
 	c.n.Debugf(MsgReceivePin, "[%s] handleRqObject %s", c.String(),
 		rq.Key.Hex()[:7])
 
+	if c.canFetch() == false {
+		c.sendErr(seq, ErrAccessDenied)
+		return
+	}
+
 	var (
 		gc = make(chan skyobject.Object, 1)
 
@@ -815,11 +1234,208 @@ func (c *Conn) handleRqObject(seq uint32, rq *msg.RqObject) {
 	return
 }
 
+// async
+func (c *Conn) handleRqObjects(seq uint32, rq *msg.RqObjects) {
+	defer c.await.Done()
+
+	c.n.Debugf(MsgReceivePin, "[%s] handleRqObjects %d", c.String(),
+		len(rq.Keys))
+
+	if c.canFetch() == false {
+		c.sendErr(seq, ErrAccessDenied)
+		return
+	}
+
+	var (
+		gc   = make(chan skyobject.Object, len(rq.Keys))
+		objs = make(map[cipher.SHA256][]byte, len(rq.Keys))
+
+		tm *time.Timer
+		tc <-chan time.Time
+	)
+
+	// see TODOs of the handleRqObject
+
+	for _, key := range rq.Keys {
+
+		if _, ok := objs[key]; ok == true {
+			continue // repeated key
+		}
+
+		objs[key] = nil
+
+		if err := c.n.c.Want(key, gc, 0); err != nil {
+			c.n.Fatal("DB failure: ", err)
+		}
+
+	}
+
+	defer func() {
+		for key := range objs {
+			c.n.c.Unwant(key, gc) // to be memory safe
+		}
+	}()
+
+	// reply with objects the Node has, before the requester
+	// drops the request by timeout
+
+	if rt := c.responseTimeout(); rt > 0 {
+		tm = time.NewTimer(rt / 2)
+		tc = tm.C
+
+		defer tm.Stop()
+	}
+
+	for got := 0; got < len(objs); got++ {
+
+		select {
+		case obj := <-gc:
+			objs[obj.Key] = obj.Val
+			continue
+		case <-tc:
+		case <-c.closeq:
+			return // closed
+		}
+
+		break // timeout
+	}
+
+	var (
+		reply = &msg.Objects{Values: make([][]byte, len(rq.Keys))}
+		vol   int
+	)
+
+	for i, key := range rq.Keys {
+
+		var val = objs[key]
+
+		// an object that doesn't fit the limit is left
+		// blank, even if it's the only one (it can't be
+		// sent anyway)
+
+		if vol+len(val) > c.n.config.MaxResponseVolume {
+			continue // leave blank
+		}
+
+		reply.Values[i] = val
+		vol += len(val) + objectOverhead
+
+	}
+
+	c.sendMsg(c.nextSeq(), seq, reply)
+}
+
+// async
+func (c *Conn) handleRqTree(seq uint32, rq *msg.RqTree) {
+	defer c.await.Done()
+
+	c.n.Debugf(MsgReceivePin, "[%s] handleRqTree %s, skip %d", c.String(),
+		rq.Root.Hex()[:7], rq.Skip)
+
+	var r, err = c.n.c.RootByHash(rq.Root)
+
+	if err != nil {
+		c.sendErr(seq, err)
+		return
+	}
+
+	if c.n.as.allowed(c, r.Pub, aclFetch) == false || c.canFetch() == false {
+		c.sendErr(seq, ErrAccessDenied)
+		return
+	}
+
+	var limit = int(rq.Limit)
+
+	if limit <= 0 || limit > c.n.config.MaxResponseVolume {
+		limit = c.n.config.MaxResponseVolume
+	}
+
+	var (
+		reply = new(msg.Objects)
+		vol   int
+		n     uint32
+
+		// order of walking is the same for every RqTree
+		// of the Root, since the Root is full; but an
+		// object can be walked many times
+		seen = make(map[cipher.SHA256]struct{})
+	)
+
+	err = c.n.c.Walk(r,
+		func(
+			key cipher.SHA256,
+			_ int,
+		) (
+			deepper bool,
+			err error,
+		) {
+
+			if _, ok := seen[key]; ok == true {
+				return // already
+			}
+
+			seen[key] = struct{}{}
+
+			if n++; n <= rq.Skip {
+				return true, nil // skip
+			}
+
+			var val []byte
+			if val, _, err = c.n.c.Get(key, 0); err != nil {
+				return
+			}
+
+			// an object that doesn't fit the limit stops the
+			// prefetching (empty reply), and the requester
+			// should request it by key
+			if vol+len(val) > limit {
+				return false, registry.ErrStopIteration
+			}
+
+			reply.Values = append(reply.Values, val)
+			vol += len(val) + objectOverhead
+
+			return true, nil
+		})
+
+	if err != nil {
+		c.sendErr(seq, err) // removed during the walking
+		return
+	}
+
+	c.sendMsg(c.nextSeq(), seq, reply)
+}
+
+// can the peer fetch objects, the peer can fetch objects
+// if it's subscribed to, or has previewed, a feed ACL of
+// which allows it
+func (c *Conn) canFetch() bool {
+
+	if c.n.as.isEmpty() == true {
+		return true // no ACLs
+	}
+
+	var feeds = c.n.fs.feedsOfConnection(c)
+
+	c.mx.Lock()
+	for pk := range c.previews {
+		feeds = append(feeds, pk)
+	}
+	c.mx.Unlock()
+
+	return c.n.as.canFetch(c, feeds)
+}
+
 func (c *Conn) handleRqPreview(seq uint32, rqp *msg.RqPreview) (_ error) {
 
 	c.n.Debugf(MsgReceivePin, "[%s] handleRqPreview %s", c.String(),
 		rqp.Feed.Hex()[:7])
 
+	if c.n.as.allowed(c, rqp.Feed, aclPreview) == false {
+		c.sendErr(seq, ErrAccessDenied)
+		return
+	}
+
 	var r, err = c.n.c.LastRoot(rqp.Feed, c.n.c.ActiveHead(rqp.Feed))
 
 	if err != nil {
@@ -827,6 +1443,11 @@ func (c *Conn) handleRqPreview(seq uint32, rqp *msg.RqPreview) (_ error) {
 		return
 	}
 
+	// allow fetching objects of the feed
+	c.mx.Lock()
+	c.previews[rqp.Feed] = struct{}{}
+	c.mx.Unlock()
+
 	c.sendMsg(c.nextSeq(), seq, &msg.Root{
 		Feed:  r.Pub,
 		Nonce: r.Nonce,
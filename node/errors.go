@@ -16,4 +16,8 @@ var (
 	ErrMaxHeadsLimit           = errors.New("max heads limit")
 	ErrUnsubscribe             = errors.New("unsubscribe")
 	ErrBlankFeed               = errors.New("blank feed")
+	ErrNoSuchRegistry          = errors.New("no such registry")
+	ErrBanned                  = errors.New("banned")
+	ErrAccessDenied            = errors.New("access denied")
+	ErrNotSupported            = errors.New("not supported by peer")
 )
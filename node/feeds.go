@@ -10,8 +10,10 @@ import (
 
 // connection and received Root
 type connRoot struct {
-	c *Conn
-	r *registry.Root
+	c       *Conn
+	r       *registry.Root
+	delta   []cipher.SHA256 // new objects of the r (can be nil)
+	history bool            // requested by SubFrom
 }
 
 // connection and feed
@@ -406,10 +408,24 @@ func (n *nodeFeeds) handleDelConn(c *Conn) {
 }
 
 // (api)
-func (n *nodeFeeds) receivedRoot(c *Conn, r *registry.Root) {
+func (n *nodeFeeds) receivedRoot(
+	c *Conn,
+	r *registry.Root,
+	delta []cipher.SHA256,
+) {
+
+	select {
+	case n.rrq <- connRoot{c: c, r: r, delta: delta}:
+	case <-n.closeq:
+	}
+
+}
+
+// (api) Root requested by SubFrom
+func (n *nodeFeeds) receivedHistoryRoot(c *Conn, r *registry.Root) {
 
 	select {
-	case n.rrq <- connRoot{c, r}:
+	case n.rrq <- connRoot{c: c, r: r, history: true}:
 	case <-n.closeq:
 	}
 
@@ -1,13 +1,41 @@
 package node
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
 
+	"github.com/skycoin/skycoin/src/cipher"
+
 	"github.com/skycoin/cxo/node/msg"
 )
 
+// handshake
+//
+// (1) -> Syn  (protocols, node id, challenge, caps, codecs)
+// (2) <- Ack  (node id, challenge, protocol, caps, codec, sig) or Err
+// (3) -> Auth (sig of AuthHash)
+// (4) <- Ok or Err
+//
+// After the (3) both sides are sure that peer owns
+// secret key of its id. All messeges after the (3),
+// including the Ok, are encrypted using session keys
+// derived from ECDH shared secret and hash of entire
+// handshake. The (4) sent by accepting side after
+// the connection has been added to the Node. Thus,
+// a connection returned by Connect is accepted by
+// remote peer. Protocol version, capabilities (see
+// caps.go) and compression codec (see compress.go)
+// negotiated by the Syn and the Ack are used after
+// the handshake.
+//
+// A peer of msg.LegacyVersion sends msg.LegacySyn
+// and receives msg.LegacyAck in response. Such
+// connections are not authenticated and encrypted
+// and accepted only if Config.RequireEncryption
+// is false
+
 func (c *Conn) handshake(nodeCloseq <-chan struct{}) (err error) {
 
 	c.n.Debugf(ConnHskPin, "[%s] handshake", c.String())
@@ -36,41 +64,58 @@ func (c *Conn) sendNodeCloseq(
 
 }
 
-func (c *Conn) performHandshake(nodeCloseq <-chan struct{}) (err error) {
+// send messege, encrypting it if the c.cc is set; the
+// Conn should not be shared yet, or lock of the c.cc
+// should be held
+func (c *Conn) sendMsgNodeCloseq(
+	seq uint32,
+	rseq uint32,
+	m msg.Msg,
+	nodeCloseq <-chan struct{},
+) (
+	err error,
+) {
 
-	c.n.Debugf(ConnHskPin, "[%s] performHandshake", c.String())
+	var raw = c.encodeMsg(seq, rseq, m)
 
-	// (1) send Syn
-	// (2) receive Ack or Err
+	if c.cc != nil {
+		raw = c.cc.encrypt(raw)
+	}
 
-	var seq = c.nextSeq()
+	return c.sendNodeCloseq(raw, nodeCloseq)
+}
 
-	err = c.sendNodeCloseq(
-		c.encodeMsg(seq, 0, &msg.Syn{
-			Protocol: msg.Version,
-			NodeID:   c.n.idpk,
-		}),
-		nodeCloseq,
-	)
+// send Err and return given error
+func (c *Conn) rejectHandshake(
+	rseq uint32,
+	reason error,
+	nodeCloseq <-chan struct{},
+) (
+	err error,
+) {
 
-	if err != nil {
-		return
-	}
+	c.sendMsgNodeCloseq(c.nextSeq(), rseq, &msg.Err{Err: reason.Error()},
+		nodeCloseq)
 
-	var (
-		rt time.Duration
+	return reason
+}
 
+// receive raw handshake messege
+func (c *Conn) receiveRawNodeCloseq(
+	nodeCloseq <-chan struct{},
+) (
+	raw []byte,
+	err error,
+) {
+
+	var (
 		tm *time.Timer
 		tc <-chan time.Time
-	)
 
-	if c.IsTCP() == true {
-		rt = c.n.config.TCP.ResponseTimeout
-	} else {
-		rt = c.n.config.UDP.ResponseTimeout
-	}
+		ok bool
+	)
 
-	if rt > 0 {
+	if rt := c.responseTimeout(); rt > 0 {
 		tm = time.NewTimer(rt)
 		tc = tm.C
 
@@ -78,52 +123,174 @@ func (c *Conn) performHandshake(nodeCloseq <-chan struct{}) (err error) {
 	}
 
 	select {
-
-	case raw, ok := <-c.GetChanIn():
-
+	case raw, ok = <-c.GetChanIn():
 		if ok == false {
-			return ErrClosed
+			err = ErrClosed
 		}
+	case <-tc:
+		err = ErrTimeout
+	case <-nodeCloseq:
+		err = ErrClosed
+	}
 
-		var (
-			rseq uint32
-			m    msg.Msg
-		)
+	return
+}
 
-		if _, rseq, m, err = c.decodeRaw(raw); err != nil {
-			return
-		}
+// receive a handshake messege; if the c.cc is set,
+// then the messege can be encrypted or can be a
+// plain Err (if peer rejects the handshake)
+func (c *Conn) receiveNodeCloseq(
+	nodeCloseq <-chan struct{},
+) (
+	seq uint32,
+	rseq uint32,
+	m msg.Msg,
+	err error,
+) {
 
-		if rseq != seq {
-			return errors.New("invlaid resposne for handshake: wrong seq")
-		}
+	var raw []byte
+
+	if raw, err = c.receiveRawNodeCloseq(nodeCloseq); err != nil {
+		return
+	}
+
+	if c.cc == nil {
+		return c.decodeRaw(raw)
+	}
+
+	var dec []byte
+	if dec, err = c.cc.decrypt(raw); err == nil {
+		return c.decodeRaw(dec)
+	}
 
-		switch x := m.(type) {
+	// only Err can be sent in plain (if peer rejects handshake)
 
-		case *msg.Ack:
+	var ps, prs, pm, perr = c.decodeRaw(raw)
 
-			c.peerID = x.NodeID
+	if _, isErr := pm.(*msg.Err); perr == nil && isErr == true {
+		return ps, prs, pm, nil
+	}
 
-			return // ok
+	return // decrypting error
+}
 
-		case *msg.Err:
+// random challenge
+func newChallenge() (challenge cipher.SHA256) {
+	return cipher.SumSHA256(cipher.RandByte(32))
+}
 
-			return errors.New(x.Err)
+// verify signature of the peer
+func (c *Conn) verifyPeer(hash cipher.SHA256, sig cipher.Sig) (err error) {
 
-		default:
+	if err = cipher.VerifySignature(c.peerID, sig, hash); err != nil {
+		err = fmt.Errorf("can't authenticate peer %s: %v",
+			c.peerID.Hex()[:7], err)
+	}
 
-			return fmt.Errorf("invalid response type for handshake: %T", m)
+	return
+}
 
+func (c *Conn) performHandshake(nodeCloseq <-chan struct{}) (err error) {
+
+	c.n.Debugf(ConnHskPin, "[%s] performHandshake", c.String())
+
+	// (1) send Syn
+	// (2) receive Ack or Err
+	// (3) send Auth
+	// (4) receive Ok or Err
+
+	var (
+		_, idpk, idsk = c.n.identity() // the same during the handshake
+
+		seq = c.nextSeq()
+		syn = &msg.Syn{
+			Protocol:    msg.Version,
+			NodeID:      idpk,
+			Challenge:   newChallenge(),
+			MinProtocol: msg.MinVersion,
+			Caps:        c.n.capabilities(),
+			Codecs:      c.n.codecs(),
 		}
+	)
 
-	case <-tc:
+	if err = c.sendMsgNodeCloseq(seq, 0, syn, nodeCloseq); err != nil {
+		return
+	}
 
-		return ErrTimeout
+	// (2)
 
-	case <-nodeCloseq:
+	var (
+		aseq, rseq uint32
+		m          msg.Msg
+	)
 
-		return ErrClosed
+	if aseq, rseq, m, err = c.receiveNodeCloseq(nodeCloseq); err != nil {
+		return
+	}
+
+	if rseq != seq {
+		return errors.New("invlaid resposne for handshake: wrong seq")
+	}
+
+	var ack *msg.Ack
+
+	switch x := m.(type) {
+	case *msg.Ack:
+		ack = x
+	case *msg.Err:
+		return errors.New(x.Err)
+	default:
+		return fmt.Errorf("invalid response type for handshake: %T", m)
+	}
 
+	c.peerID = ack.NodeID
+
+	if err = c.verifyPeer(msg.AckHash(syn, ack), ack.Sig); err != nil {
+		return
+	}
+
+	if err = checkNegotiated(syn, ack); err != nil {
+		return
+	}
+
+	// (3)
+
+	var transcript = msg.AuthHash(syn, ack)
+
+	seq = c.nextSeq()
+
+	err = c.sendMsgNodeCloseq(seq, aseq, &msg.Auth{
+		Sig: cipher.SignHash(transcript, idsk),
+	}, nodeCloseq)
+
+	if err != nil {
+		return
+	}
+
+	c.cc, err = newConnCipher(idsk, c.peerID, transcript, false)
+
+	if err != nil {
+		return
+	}
+
+	// (4)
+
+	if _, rseq, m, err = c.receiveNodeCloseq(nodeCloseq); err != nil {
+		return
+	}
+
+	if rseq != seq {
+		return errors.New("invlaid resposne for handshake: wrong seq")
+	}
+
+	switch x := m.(type) {
+	case *msg.Ok:
+		c.protocol, c.caps = ack.Protocol, ack.Caps // accepted
+		return c.setCodec(ack.Codec)                // checked above
+	case *msg.Err:
+		return errors.New(x.Err)
+	default:
+		return fmt.Errorf("invalid response type for handshake: %T", m)
 	}
 
 }
@@ -134,23 +301,26 @@ func (c *Conn) acceptHandshake(nodeCloseq <-chan struct{}) (err error) {
 
 	// (1) receive the Syn
 	// (2) send the Ack or Err
+	// (3) receive the Auth
+	//
+	// the (4) is acceptConnection
 
-	// (1)
+	var raw []byte
 
-	var (
-		raw []byte
-		ok  bool
-	)
+	if raw, err = c.receiveRawNodeCloseq(nodeCloseq); err != nil {
+		return
+	}
 
-	select {
-	case raw, ok = <-c.GetChanIn():
+	// [ 4 seq ][ 4 rseq ][ 1 msg type ]
 
-		if ok == false {
-			return ErrClosed
+	if len(raw) > 8 {
+		if lsyn, lerr := msg.DecodeLegacySyn(raw[8:]); lerr == nil {
+			return c.acceptLegacyHandshake(
+				binary.LittleEndian.Uint32(raw),
+				lsyn,
+				nodeCloseq,
+			)
 		}
-
-	case <-nodeCloseq:
-		return ErrClosed
 	}
 
 	var (
@@ -162,47 +332,138 @@ func (c *Conn) acceptHandshake(nodeCloseq <-chan struct{}) (err error) {
 		return
 	}
 
-	switch x := m.(type) {
+	var syn, isSyn = m.(*msg.Syn)
 
-	case *msg.Syn:
+	if isSyn == false {
+		return c.rejectHandshake(seq, fmt.Errorf(
+			"invalid messege type received (expected handshake): %T",
+			m,
+		), nodeCloseq)
+	}
 
-		if x.Protocol != msg.Version {
+	var protocol, caps, nerr = c.n.negotiate(syn)
 
-			err = fmt.Errorf("incompatible protocol version: %d, want %d",
-				x.Protocol,
-				msg.Version)
+	if nerr != nil {
+		return c.rejectHandshake(seq, nerr, nodeCloseq)
+	}
 
-			// send Err back
+	var codec string
 
-			c.sendNodeCloseq(
-				c.encodeMsg(c.nextSeq(), seq, &msg.Err{Err: err.Error()}),
-				nodeCloseq,
-			)
+	if hasCap(caps, msg.CapCompression) == true {
+		if codec = c.n.chooseCodec(syn.Codecs); codec == "" {
+			caps = withoutCap(caps, msg.CapCompression) // no common codec
+		}
+	}
 
-			return
+	c.peerID = syn.NodeID
 
+	// (2) send Ack back
+
+	var (
+		_, idpk, idsk = c.n.identity() // the same during the handshake
+
+		aseq = c.nextSeq()
+		ack  = &msg.Ack{
+			NodeID:    idpk,
+			Challenge: newChallenge(),
+			Protocol:  protocol,
+			Caps:      caps,
+			Codec:     codec,
 		}
+	)
 
-		c.peerID = x.NodeID
+	ack.Sig = cipher.SignHash(msg.AckHash(syn, ack), idsk)
 
-		// (2) send Ack back
+	if err = c.sendMsgNodeCloseq(aseq, seq, ack, nodeCloseq); err != nil {
+		return
+	}
+
+	// (3) receive Auth
 
-		err = c.sendNodeCloseq(
-			c.encodeMsg(c.nextSeq(), seq, &msg.Ack{
-				NodeID: c.n.idpk,
-			}),
-			nodeCloseq,
-		)
+	var rseq uint32
 
+	if c.hseq, rseq, m, err = c.receiveNodeCloseq(nodeCloseq); err != nil {
 		return
+	}
 
-	default:
+	if rseq != aseq {
+		return c.rejectHandshake(c.hseq,
+			errors.New("invlaid resposne for handshake: wrong seq"),
+			nodeCloseq)
+	}
 
-		return fmt.Errorf(
-			"invalid messege type received (expected handshake): %T",
-			m,
-		)
+	var auth, isAuth = m.(*msg.Auth)
+
+	if isAuth == false {
+		return c.rejectHandshake(c.hseq,
+			fmt.Errorf("invalid response type for handshake: %T", m),
+			nodeCloseq)
+	}
+
+	var transcript = msg.AuthHash(syn, ack)
+
+	if err = c.verifyPeer(transcript, auth.Sig); err != nil {
+		return c.rejectHandshake(c.hseq, err, nodeCloseq)
+	}
+
+	if c.cc, err = newConnCipher(idsk, c.peerID, transcript, true); err != nil {
+		return
+	}
+
+	c.protocol, c.caps = ack.Protocol, ack.Caps
+
+	// the Ok is too small to be compressed
+	return c.setCodec(ack.Codec)
 
+}
+
+// a peer of the LegacyVersion can't authenticate and
+// encrypt connection; the peer is accepted only if the
+// Node doesn't require encryption
+func (c *Conn) acceptLegacyHandshake(
+	seq uint32,
+	syn *msg.LegacySyn,
+	nodeCloseq <-chan struct{},
+) (
+	err error,
+) {
+
+	if c.n.config.RequireEncryption == true {
+		return c.rejectHandshake(seq, fmt.Errorf(
+			"incompatible protocol version: %d, want %d (encryption required)",
+			syn.Protocol,
+			msg.Version,
+		), nodeCloseq)
 	}
 
+	c.n.Debugf(ConnHskPin, "[%s] legacy peer %s, not encrypted",
+		c.String(), syn.NodeID.Hex()[:7])
+
+	c.peerID = syn.NodeID
+	c.protocol = msg.LegacyVersion // without capabilities
+
+	return c.sendMsgNodeCloseq(c.nextSeq(), seq, &msg.LegacyAck{
+		NodeID: c.n.ID(),
+	}, nodeCloseq)
+}
+
+// (4) add accepted connection to the Node and send Ok,
+// or send Err if the Node rejects the connection; the
+// lock of the c.cc held to be sure that the Ok is the
+// first encrypted messege sent
+func (c *Conn) acceptConnection(nodeCloseq <-chan struct{}) (err error) {
+
+	if c.cc == nil {
+		return c.n.addConnection(c) // legacy peer, there is not the (4)
+	}
+
+	c.cc.smx.Lock()
+	defer c.cc.smx.Unlock()
+
+	if err = c.n.addConnection(c); err != nil {
+		return c.rejectHandshake(c.hseq, err, nodeCloseq)
+	}
+
+	c.sendMsgNodeCloseq(c.nextSeq(), c.hseq, &msg.Ok{}, nodeCloseq)
+	return
 }
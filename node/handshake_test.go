@@ -0,0 +1,224 @@
+package node
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/node/msg"
+)
+
+func connectTestNodes(
+	t *testing.T,
+	sn, cn *Node,
+	isTCP bool,
+) (
+	c *Conn,
+	err error,
+) {
+
+	t.Helper()
+
+	if isTCP == true {
+		return cn.TCP().Connect(sn.TCP().Address())
+	}
+
+	return cn.UDP().Connect(sn.UDP().Address())
+}
+
+func Test_handshake(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) { testHandshake(t, true) })
+	t.Run("udp", func(t *testing.T) {
+		// TODO (kostyarin): UDP connections of the factory established
+		//                   without a discovery server have not
+		//                   factory-level crypto and can't deliver
+		//                   messeges (the same is true for handshake
+		//                   of the legacy protocol)
+		t.Skip("UDP requires connection to a discovery server")
+		testHandshake(t, false)
+	})
+}
+
+func testHandshake(t *testing.T, isTCP bool) {
+
+	var (
+		sn = getTestNode("server")
+		cn = getTestNodeNotListen("client")
+	)
+
+	defer sn.Close()
+	defer cn.Close()
+
+	var c, err = connectTestNodes(t, sn, cn, isTCP)
+	assertNil(t, err)
+
+	// the Connect returns after the connection
+	// has been accepted by the server
+
+	assertTrue(t, c.PeerID() == sn.ID(), "wrong peer id")
+	assertTrue(t, c.IsEncrypted() == true, "not encrypted")
+
+	var scs = sn.Connections()
+	assertTrue(t, len(scs) == 1, "wrong number of connections")
+	assertTrue(t, scs[0].PeerID() == cn.ID(), "wrong peer id")
+	assertTrue(t, scs[0].IsEncrypted() == true, "not encrypted")
+
+	// request-response through the encrypted connection
+
+	var pk, _ = cipher.GenerateKeyPair()
+	assertNil(t, sn.Share(pk))
+	assertNil(t, c.Subscribe(pk))
+
+}
+
+// a node that claims ID of another node
+func Test_handshake_spoofing(t *testing.T) {
+
+	var victim, _ = cipher.GenerateKeyPair()
+
+	t.Run("initiator", func(t *testing.T) {
+
+		var (
+			sn = getTestNode("server")
+			cn = getTestNodeNotListen("client")
+		)
+
+		defer sn.Close()
+		defer cn.Close()
+
+		cn.idpk = victim // secret key of the client doesn't match
+
+		if _, err := cn.TCP().Connect(sn.TCP().Address()); err == nil {
+			t.Fatal("missing error")
+		}
+
+		assertTrue(t, len(cn.Connections()) == 0, "unexpected connections")
+		assertTrue(t, len(sn.Connections()) == 0, "unexpected connections")
+
+	})
+
+	t.Run("acceptor", func(t *testing.T) {
+
+		var (
+			sn = getTestNode("server")
+			cn = getTestNodeNotListen("client")
+		)
+
+		defer sn.Close()
+		defer cn.Close()
+
+		sn.idpk = victim // secret key of the server doesn't match
+
+		if _, err := cn.TCP().Connect(sn.TCP().Address()); err == nil {
+			t.Fatal("missing error")
+		}
+
+		assertTrue(t, len(cn.Connections()) == 0, "unexpected connections")
+
+		// the server waits for Auth
+		<-time.After(TM)
+
+		assertTrue(t, len(sn.Connections()) == 0, "unexpected connections")
+
+	})
+
+}
+
+func Test_handshake_transcript(t *testing.T) {
+
+	var (
+		pk, sk = cipher.GenerateKeyPair()
+
+		syn = &msg.Syn{
+			Protocol:  msg.Version,
+			NodeID:    pk,
+			Challenge: newChallenge(),
+		}
+		ack = &msg.Ack{
+			NodeID:    pk,
+			Challenge: newChallenge(),
+		}
+	)
+
+	ack.Sig = cipher.SignHash(msg.AckHash(syn, ack), sk)
+
+	var (
+		auth       = cipher.SignHash(msg.AuthHash(syn, ack), sk)
+		transcript = msg.AuthHash(syn, ack)
+	)
+
+	assertNil(t, cipher.VerifySignature(pk, ack.Sig, msg.AckHash(syn, ack)))
+	assertNil(t, cipher.VerifySignature(pk, auth, transcript))
+
+	// change the Syn (e.g. the protocol version)
+
+	syn.Protocol++
+
+	assertTrue(t,
+		cipher.VerifySignature(pk, ack.Sig, msg.AckHash(syn, ack)) != nil,
+		"signature of modified Syn is valid")
+	assertTrue(t,
+		cipher.VerifySignature(pk, auth, msg.AuthHash(syn, ack)) != nil,
+		"signature of modified Syn is valid")
+	assertTrue(t, msg.AuthHash(syn, ack) != transcript,
+		"the same transcript")
+
+}
+
+func Test_connCipher(t *testing.T) {
+
+	var (
+		apk, ask = cipher.GenerateKeyPair()
+		bpk, bsk = cipher.GenerateKeyPair()
+
+		transcript = newChallenge()
+	)
+
+	var a, err = newConnCipher(ask, bpk, transcript, false)
+	assertNil(t, err)
+
+	var b *connCipher
+	b, err = newConnCipher(bsk, apk, transcript, true)
+	assertNil(t, err)
+
+	var (
+		raw = []byte("hey-ho!")
+		enc = a.encrypt(raw)
+		dec []byte
+	)
+
+	dec, err = b.decrypt(enc)
+	assertNil(t, err)
+	assertTrue(t, string(dec) == string(raw), "wrong decrypted messege")
+
+	// replay
+	_, err = b.decrypt(enc)
+	assertTrue(t, err != nil, "missing error")
+
+	// tampered
+	enc = b.encrypt(raw)
+	enc[len(enc)-1]++
+	_, err = a.decrypt(enc)
+	assertTrue(t, err != nil, "missing error")
+
+	// wrong keys
+
+	var _, csk = cipher.GenerateKeyPair()
+
+	var c *connCipher
+	c, err = newConnCipher(csk, apk, transcript, true) // not a b
+	assertNil(t, err)
+
+	_, err = c.decrypt(a.encrypt(raw))
+	assertTrue(t, err != nil, "missing error")
+
+	// wrong transcript
+
+	c, err = newConnCipher(bsk, apk, newChallenge(), true)
+	assertNil(t, err)
+
+	_, err = c.decrypt(a.encrypt(raw))
+	assertTrue(t, err != nil, "missing error")
+
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 
+	"github.com/skycoin/cxo/data"
 	"github.com/skycoin/cxo/node/msg"
 	"github.com/skycoin/cxo/skyobject"
 	"github.com/skycoin/cxo/skyobject/statutil"
@@ -92,11 +93,26 @@ func (n *nodeHead) node() *Node {
 	return n.n.fs.n
 }
 
+// a failedRequest represents request keys of which
+// should be requested again; if the err is nil, then
+// the request is partially succeeded, and the keys
+// are keys peer didn't send (because of response
+// volume limit, for example)
 type failedRequest struct {
-	c   *Conn         // connection
-	seq uint64        // seq of the filling Root
-	key cipher.SHA256 // requested object
-	err error         // failed if the err is not nil
+	c    *Conn           // connection
+	seq  uint64          // seq of the filling Root
+	keys []cipher.SHA256 // requested objects
+	err  error           // failed if the err is not nil
+}
+
+// prefetched objects (see RqTree and RootDelta)
+type prefetched struct {
+	c    *Conn           // connection
+	seq  uint64          // seq of the filling Root
+	hash cipher.SHA256   // hash of the filling Root
+	keys []cipher.SHA256 // requested keys (delta) or nil (tree)
+	vals [][]byte        // objects
+	err  error           // request error
 }
 
 // handle local "fields" of the nodeHead
@@ -116,6 +132,8 @@ type fillHead struct {
 
 	p connRoot // waits to be filled
 
+	hq []connRoot // history Root objects to fill (ordered by seq)
+
 	cs knownRoots // conn -> known root objects (seq)
 
 	successq chan *Conn         // succeeded requests
@@ -125,6 +143,16 @@ type fillHead struct {
 	fc  *list.List // connections to fill from (*Conn)
 
 	requesting int // number of running requests
+
+	// prefetching (see Config.MaxPrefetchVolume)
+
+	pf     map[cipher.SHA256][]byte // prefetched objects
+	pfv    int                      // volume of the pf
+	pfskip uint32                   // objects received
+	pfkeys []cipher.SHA256          // delta to prefetch
+	pfc    *Conn                    // prefetch from
+	pfrun  bool                     // prefetching in progress
+	pfq    chan prefetched          // prefetched objects
 }
 
 func (n *nodeHead) handle() {
@@ -148,12 +176,15 @@ func (n *nodeHead) handle() {
 
 			successq: make(chan *Conn),         // release connection
 			failureq: make(chan failedRequest), // failed requests
+
+			pfq: make(chan prefetched), // prefetched objects
 		}
 
 		key cipher.SHA256
 		c   *Conn
 		cr  connRoot
 		fc  failedRequest
+		pf  prefetched
 		err error // fillign failure or nil
 	)
 
@@ -171,6 +202,10 @@ func (n *nodeHead) handle() {
 
 			f.handleRequestFailure(fc)
 
+		case pf = <-f.pfq:
+
+			f.handlePrefetched(pf)
+
 		case err = <-f.ff:
 
 			f.handleFillingResult(err)
@@ -197,6 +232,7 @@ func (n *nodeHead) handle() {
 		case err = <-errq:
 
 			f.p = connRoot{} // remove pending Root
+			f.hq = nil       // and history
 			if f.f != nil {
 				f.f.Close()
 				f.handleFillingResult(err)
@@ -217,6 +253,11 @@ func (n *nodeHead) handle() {
 func (f *fillHead) handleRequest(key cipher.SHA256) {
 	f.node().Debugln(FillPin, "[fill] handleRequest", key.Hex()[:7])
 
+	if f.usePrefetched(key) == true {
+		f.triggerPrefetch() // free space for next
+		return
+	}
+
 	f.rqo.PushBack(key)
 	f.triggerRequest()
 }
@@ -231,14 +272,20 @@ func (f *fillHead) handleSuccess(c *Conn) {
 
 func (f *fillHead) handleRequestFailure(fr failedRequest) {
 	f.node().Debugln(FillPin, "[fill] handleRequestFailure", fr.c.String(),
-		fr.key.Hex()[:7])
+		len(fr.keys), fr.err)
 
 	f.requesting--
 
 	switch fr.err {
+	case nil:
+
+		// partially succeeded
+		f.fc.PushBack(fr.c)
+
 	case ErrInvalidResponse:
 
 		// close connections that sends invalid responses
+		f.node().bs.violation(fr.c, ViolationObject, fr.err)
 		go fr.c.fatality(fr.err)
 		delete(f.cs, fr.c) // remove connection
 
@@ -247,9 +294,15 @@ func (f *fillHead) handleRequestFailure(fr failedRequest) {
 		// closed
 		delete(f.cs, fr.c) // remove connection
 
+	case ErrAccessDenied:
+
+		// the peer doesn't allow us to fetch objects (see ACL)
+		delete(f.cs, fr.c) // remove connection
+
 	case ErrTimeout:
 
 		// probably don't have object we're requesting anymore
+		f.node().bs.violation(fr.c, ViolationTimeout, fr.err)
 		f.cs.removeKnown(fr.c, fr.seq)
 
 	default:
@@ -258,7 +311,11 @@ func (f *fillHead) handleRequestFailure(fr failedRequest) {
 
 	}
 
-	f.rqo.PushFront(fr.key) // shift
+	// shift keeping order
+	for i := len(fr.keys) - 1; i >= 0; i-- {
+		f.rqo.PushFront(fr.keys[i])
+	}
+
 	f.triggerRequest()
 
 }
@@ -267,6 +324,11 @@ func (f *fillHead) handleReceivedRoot(cr connRoot) {
 	f.node().Debugln(FillPin, "[fill] handleReceivedRoot",
 		cr.c.String(), cr.r.Short())
 
+	if cr.history == true {
+		f.handleHistoryRoot(cr)
+		return
+	}
+
 	// there are a filling Root
 
 	if f.r.r != nil {
@@ -322,6 +384,46 @@ func (f *fillHead) handleReceivedRoot(cr connRoot) {
 
 }
 
+// a Root requested by SubFrom; such Root objects are
+// filled in order of their seq numbers, before the
+// pending Root, even if they are older
+func (f *fillHead) handleHistoryRoot(cr connRoot) {
+
+	f.cs.addKnown(cr.c, cr.r.Seq) // add to known
+
+	if f.r.r != nil && f.r.r.Seq == cr.r.Seq {
+		f.fc.PushBack(cr.c) // add to filling connections
+		f.triggerRequest()
+		return
+	}
+
+	// callback
+	if reject := f.node().onRootReceived(cr.c, cr.r); reject != nil {
+		return // rejected
+	}
+
+	if f.r.r == nil {
+		f.createFiller(cr) // fill the Root
+		return
+	}
+
+	// insert keeping order
+
+	var i int
+	for ; i < len(f.hq); i++ {
+		if f.hq[i].r.Seq == cr.r.Seq {
+			return // already have
+		}
+		if f.hq[i].r.Seq > cr.r.Seq {
+			break
+		}
+	}
+
+	f.hq = append(f.hq, connRoot{})
+	copy(f.hq[i+1:], f.hq[i:])
+	f.hq[i] = cr
+}
+
 // value for channels, if hte (*Node).maxFillingParallel
 // is zero, then the skyobject.Filler has no limits for
 // goroutines, but we can't create an unlimited channel,
@@ -337,8 +439,11 @@ func (f *fillHead) createFiller(cr connRoot) {
 	f.node().Debugln(FillPin, "[fill] createFiller", cr.c.String(),
 		cr.r.Short())
 
-	// broadcast the Root we are going to fill
-	f.nodeHead.n.fs.broadcastRoot(cr)
+	// broadcast the Root we are going to fill,
+	// peers are not interested in old Root objects
+	if cr.history == false {
+		f.nodeHead.n.fs.broadcastRoot(cr)
+	}
 
 	f.tp = time.Now() // time point
 
@@ -354,6 +459,8 @@ func (f *fillHead) createFiller(cr connRoot) {
 	f.rqo = list.New()                   // create list of keys
 	f.fc = f.cs.buildConnsList(cr.r.Seq) // create list of connections
 
+	f.startPrefetch(cr)
+
 	f.await.Add(1)
 	go f.runFiller(f.f)
 }
@@ -386,27 +493,49 @@ func (f *fillHead) closeFiller() {
 	f.r = connRoot{}
 	f.requesting = 0
 
+	f.stopPrefetch()
+	f.pf, f.pfv = nil, 0 // GC
+
 }
 
 func (f *fillHead) handleFillingResult(err error) {
 
 	f.node().Debugf(FillPin, "handleFillingResult %s: %v", f.r.r.Short(), err)
 
+	var filled = f.r // keep after closing
+
 	if err == nil {
 		f.node().onRootFilled(f.r.r)     // callback
 		f.favg.Add(time.Now().Sub(f.tp)) // average time
-		f.cs.moveForward(f.r.r.Seq + 1)  // move forward
+		if f.r.history == false {
+			f.cs.moveForward(f.r.r.Seq + 1) // move forward
+		}
 	} else {
 		f.node().onFillingBreaks(f.r.r, err) // callback
 	}
 
 	f.closeFiller() // close the filler and wait it's goroutines
 
+	// the pending Root can be filled as history Root
+	if err == nil && f.p.r != nil && f.p.r.Seq <= filled.r.Seq {
+		f.p = connRoot{}
+	}
+
+	// history first
+
+	if len(f.hq) > 0 {
+		var next = f.hq[0]
+		f.hq[0] = connRoot{} // GC
+		f.hq = f.hq[1:]
+		f.createFiller(next)
+		return
+	}
+
 	// is there a pending Root to be filled?
 
 	// no
 
-	if f.p == (connRoot{}) {
+	if f.p.r == nil {
 		return
 	}
 
@@ -431,38 +560,58 @@ func (f *fillHead) triggerRequest() {
 // request objects from anymore, neither busy nor idle
 func (f *fillHead) tryRequest() (fatal bool) {
 
-	if f.rqo.Len() == 0 {
-		return // no objects to request
-	}
+	for f.rqo.Len() > 0 {
+
+		var c = f.nextConn()
+
+		if c == nil {
+			fatal = (f.requesting == 0)
+			return // no connections to request from
+		}
+
+		// do the request
+
+		f.requesting++
+
+		f.await.Add(1) // nodeHead.await
+		go f.request(c, f.r.r.Seq, f.nextKeys(c))
 
-	if f.fc.Len() == 0 {
-		fatal = (f.requesting == 0)
-		return // no connections to request from
 	}
 
-	var c = f.fc.Remove(f.fc.Front()).(*Conn) // unshift
+	return // no objects to request
+}
 
-	// the c can be removed from the head, let's check it out
+// next idle connection or nil
+func (f *fillHead) nextConn() (c *Conn) {
 
-	for _, ok := f.cs[c]; ok == false; _, ok = f.cs[c] {
+	for f.fc.Len() > 0 {
 
-		if f.fc.Len() == 0 {
-			fatal = (f.requesting == 0)
-			return // no connections
-		}
+		c = f.fc.Remove(f.fc.Front()).(*Conn) // unshift
+
+		// the c can be removed from the head, let's check it out
 
-		c = f.fc.Remove(f.fc.Front()).(*Conn) // unshift next
+		if _, ok := f.cs[c]; ok == true {
+			return
+		}
 
 	}
 
-	var key = f.rqo.Remove(f.rqo.Front()).(cipher.SHA256) // unshift
+	return nil // no connections
+}
 
-	// do the request
+// keys to request from given connection
+func (f *fillHead) nextKeys(c *Conn) (keys []cipher.SHA256) {
 
-	f.requesting++
+	var max = f.node().config.MaxRequestObjects
 
-	f.await.Add(1) // nodeHead.await
-	go f.request(c, f.r.r.Seq, key)
+	// peers without the batch capability can't reply for RqObjects
+	if c.HasCap(msg.CapBatch) == false {
+		max = 1
+	}
+
+	for f.rqo.Len() > 0 && len(keys) < max {
+		keys = append(keys, f.rqo.Remove(f.rqo.Front()).(cipher.SHA256))
+	}
 
 	return
 }
@@ -472,17 +621,57 @@ func (f *fillHead) node() *Node {
 	return f.n.fs.n
 }
 
-// (async) request object
-func (f *fillHead) request(c *Conn, seq uint64, key cipher.SHA256) {
+// (async)
+func (f *fillHead) success(c *Conn) {
+	select {
+	case f.successq <- c:
+	case <-f.closeq:
+	}
+}
+
+// (async)
+func (f *fillHead) failure(fr failedRequest) {
+	select {
+	case f.failureq <- fr:
+	case <-f.closeq:
+	}
+}
+
+// (async) request object(s)
+func (f *fillHead) request(c *Conn, seq uint64, keys []cipher.SHA256) {
 	defer f.await.Done()
 
+	if len(keys) == 1 {
+		f.requestObject(c, seq, keys[0])
+		return
+	}
+
+	f.requestObjects(c, seq, keys)
+}
+
+// error of an Err reply for an object request; the
+// ErrAccessDenied means that the peer doesn't allow
+// to fetch objects (see ACL), any other Err reply is
+// invalid response
+func replyErr(e *msg.Err) (err error) {
+	if e.Err == ErrAccessDenied.Error() {
+		return ErrAccessDenied
+	}
+	return ErrInvalidResponse
+}
+
+// (async) request object
+func (f *fillHead) requestObject(c *Conn, seq uint64, key cipher.SHA256) {
+
 	f.node().Debugf(FillPin, "[fill] request from [%s] %d %s", c.String(), seq,
 		key.Hex()[:7])
 
+	var keys = []cipher.SHA256{key}
+
 	var reply, err = c.sendRequest(&msg.RqObject{Key: key})
 
 	if err != nil {
-		f.failureq <- failedRequest{c, seq, key, err}
+		f.failure(failedRequest{c, seq, keys, err})
 		return
 	}
 
@@ -491,7 +680,7 @@ func (f *fillHead) request(c *Conn, seq uint64, key cipher.SHA256) {
 		var rk = cipher.SumSHA256(x.Value)
 
 		if rk != key {
-			f.failureq <- failedRequest{c, seq, key, ErrInvalidResponse}
+			f.failure(failedRequest{c, seq, keys, ErrInvalidResponse})
 			return
 		}
 
@@ -501,17 +690,366 @@ func (f *fillHead) request(c *Conn, seq uint64, key cipher.SHA256) {
 			return
 		}
 
-		f.successq <- c
+		f.success(c)
+
+	case *msg.Err:
+		f.failure(failedRequest{c, seq, keys, replyErr(x)})
 
 	default:
-		f.failureq <- failedRequest{c, seq, key, ErrInvalidResponse}
+		f.failure(failedRequest{c, seq, keys, ErrInvalidResponse})
+	}
+
+}
+
+// (async) request many objects at once
+func (f *fillHead) requestObjects(c *Conn, seq uint64, keys []cipher.SHA256) {
+
+	f.node().Debugf(FillPin, "[fill] request from [%s] %d %d objects",
+		c.String(), seq, len(keys))
+
+	var reply, err = c.sendRequest(&msg.RqObjects{Keys: keys})
+
+	if err != nil {
+		f.failure(failedRequest{c, seq, keys, err})
+		return
+	}
+
+	if x, ok := reply.(*msg.Err); ok == true {
+		f.failure(failedRequest{c, seq, keys, replyErr(x)})
+		return
+	}
+
+	var objs, ok = reply.(*msg.Objects)
+
+	if ok == false || len(objs.Values) != len(keys) {
+		f.failure(failedRequest{c, seq, keys, ErrInvalidResponse})
+		return
+	}
+
+	var missing []cipher.SHA256 // not sent
+
+	for i, val := range objs.Values {
+
+		if len(val) == 0 {
+			missing = append(missing, keys[i])
+			continue
+		}
+
+		if cipher.SumSHA256(val) != keys[i] {
+			missing = append(missing, keys[i:]...)
+			f.failure(failedRequest{c, seq, missing, ErrInvalidResponse})
+			return
+		}
+
+		// incremented by the Want call(s)
+		if _, err := f.node().c.SetWanted(keys[i], val); err != nil {
+			f.node().Fatal("DB failure:", err)
+			return
+		}
+
+	}
+
+	switch len(missing) {
+	case 0:
+		f.success(c)
+	case len(keys):
+		// probably, don't have objects we're requesting anymore
+		f.failure(failedRequest{c, seq, missing, ErrTimeout})
+	default:
+		f.failure(failedRequest{c, seq, missing, nil})
+	}
+
+}
+
+//
+// prefetching
+//
+
+// the prefetching used for heads the Node has not full Root
+// objects of; it's a new head for the Node and the Node
+// probably doesn't have any objects of the Root, thus it
+// requests all objects of the Root; and if the Root received
+// with delta (see msg.RootDelta), then the Node requests
+// objects of the delta it doesn't have
+func (f *fillHead) startPrefetch(cr connRoot) {
+
+	if f.node().config.MaxPrefetchVolume <= 0 {
+		return // turned off
 	}
 
+	// peers without the batch capability can't reply
+	// for RqTree and RqObjects
+	if cr.c == nil || cr.c.HasCap(msg.CapBatch) == false {
+		return
+	}
+
+	if _, err := f.node().c.LastRootSeq(cr.r.Pub, cr.r.Nonce); err == nil {
+
+		// not a new head
+
+		if f.pfkeys = f.missingKeys(cr.delta); len(f.pfkeys) == 0 {
+			return // nothing to prefetch
+		}
+
+	}
+
+	f.pf = make(map[cipher.SHA256][]byte)
+	f.pfc = cr.c
+	f.pfskip = 0
+
+	f.triggerPrefetch()
+}
+
+// keys of the delta the Node doesn't have
+func (f *fillHead) missingKeys(delta []cipher.SHA256) (keys []cipher.SHA256) {
+
+	for _, key := range delta {
+
+		switch _, _, err := f.node().c.Get(key, 0); err {
+		case nil:
+			continue // already have
+		case data.ErrNotFound:
+			keys = append(keys, key)
+		default:
+			f.node().Fatal("DB failure:", err)
+		}
+
+	}
+
+	return
+}
+
+// stop prefetching, but keep prefetched objects
+func (f *fillHead) stopPrefetch() {
+	f.pfc, f.pfrun, f.pfkeys = nil, false, nil
+}
+
+// is the delta prefetching (not tree)
+func (f *fillHead) isDeltaPrefetch() bool {
+	return f.pfkeys != nil
+}
+
+func (f *fillHead) triggerPrefetch() {
+
+	if f.pfc == nil || f.pfrun == true {
+		return // not prefetching or in progress
+	}
+
+	var limit = f.node().config.MaxPrefetchVolume - f.pfv
+
+	if limit <= 0 {
+		return // wait for filler
+	}
+
+	if rv := f.node().config.MaxResponseVolume; limit > rv {
+		limit = rv
+	}
+
+	f.pfrun = true
+
+	if f.isDeltaPrefetch() == true {
+
+		var keys = f.pfkeys
+
+		if max := f.node().config.MaxRequestObjects; len(keys) > max {
+			keys = keys[:max]
+		}
+
+		f.pfkeys = f.pfkeys[len(keys):]
+
+		f.await.Add(1) // nodeHead.await
+		go f.prefetchKeys(f.pfc, f.r.r.Seq, f.r.r.Hash, keys)
+		return
+	}
+
+	f.await.Add(1) // nodeHead.await
+	go f.prefetch(f.pfc, f.r.r.Seq, f.r.r.Hash, f.pfskip, uint32(limit))
+}
+
+// (async) request objects of delta of the filling Root
+func (f *fillHead) prefetchKeys(
+	c *Conn,
+	seq uint64,
+	hash cipher.SHA256,
+	keys []cipher.SHA256,
+) {
+
+	defer f.await.Done()
+
+	f.node().Debugf(FillPin, "[fill] prefetch delta from [%s] %d, %d keys",
+		c.String(), seq, len(keys))
+
+	var pf = prefetched{c: c, seq: seq, hash: hash, keys: keys}
+
+	var reply, err = c.sendRequest(&msg.RqObjects{Keys: keys})
+
+	if err == nil {
+		if objs, ok := reply.(*msg.Objects); ok == false {
+			err = ErrInvalidResponse // or Err
+		} else if len(objs.Values) != len(keys) {
+			err = ErrInvalidResponse
+		} else {
+			pf.vals = objs.Values
+		}
+	}
+
+	pf.err = err
+
+	select {
+	case f.pfq <- pf:
+	case <-f.closeq:
+	}
+}
+
+// (async) request objects of the filling Root
+func (f *fillHead) prefetch(
+	c *Conn,
+	seq uint64,
+	hash cipher.SHA256,
+	skip uint32,
+	limit uint32,
+) {
+
+	defer f.await.Done()
+
+	f.node().Debugf(FillPin, "[fill] prefetch from [%s] %d, skip %d",
+		c.String(), seq, skip)
+
+	var pf = prefetched{c: c, seq: seq, hash: hash}
+
+	var reply, err = c.sendRequest(&msg.RqTree{
+		Root:  hash,
+		Skip:  skip,
+		Limit: limit,
+	})
+
+	if err == nil {
+		if objs, ok := reply.(*msg.Objects); ok == true {
+			pf.vals = objs.Values
+		} else {
+			err = ErrInvalidResponse // or Err
+		}
+	}
+
+	pf.err = err
+
+	select {
+	case f.pfq <- pf:
+	case <-f.closeq:
+	}
+}
+
+func (f *fillHead) handlePrefetched(pf prefetched) {
+
+	f.node().Debugln(FillPin, "[fill] handlePrefetched", pf.c.String(),
+		len(pf.vals), pf.err)
+
+	if f.r.r == nil || f.r.r.Seq != pf.seq || f.r.r.Hash != pf.hash ||
+		f.pfc != pf.c {
+
+		return // belongs to previous filling
+	}
+
+	f.pfrun = false
+
+	// stop on first error or if there are no objects anymore
+	if pf.err != nil || len(pf.vals) == 0 {
+		f.stopPrefetch()
+		return
+	}
+
+	var (
+		got     int             // received objects
+		missing []cipher.SHA256 // not sent (delta)
+	)
+
+	for i, val := range pf.vals {
+
+		f.pfskip++
+
+		if pf.keys != nil && len(val) == 0 {
+			missing = append(missing, pf.keys[i])
+			continue
+		}
+
+		got++
+
+		var key = cipher.SumSHA256(val)
+
+		if pf.keys != nil && key != pf.keys[i] {
+			f.stopPrefetch() // invalid response, request them by keys
+			return
+		}
+
+		if _, ok := f.pf[key]; ok == true {
+			continue
+		}
+
+		f.pf[key] = val
+		f.pfv += len(val)
+
+	}
+
+	if pf.keys != nil {
+
+		if got == 0 {
+			f.stopPrefetch() // peer doesn't have the objects
+			return
+		}
+
+		// the missing are sent next time (volume limit)
+		f.pfkeys = append(missing, f.pfkeys...)
+
+		if len(f.pfkeys) == 0 {
+			f.stopPrefetch() // the delta is received
+		}
+
+	}
+
+	// requested objects waiting for connections
+
+	for e := f.rqo.Front(); e != nil; {
+
+		var next = e.Next()
+
+		if f.usePrefetched(e.Value.(cipher.SHA256)) == true {
+			f.rqo.Remove(e)
+		}
+
+		e = next
+
+	}
+
+	f.triggerPrefetch()
+}
+
+// use prefetched object if it exists
+func (f *fillHead) usePrefetched(key cipher.SHA256) (ok bool) {
+
+	var val []byte
+
+	if val, ok = f.pf[key]; ok == false {
+		return
+	}
+
+	delete(f.pf, key)
+	f.pfv -= len(val)
+
+	// incremented by the Want call(s)
+	if _, err := f.node().c.SetWanted(key, val); err != nil {
+		f.node().Fatal("DB failure:", err)
+	}
+
+	return
 }
 
 func (f *fillHead) handleDelConn(c *Conn) {
 	delete(f.cs, c) // just remove it from list of known
 
+	if f.pfc == c {
+		f.stopPrefetch()
+	}
+
 	if f.r.c == c {
 		f.r.c = nil // GC
 	}
@@ -0,0 +1,571 @@
+package node
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// HTTP API
+//
+// The HTTP API is JSON version of the RPC. All replies are JSON
+// documents. Errors are {"error": "description"} with appropriate
+// status code (400 for invalid requests, 404 if something not
+// found, 405 for wrong method and 500 for other errors). Public
+// keys are hex-encoded strings. The ?depth=n query argument of
+// Root objects is depth of references expansion (see
+// registry.ToJSON, default is 0)
+//
+//     GET    /feeds                              shared feeds
+//     PUT    /feeds/{pk}                         share feed
+//     DELETE /feeds/{pk}                         don't share feed
+//     GET    /feeds/{pk}/connections             connections of feed
+//
+//     GET    /connections                        all connections
+//
+//     GET    /{tcp|udp}/address                  listening address
+//     POST   /{tcp|udp}/connect                  {"address": "host:port"}
+//     POST   /{tcp|udp}/disconnect               {"address": "host:port"}
+//     POST   /{tcp|udp}/subscribe                {"address": "", "feed": ""}
+//     POST   /{tcp|udp}/unsubscribe              {"address": "", "feed": ""}
+//     GET    /{tcp|udp}/remote_feeds?address=    feeds of remote peer
+//     GET    /{tcp|udp}/stat?address=            stat of connection
+//
+//     GET    /roots/{pk}/last                    last Root of active head
+//     GET    /roots/{pk}/{nonce}/{seq}           Root
+//     GET    /roots/{pk}/{nonce}/{seq}/tree      {"tree": "printed tree"}
+//
+//     GET    /stat                               statistic of the Node
+//
+// Replies of POST, PUT and DELETE are {} if succeeded
+
+// wrap the HTTP
+type httpServer struct {
+	l net.Listener // underlying listener
+	s *http.Server //
+	n *Node        // back reference
+}
+
+// create HTTP server
+func (n *Node) newHTTP() (h *httpServer) {
+	h = new(httpServer)
+	h.n = n
+	h.s = &http.Server{Handler: h}
+	return
+}
+
+func (h *httpServer) Listen(address string) (err error) {
+
+	if h.l, err = net.Listen("tcp", address); err != nil {
+		return
+	}
+
+	h.n.await.Add(1)
+	go h.run()
+
+	return
+}
+
+func (h *httpServer) run() {
+	defer h.n.await.Done()
+	h.s.Serve(h.l)
+}
+
+func (h *httpServer) Address() (address string) {
+	if h.l != nil {
+		address = h.l.Addr().String()
+	}
+	return
+}
+
+func (h *httpServer) Close() (err error) {
+	if h.l != nil {
+		err = h.s.Close()
+	}
+	return
+}
+
+// an httpError is error with status code
+type httpError struct {
+	code int
+	err  error
+}
+
+func (h *httpError) Error() string {
+	return h.err.Error()
+}
+
+func httpErr(code int, err error) error {
+	return &httpError{code, err}
+}
+
+var (
+	errHTTPNotFound = httpErr(http.StatusNotFound,
+		errors.New("not found"))
+	errHTTPMethod = httpErr(http.StatusMethodNotAllowed,
+		errors.New("method not allowed"))
+	errHTTPNoSuchConn = httpErr(http.StatusNotFound,
+		errors.New("no such connection"))
+	errHTTPNoSuchTransport = httpErr(http.StatusNotFound,
+		errors.New("no such transport"))
+)
+
+func httpBadRequest(err error) error {
+	return httpErr(http.StatusBadRequest, err)
+}
+
+func (h *httpServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	var (
+		path = strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+		reply interface{}
+		err   error
+	)
+
+	switch path[0] {
+	case "feeds":
+		reply, err = h.feeds(req, path[1:])
+	case "connections":
+		reply, err = h.connections(req, path[1:])
+	case "tcp", "udp":
+		reply, err = h.transport(req, path[0], path[1:])
+	case "roots":
+		reply, err = h.roots(req, path[1:])
+	case "stat":
+		reply, err = h.stat(req, path[1:])
+	default:
+		err = errHTTPNotFound
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		h.n.Debugln(HTTPPin, "[HTTP]", req.Method, req.URL.Path, err)
+		w.WriteHeader(httpStatus(err))
+		reply = map[string]string{"error": err.Error()}
+	} else if reply == nil {
+		reply = struct{}{}
+	}
+
+	json.NewEncoder(w).Encode(reply)
+}
+
+func httpStatus(err error) int {
+
+	switch err {
+	case data.ErrNotFound, data.ErrNoSuchFeed, data.ErrNoSuchHead:
+		return http.StatusNotFound
+	}
+
+	if he, ok := err.(*httpError); ok == true {
+		return he.code
+	}
+
+	return http.StatusInternalServerError
+}
+
+func httpMethod(req *http.Request, method string) (err error) {
+	if req.Method != method {
+		err = errHTTPMethod
+	}
+	return
+}
+
+func httpPubKey(s string) (pk cipher.PubKey, err error) {
+
+	var b []byte
+	if b, err = hex.DecodeString(s); err != nil {
+		err = httpBadRequest(err)
+		return
+	}
+
+	if len(b) != len(pk) {
+		err = httpBadRequest(errors.New("invalid PubKey length"))
+		return
+	}
+
+	return cipher.NewPubKey(b), nil
+}
+
+func httpPubKeys(pks []cipher.PubKey) (s []string) {
+
+	s = make([]string, 0, len(pks))
+
+	for _, pk := range pks {
+		s = append(s, pk.Hex())
+	}
+
+	return
+}
+
+func httpUint(s string) (u uint64, err error) {
+	if u, err = strconv.ParseUint(s, 10, 64); err != nil {
+		err = httpBadRequest(err)
+	}
+	return
+}
+
+// GET /feeds
+// PUT /feeds/{pk}
+// DELETE /feeds/{pk}
+// GET /feeds/{pk}/connections
+func (h *httpServer) feeds(
+	req *http.Request,
+	path []string,
+) (
+	reply interface{},
+	err error,
+) {
+
+	if len(path) == 0 || path[0] == "" {
+		if err = httpMethod(req, http.MethodGet); err != nil {
+			return
+		}
+		return httpPubKeys(h.n.Feeds()), nil
+	}
+
+	var pk cipher.PubKey
+	if pk, err = httpPubKey(path[0]); err != nil {
+		return
+	}
+
+	switch {
+
+	case len(path) == 1:
+
+		switch req.Method {
+		case http.MethodPut:
+			err = h.n.Share(pk)
+		case http.MethodDelete:
+			err = h.n.DontShare(pk)
+		default:
+			err = errHTTPMethod
+		}
+
+	case len(path) == 2 && path[1] == "connections":
+
+		if err = httpMethod(req, http.MethodGet); err != nil {
+			return
+		}
+
+		var cs []string
+		err = (&RPC{h.n}).ConnectionsOfFeed(pk, &cs)
+		reply = cs
+
+	default:
+		err = errHTTPNotFound
+	}
+
+	return
+}
+
+// GET /connections
+func (h *httpServer) connections(
+	req *http.Request,
+	path []string,
+) (
+	reply interface{},
+	err error,
+) {
+
+	if len(path) != 0 {
+		return nil, errHTTPNotFound
+	}
+
+	if err = httpMethod(req, http.MethodGet); err != nil {
+		return
+	}
+
+	return h.n.connections(), nil
+}
+
+// body of POST requests of transports
+type httpConnFeed struct {
+	Address string `json:"address"`
+	Feed    string `json:"feed"`
+}
+
+func (h *httpConnFeed) connFeed() (cf ConnFeed, err error) {
+
+	if h.Address == "" {
+		err = httpBadRequest(errors.New("missing address"))
+		return
+	}
+
+	cf.Address = h.Address
+
+	if cf.Feed, err = httpPubKey(h.Feed); err != nil {
+		return
+	}
+
+	return
+}
+
+// the TCPRPC and the UDPRPC
+type transportRPC interface {
+	Connect(address string, _ *struct{}) (err error)
+	Disconnect(address string, _ *struct{}) (err error)
+	Subscribe(cf ConnFeed, _ *struct{}) (err error)
+	Unsubscribe(cf ConnFeed, _ *struct{}) (err error)
+	RemoteFeeds(address string, rfs *[]cipher.PubKey) (err error)
+	Stat(address string, cs *ConnStat) (err error)
+	Address(_ struct{}, address *string) (err error)
+}
+
+// GET /{tcp|udp}/address
+// POST /{tcp|udp}/connect
+// POST /{tcp|udp}/disconnect
+// POST /{tcp|udp}/subscribe
+// POST /{tcp|udp}/unsubscribe
+// GET /{tcp|udp}/remote_feeds?address=
+// GET /{tcp|udp}/stat?address=
+func (h *httpServer) transport(
+	req *http.Request,
+	network string,
+	path []string,
+) (
+	reply interface{},
+	err error,
+) {
+
+	if len(path) != 1 {
+		return nil, errHTTPNotFound
+	}
+
+	var tr transportRPC
+	if network == "tcp" {
+		tr = &TCPRPC{h.n}
+	} else {
+		tr = &UDPRPC{h.n}
+	}
+
+	switch path[0] {
+
+	case "address":
+
+		if err = httpMethod(req, http.MethodGet); err != nil {
+			return
+		}
+
+		var address string
+		if err = tr.Address(struct{}{}, &address); err != nil {
+			return nil, errHTTPNoSuchTransport
+		}
+
+		return map[string]string{"address": address}, nil
+
+	case "remote_feeds":
+
+		if err = httpMethod(req, http.MethodGet); err != nil {
+			return
+		}
+
+		var rfs []cipher.PubKey
+		if err = tr.RemoteFeeds(req.URL.Query().Get("address"),
+			&rfs); err != nil {
+
+			return
+		}
+
+		return httpPubKeys(rfs), nil
+
+	case "stat":
+
+		if err = httpMethod(req, http.MethodGet); err != nil {
+			return
+		}
+
+		var cs ConnStat
+		if err = tr.Stat(req.URL.Query().Get("address"), &cs); err != nil {
+			if err.Error() == "no such connection" {
+				err = errHTTPNoSuchConn
+			}
+			return
+		}
+
+		return map[string]interface{}{
+			"protocol":      cs.Protocol,
+			"caps":          cs.Caps,
+			"codec":         cs.Codec,
+			"sent":          cs.Sent,
+			"sent_wire":     cs.SentWire,
+			"received":      cs.Received,
+			"received_wire": cs.ReceivedWire,
+			"send_ratio":    cs.SendRatio(),
+			"receive_ratio": cs.ReceiveRatio(),
+		}, nil
+
+	case "connect", "disconnect", "subscribe", "unsubscribe":
+
+	default:
+		return nil, errHTTPNotFound
+
+	}
+
+	if err = httpMethod(req, http.MethodPost); err != nil {
+		return
+	}
+
+	var hcf httpConnFeed
+	if err = json.NewDecoder(req.Body).Decode(&hcf); err != nil {
+		return nil, httpBadRequest(err)
+	}
+
+	switch path[0] {
+
+	case "connect":
+
+		if hcf.Address == "" {
+			return nil, httpBadRequest(errors.New("missing address"))
+		}
+
+		err = tr.Connect(hcf.Address, nil)
+
+	case "disconnect":
+
+		if hcf.Address == "" {
+			return nil, httpBadRequest(errors.New("missing address"))
+		}
+
+		err = tr.Disconnect(hcf.Address, nil)
+
+	default: // subscribe, unsubscribe
+
+		var cf ConnFeed
+		if cf, err = hcf.connFeed(); err != nil {
+			return
+		}
+
+		if path[0] == "subscribe" {
+			err = tr.Subscribe(cf, nil)
+		} else {
+			err = tr.Unsubscribe(cf, nil)
+		}
+
+	}
+
+	if err != nil && err.Error() == "no such connection" {
+		err = errHTTPNoSuchConn
+	}
+
+	return
+}
+
+// GET /roots/{pk}/last
+// GET /roots/{pk}/{nonce}/{seq}
+// GET /roots/{pk}/{nonce}/{seq}/tree
+func (h *httpServer) roots(
+	req *http.Request,
+	path []string,
+) (
+	reply interface{},
+	err error,
+) {
+
+	if len(path) < 2 || len(path) > 4 {
+		return nil, errHTTPNotFound
+	}
+
+	if err = httpMethod(req, http.MethodGet); err != nil {
+		return
+	}
+
+	var pk cipher.PubKey
+	if pk, err = httpPubKey(path[0]); err != nil {
+		return
+	}
+
+	var (
+		c = h.n.c
+		r *registry.Root
+	)
+
+	switch {
+
+	case len(path) == 2 && path[1] == "last":
+
+		r, err = c.LastRoot(pk, c.ActiveHead(pk))
+
+	case len(path) >= 3:
+
+		var nonce, seq uint64
+
+		if nonce, err = httpUint(path[1]); err != nil {
+			return
+		}
+
+		if seq, err = httpUint(path[2]); err != nil {
+			return
+		}
+
+		r, err = c.Root(pk, nonce, seq)
+
+	default:
+		return nil, errHTTPNotFound
+
+	}
+
+	if err != nil {
+		return
+	}
+
+	var pack registry.Pack
+	if pack, err = c.Pack(r, nil); err != nil {
+		return
+	}
+
+	if len(path) == 4 {
+
+		if path[3] != "tree" {
+			return nil, errHTTPNotFound
+		}
+
+		var tree string
+		if tree, err = r.Tree(pack); err != nil {
+			return
+		}
+
+		return map[string]string{"tree": tree}, nil
+
+	}
+
+	var depth int
+
+	if ds := req.URL.Query().Get("depth"); ds != "" {
+		if depth, err = strconv.Atoi(ds); err != nil {
+			return nil, httpBadRequest(err)
+		}
+	}
+
+	return r.JSON(pack, depth)
+}
+
+// GET /stat
+func (h *httpServer) stat(
+	req *http.Request,
+	path []string,
+) (
+	reply interface{},
+	err error,
+) {
+
+	if len(path) != 0 {
+		return nil, errHTTPNotFound
+	}
+
+	if err = httpMethod(req, http.MethodGet); err != nil {
+		return
+	}
+
+	return h.n.Stat(), nil
+}
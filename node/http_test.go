@@ -0,0 +1,88 @@
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func testHTTP(
+	t *testing.T,
+	method string,
+	url string,
+	code int,
+) (
+	reply interface{},
+) {
+
+	t.Helper()
+
+	var req, err = http.NewRequest(method, url, nil)
+	assertNil(t, err)
+
+	var resp *http.Response
+	resp, err = http.DefaultClient.Do(req)
+	assertNil(t, err)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != code {
+		t.Errorf("%s %s: wrong status code %d, want %d", method, url,
+			resp.StatusCode, code)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: wrong Content-Type %q", method, url, ct)
+	}
+
+	assertNil(t, json.NewDecoder(resp.Body).Decode(&reply))
+	return
+}
+
+func TestNode_HTTP(t *testing.T) {
+
+	var conf = getTestConfigNotListen("test")
+	conf.HTTP = "127.0.0.1:0"
+
+	var n, err = NewNode(conf)
+	assertNil(t, err)
+	defer n.Close()
+
+	var (
+		addr  = "http://" + n.HTTPAddress()
+		pk, _ = cipher.GenerateKeyPair()
+	)
+
+	testHTTP(t, http.MethodPut, addr+"/feeds/"+pk.Hex(), http.StatusOK)
+
+	var feeds = testHTTP(t, http.MethodGet, addr+"/feeds", http.StatusOK)
+
+	if fs, ok := feeds.([]interface{}); ok == false || len(fs) != 1 ||
+		fs[0] != pk.Hex() {
+
+		t.Error("wrong feeds", feeds)
+	}
+
+	var stat = testHTTP(t, http.MethodGet, addr+"/stat", http.StatusOK)
+
+	if _, ok := stat.(map[string]interface{}); ok == false {
+		t.Error("wrong stat", stat)
+	}
+
+	testHTTP(t, http.MethodGet, addr+"/roots/"+pk.Hex()+"/last",
+		http.StatusNotFound)
+
+	// errors
+
+	testHTTP(t, http.MethodPut, addr+"/feeds/invalid", http.StatusBadRequest)
+	testHTTP(t, http.MethodPost, addr+"/feeds", http.StatusMethodNotAllowed)
+	testHTTP(t, http.MethodGet, addr+"/unknown", http.StatusNotFound)
+
+	testHTTP(t, http.MethodDelete, addr+"/feeds/"+pk.Hex(), http.StatusOK)
+
+	if n.IsSharing(pk) == true {
+		t.Error("still sharing")
+	}
+
+}
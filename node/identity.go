@@ -0,0 +1,129 @@
+package node
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	discovery "github.com/skycoin/net/skycoin-messenger/factory"
+)
+
+// ErrInvalidIdentity occurs if saved identity
+// of the Node is damaged
+var ErrInvalidIdentity = errors.New("invalid identity of the node")
+
+// seed config of given secret key
+func seedConfig(sk cipher.SecKey) (id *discovery.SeedConfig) {
+	return &discovery.SeedConfig{
+		PublicKey: cipher.PubKeyFromSecKey(sk).Hex(),
+		SecKey:    sk.Hex(),
+	}
+}
+
+// parse and check seed config
+func parseSeedConfig(
+	id *discovery.SeedConfig,
+) (
+	pk cipher.PubKey,
+	sk cipher.SecKey,
+	err error,
+) {
+
+	if pk, err = cipher.PubKeyFromHex(id.PublicKey); err != nil {
+		return
+	}
+
+	if sk, err = cipher.SecKeyFromHex(id.SecKey); err != nil {
+		return
+	}
+
+	if cipher.PubKeyFromSecKey(sk) != pk {
+		err = ErrInvalidIdentity
+	}
+
+	return
+}
+
+// identity of the Node: the IDSecKey, saved or new one
+func (c *Config) identity() (id *discovery.SeedConfig, err error) {
+
+	if c.IDSecKey != (cipher.SecKey{}) {
+		return seedConfig(c.IDSecKey), nil
+	}
+
+	var path = c.identityFile()
+
+	if path == "" {
+		return discovery.NewSeedConfig(), nil // random
+	}
+
+	if id, err = discovery.ReadOrCreateSeedConfig(path); err != nil {
+		return
+	}
+
+	_, _, err = parseSeedConfig(id)
+	return
+}
+
+// set identity
+func (n *Node) setID(id *discovery.SeedConfig) (err error) {
+
+	var (
+		pk cipher.PubKey
+		sk cipher.SecKey
+	)
+
+	if pk, sk, err = parseSeedConfig(id); err != nil {
+		return
+	}
+
+	n.idmx.Lock()
+	defer n.idmx.Unlock()
+
+	n.id, n.idpk, n.idsk = id, pk, sk
+	return
+}
+
+// identity of the Node
+func (n *Node) identity() (
+	id *discovery.SeedConfig,
+	pk cipher.PubKey,
+	sk cipher.SecKey,
+) {
+
+	n.idmx.RLock()
+	defer n.idmx.RUnlock()
+
+	return n.id, n.idpk, n.idsk
+}
+
+// RotateID generates new identity of the Node and
+// saves it if the identity is saved (see IDSecKey
+// of the Config). Established connections and
+// connections to discovery servers are not affected,
+// and new connections use the new identity. The
+// RotateID returns new ID
+func (n *Node) RotateID() (id cipher.PubKey, err error) {
+
+	var sc = discovery.NewSeedConfig()
+
+	if sc == nil {
+		return id, errors.New("can't generate new identity")
+	}
+
+	if path := n.config.identityFile(); path != "" &&
+		n.config.IDSecKey == (cipher.SecKey{}) {
+
+		if err = discovery.WriteSeedConfig(sc, path); err != nil {
+			return
+		}
+	}
+
+	if err = n.setID(sc); err != nil {
+		return
+	}
+
+	n.Printf("new identity %s", sc.PublicKey)
+
+	return n.ID(), nil
+}
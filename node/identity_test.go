@@ -0,0 +1,76 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// config of a Node with DB on disk
+func getTestConfigDataDir(prefix, dir string) (c *Config) {
+	c = getTestConfigNotListen(prefix)
+	c.Config.InMemoryDB = false
+	c.Config.DataDir = dir
+	c.Config.DBPath = filepath.Join(dir, "cxds.db")
+	return
+}
+
+func TestNode_RotateID(t *testing.T) {
+
+	var dir, err = ioutil.TempDir("", "cxo-identity")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	var n *Node
+	n, err = NewNode(getTestConfigDataDir("first", dir))
+	assertNil(t, err)
+
+	var id = n.ID()
+
+	assertNil(t, n.Close())
+
+	// restore
+
+	n, err = NewNode(getTestConfigDataDir("restored", dir))
+	assertNil(t, err)
+
+	assertTrue(t, n.ID() == id, "identity not restored")
+
+	// rotate
+
+	var rotated cipher.PubKey
+	rotated, err = n.RotateID()
+	assertNil(t, err)
+
+	assertTrue(t, rotated != id, "identity not changed")
+	assertTrue(t, n.ID() == rotated, "wrong identity")
+
+	assertNil(t, n.Close())
+
+	n, err = NewNode(getTestConfigDataDir("rotated", dir))
+	assertNil(t, err)
+	defer n.Close()
+
+	assertTrue(t, n.ID() == rotated, "rotated identity not saved")
+
+}
+
+func TestNode_IDSecKey(t *testing.T) {
+
+	var (
+		pk, sk = cipher.GenerateKeyPair()
+		conf   = getTestConfigNotListen("test")
+	)
+
+	conf.IDSecKey = sk
+
+	var n, err = NewNode(conf)
+	assertNil(t, err)
+	defer n.Close()
+
+	assertTrue(t, n.ID() == pk, "IDSecKey ignored")
+
+}
@@ -33,6 +33,15 @@ const (
 
 	DiscoveryPin // show discovery debug logs
 
+	// http
+
+	HTTPPin // errors of HTTP API
+
+	// peers
+
+	PeersPin // reconnections to known peers
+	BanPin   // violations of peers
+
 	// joiners
 
 	MsgPin  = MsgSendPin | MsgReceivePin // send/receive
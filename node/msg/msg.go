@@ -16,8 +16,71 @@ import (
 // [ .... ] - encoded message
 //
 
-// Version is current protocol version
-const Version uint16 = 3
+// Version is current protocol version. Peers
+// negotiate version during handshake. A peer
+// supports all versions from MinVersion to
+// Version, and the greatest version both peers
+// support is used. Layout of Syn and Ack is
+// the same for all versions from MinVersion.
+// Thus, new versions can be rolled out without
+// breaking old peers. Optional features of the
+// protocol are capabilities (see Caps)
+const Version uint16 = 5
+
+// MinVersion is min protocol version supported
+const MinVersion uint16 = 5
+
+// LegacyVersion is previous protocol version. The
+// LegacyVersion has not peer authentication and
+// encryption. And handshake messages of the version
+// are LegacySyn and LegacyAck
+const LegacyVersion uint16 = 3
+
+// CodecFlate is name of DEFLATE compression codec
+// (see Syn, Ack and Compressed)
+const CodecFlate = "flate"
+
+// Capabilities are optional features of the protocol
+// negotiated during handshake (see Syn and Ack). A peer
+// sends messeges of a capability only if the capability
+// has been negotiated. Unknown capabilities are ignored
+const (
+	// RqObjects, Objects and RqTree
+	CapBatch = "batch"
+	// RootDelta
+	CapDelta = "delta"
+	// SubFrom and HistoryRoot
+	CapHistory = "history"
+	// Compressed (see Codecs of the Syn)
+	CapCompression = "compression"
+)
+
+// Caps is list of all capabilities supported
+var Caps = []string{
+	CapBatch,
+	CapDelta,
+	CapHistory,
+	CapCompression,
+}
+
+// CapOf returns capability required by messeges of
+// given type, or empty string if messeges of the
+// type don't require a capability
+func CapOf(t Type) string {
+
+	switch t {
+	case RqObjectsType, ObjectsType, RqTreeType:
+		return CapBatch
+	case RootDeltaType:
+		return CapDelta
+	case SubFromType, HistoryRootType:
+		return CapHistory
+	case CompressedType:
+		return CapCompression
+	}
+
+	return ""
+}
 
 // be sure that all messages implements Msg interface compiler time
 var (
@@ -29,8 +92,12 @@ var (
 
 	// handshake
 
-	_ Msg = &Syn{} // <- Syn (node id, protocol version)
-	_ Msg = &Ack{} // -> Ack (peer id)
+	_ Msg = &Syn{}  // <- Syn (versions, node id, challenge, caps, codecs)
+	_ Msg = &Ack{}  // -> Ack (peer id, challenge, version, caps, codec, sig)
+	_ Msg = &Auth{} // <- Auth (sig), -> Ok or Err
+
+	_ Msg = &LegacySyn{} // <- Syn (node id, protocol version)
+	_ Msg = &LegacyAck{} // -> Ack (peer id)
 
 	// common replies
 
@@ -39,8 +106,9 @@ var (
 
 	// subscriptions
 
-	_ Msg = &Sub{}   // <- Sub (feed)
-	_ Msg = &Unsub{} // <- Unsub (feed)
+	_ Msg = &Sub{}     // <- Sub (feed)
+	_ Msg = &Unsub{}   // <- Unsub (feed)
+	_ Msg = &SubFrom{} // <- SubFrom (feed, nonce, seq, time, limit)
 
 	// public server features
 
@@ -49,16 +117,26 @@ var (
 
 	// root (push and done)
 
-	_ Msg = &Root{} // <- Root (feed, nonce, seq, sig, val)
+	_ Msg = &Root{}        // <- Root (feed, nonce, seq, sig, val)
+	_ Msg = &RootDelta{}   // <- RootDelta (root, delta)
+	_ Msg = &HistoryRoot{} // <- HistoryRoot (root)
 
 	// objects
 
 	_ Msg = &RqObject{} // <- RqO (key, prefetch)
 	_ Msg = &Object{}   // -> O   (val, vals)
 
+	_ Msg = &RqObjects{} // <- RqOs (keys)
+	_ Msg = &Objects{}   // -> Os   (vals)
+	_ Msg = &RqTree{}    // <- RqT  (root, skip, limit), -> Os or Err
+
 	// preview
 
 	_ Msg = &RqPreview{} // -> RqPreview (feed)
+
+	// compression
+
+	_ Msg = &Compressed{} // <-> Compressed (compressed encoded messege)
 )
 
 //
@@ -105,10 +183,21 @@ func (*Pong) Encode() []byte {
 // handshake
 //
 
-// A Syn is handshake initiator message
+// A Syn is handshake initiator message. The
+// Protocol and the MinProtocol are max and min
+// protocol versions supported by the initiator.
+// The Challenge is random hash that makes every
+// handshake unique. The Caps is list of supported
+// capabilities. The Codecs is list of compression
+// codecs supported by the initiator in order of
+// preference (see Compressed)
 type Syn struct {
-	Protocol uint16
-	NodeID   cipher.PubKey // node id
+	Protocol    uint16        // max version
+	NodeID      cipher.PubKey // node id
+	Challenge   cipher.SHA256 // random challenge
+	MinProtocol uint16        // min version
+	Caps        []string      // supported capabilities
+	Codecs      []string      // supported codecs
 }
 
 // Type implements Msg interface
@@ -119,9 +208,21 @@ func (s *Syn) Encode() []byte { return encode(s) }
 
 // An Ack is response for the Syn
 // if handshake has been accepted.
-// Otherwise, the Err returned
+// Otherwise, the Err returned. The Sig
+// is signature of AckHash made by secret
+// key of the NodeID. The Protocol is version
+// chosen by accepting side, and the Caps is
+// capabilities supported by both sides. The Codec
+// is one of codecs of the Syn, chosen by accepting
+// side, or empty string if the connection is not
+// compressed
 type Ack struct {
-	NodeID cipher.PubKey // node id
+	NodeID    cipher.PubKey // node id
+	Challenge cipher.SHA256 // random challenge
+	Protocol  uint16        // negotiated version
+	Caps      []string      // negotiated capabilities
+	Codec     string        // chosen codec
+	Sig       cipher.Sig    // signature of AckHash
 }
 
 // Type implements Msg interface
@@ -130,6 +231,113 @@ func (*Ack) Type() Type { return AckType }
 // Encode the Ack
 func (a *Ack) Encode() []byte { return encode(a) }
 
+// An Auth is response for the Ack. The Sig is
+// signature of AuthHash made by secret key of
+// NodeID of the Syn. The Auth replied with Ok
+// if handshake has been accepted. Otherwise,
+// the Err returned
+type Auth struct {
+	Sig cipher.Sig // signature of AuthHash
+}
+
+// Type implements Msg interface
+func (*Auth) Type() Type { return AuthType }
+
+// Encode the Auth
+func (a *Auth) Encode() []byte { return encode(a) }
+
+// A LegacySyn is Syn of the LegacyVersion
+type LegacySyn struct {
+	Protocol uint16
+	NodeID   cipher.PubKey // node id
+}
+
+// Type implements Msg interface
+func (*LegacySyn) Type() Type { return SynType }
+
+// Encode the LegacySyn
+func (s *LegacySyn) Encode() []byte { return encode(s) }
+
+// A LegacyAck is Ack of the LegacyVersion
+type LegacyAck struct {
+	NodeID cipher.PubKey // node id
+}
+
+// Type implements Msg interface
+func (*LegacyAck) Type() Type { return AckType }
+
+// Encode the LegacyAck
+func (a *LegacyAck) Encode() []byte { return encode(a) }
+
+// DecodeLegacySyn decodes LegacySyn. The Decode
+// can't decode LegacySyn, because it decodes Syn
+// of current Version. Thus, a handshake should
+// try to decode a LegacySyn first
+func DecodeLegacySyn(p []byte) (syn *LegacySyn, err error) {
+
+	if len(p) < 1 {
+		return nil, ErrEmptyMessage
+	}
+
+	if Type(p[0]) != SynType {
+		return nil, InvalidTypeError{Type(p[0])}
+	}
+
+	syn = new(LegacySyn)
+
+	var n int
+
+	n, err = encoder.DeserializeRawToValue(p[1:], reflect.ValueOf(syn))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n+1 != len(p) {
+		return nil, ErrIncomplieDecoding
+	}
+
+	if syn.Protocol != LegacyVersion {
+		return nil, fmt.Errorf("not a LegacySyn: protocol version %d",
+			syn.Protocol)
+	}
+
+	return
+}
+
+// AckHash returns hash that should be signed by
+// accepting side of a handshake. The hash covers
+// encoded Syn and all fields of the Ack except
+// the signature. Thus, the signature can't be
+// reused for another handshake, and any change
+// of the Syn breaks the handshake
+func AckHash(syn *Syn, ack *Ack) (hash cipher.SHA256) {
+
+	var p = append([]byte("ack"), syn.Encode()...)
+
+	p = append(p, ack.NodeID[:]...)
+	p = append(p, ack.Challenge[:]...)
+	p = append(p, encoder.Serialize(ack.Protocol)...)
+	p = append(p, encoder.Serialize(ack.Caps)...)
+	p = append(p, encoder.Serialize(ack.Codec)...)
+
+	return cipher.SumSHA256(p)
+}
+
+// AuthHash returns hash that should be signed by
+// initiator of a handshake. The hash covers both
+// encoded Syn and encoded Ack. Since, the hash
+// covers entire handshake, it is also used to
+// derive session keys
+func AuthHash(syn *Syn, ack *Ack) (hash cipher.SHA256) {
+
+	var p = append([]byte("auth"), syn.Encode()...)
+
+	p = append(p, ack.Encode()...)
+
+	return cipher.SumSHA256(p)
+}
+
 //
 // common
 //
@@ -199,6 +407,29 @@ func (u *Unsub) Encode() []byte {
 	)
 }
 
+// A SubFrom is Sub that requests history of a head
+// of the feed too. The SubFrom replied with Ok or Err,
+// like the Sub. After the Ok, remote peer pushes
+// HistoryRoot messages in order, starting from Root
+// with given Seq, or from first Root created after
+// given Time if the Time is not zero. The Limit is max
+// number of the Root objects, remote peer can reduce
+// it. Zero Limit means limit of the remote peer. The
+// zero Nonce means active head of the feed
+type SubFrom struct {
+	Feed  cipher.PubKey // feed
+	Nonce uint64        // head, zero for active
+	Seq   uint64        // from seq
+	Time  int64         // since the time (unix nano), if not zero
+	Limit uint32        // max Root objects, zero for default
+}
+
+// Type implements Msg interface
+func (*SubFrom) Type() Type { return SubFromType }
+
+// Encode the SubFrom
+func (s *SubFrom) Encode() []byte { return encode(s) }
+
 //
 // list of feeds
 //
@@ -250,6 +481,40 @@ func (*Root) Type() Type { return RootType }
 // Encode the Root
 func (r *Root) Encode() []byte { return encode(r) }
 
+// A RootDelta is a Root with hashes of objects
+// the Root has, but previous Root of the same
+// head (see Prev field of the Root) doesn't. A
+// receiver that has the previous Root requests
+// only the objects, instead of walking entire
+// tree. The Delta can be incomplete, because of
+// messege size limit. The RootDelta never sent
+// to peers of the LegacyVersion
+type RootDelta struct {
+	Root  Root            // the Root
+	Delta []cipher.SHA256 // new objects
+}
+
+// Type implements Msg interface
+func (*RootDelta) Type() Type { return RootDeltaType }
+
+// Encode the RootDelta
+func (r *RootDelta) Encode() []byte { return encode(r) }
+
+// A HistoryRoot is Root pushed in reply to SubFrom.
+// Unlike the Root, the HistoryRoot is filled by
+// receiver even if the receiver has newer Root of
+// the same head. HistoryRoot messages are filled
+// in order of their Seq
+type HistoryRoot struct {
+	Root Root // the Root
+}
+
+// Type implements Msg interface
+func (*HistoryRoot) Type() Type { return HistoryRootType }
+
+// Encode the HistoryRoot
+func (h *HistoryRoot) Encode() []byte { return encode(h) }
+
 //
 // objects
 //
@@ -276,6 +541,58 @@ func (*Object) Type() Type { return ObjectType }
 // Encode the Object
 func (o *Object) Encode() []byte { return encode(o) }
 
+// A RqObjects represents a Msg that requests
+// many objects at once. The RqObjects replied
+// with Objects
+type RqObjects struct {
+	Keys []cipher.SHA256 // request
+}
+
+// Type implements Msg interface
+func (*RqObjects) Type() Type { return RqObjectsType }
+
+// Encode the RqObjects
+func (r *RqObjects) Encode() []byte { return encode(r) }
+
+// An Objects represents reply for RqObjects
+// or for RqTree. For the RqObjects the Values
+// are in order of requested keys, and an empty
+// value means that peer doesn't have the object
+// (or the object doesn't fit the reply). For
+// the RqTree the Values are objects of the tree
+// in order of walking
+type Objects struct {
+	Values [][]byte // encoded objects in person
+}
+
+// Type implements Msg interface
+func (*Objects) Type() Type { return ObjectsType }
+
+// Encode the Objects
+func (o *Objects) Encode() []byte { return encode(o) }
+
+// A RqTree represents request of all objects of a
+// Root, including the Root itself and its Registry.
+// Objects are sent in order of walking the Root,
+// skipping first Skip objects, up to Limit bytes.
+// Since, a peer can't know which objects the
+// requester has, the RqTree useful for initial
+// filling only. The RqTree replied with Objects,
+// and empty Objects means that there are no more
+// objects in the tree. Or the RqTree replied with
+// Err if peer doesn't have the Root
+type RqTree struct {
+	Root  cipher.SHA256 // hash of the Root
+	Skip  uint32        // skip first objects
+	Limit uint32        // volume limit
+}
+
+// Type implements Msg interface
+func (*RqTree) Type() Type { return RqTreeType }
+
+// Encode the RqTree
+func (r *RqTree) Encode() []byte { return encode(r) }
+
 //
 // preview
 //
@@ -291,6 +608,25 @@ func (*RqPreview) Type() Type { return RqPreviewType }
 // Encode the RqPreview
 func (r *RqPreview) Encode() []byte { return encode(r) }
 
+//
+// compression
+//
+
+// A Compressed is encoded messege compressed
+// using codec chosen during handshake (see Ack).
+// Peers send a messege compressed only if it's
+// big enough. A Compressed can't contain another
+// Compressed
+type Compressed struct {
+	Value []byte // compressed encoded messege
+}
+
+// Type implements Msg interface
+func (*Compressed) Type() Type { return CompressedType }
+
+// Encode the Compressed
+func (c *Compressed) Encode() []byte { return encode(c) }
+
 //
 // Type / Encode / Deocode / String()
 //
@@ -321,6 +657,19 @@ const (
 	ObjectType   // 13
 
 	RqPreviewType // 14
+
+	AuthType // 15
+
+	RqObjectsType // 16
+	ObjectsType   // 17
+	RqTreeType    // 18
+
+	RootDeltaType // 19
+
+	SubFromType     // 20
+	HistoryRootType // 21
+
+	CompressedType // 22
 )
 
 // Type to string mapping
@@ -346,6 +695,19 @@ var msgTypeString = [...]string{
 	ObjectType:   "Object",
 
 	RqPreviewType: "RqPreview",
+
+	AuthType: "Auth",
+
+	RqObjectsType: "RqObjects",
+	ObjectsType:   "Objects",
+	RqTreeType:    "RqTree",
+
+	RootDeltaType: "RootDelta",
+
+	SubFromType:     "SubFrom",
+	HistoryRootType: "HistoryRoot",
+
+	CompressedType: "Compressed",
 }
 
 // String implements fmt.Stringer interface
@@ -378,6 +740,19 @@ var forwardRegistry = [...]reflect.Type{
 	ObjectType:   reflect.TypeOf(Object{}),
 
 	RqPreviewType: reflect.TypeOf(RqPreview{}),
+
+	AuthType: reflect.TypeOf(Auth{}),
+
+	RqObjectsType: reflect.TypeOf(RqObjects{}),
+	ObjectsType:   reflect.TypeOf(Objects{}),
+	RqTreeType:    reflect.TypeOf(RqTree{}),
+
+	RootDeltaType: reflect.TypeOf(RootDelta{}),
+
+	SubFromType:     reflect.TypeOf(SubFrom{}),
+	HistoryRootType: reflect.TypeOf(HistoryRoot{}),
+
+	CompressedType: reflect.TypeOf(Compressed{}),
 }
 
 // An InvalidTypeError represents decoding error when
@@ -1,6 +1,9 @@
 package node
 
 import (
+	"encoding/json"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -26,17 +29,22 @@ import (
 type Node struct {
 	mx sync.Mutex // lock
 
-	log.Logger                       // logger
-	id         *discovery.SeedConfig // unique random identifier
-	c          *skyobject.Container  // related Container
+	log.Logger                      // logger
+	c          *skyobject.Container // related Container
 
-	idpk cipher.PubKey // id.PublicKey (string -> pk)
+	idmx sync.RWMutex          // lock of the identity (see RotateID)
+	id   *discovery.SeedConfig // unique identifier
+	idpk cipher.PubKey         // id.PublicKey (string -> pk)
+	idsk cipher.SecKey         // id.SecKey (string -> sk)
 
 	//
 	// feeds and connections
 	//
 
 	fs *nodeFeeds              // feeds
+	ps *nodePeers              // known peers
+	bs *nodeBans               // bans and scores of peers
+	as *nodeACL                // access control lists of feeds
 	ic map[cipher.PubKey]*Conn // node id (pk) -> connection
 	pc map[*Conn]struct{}      // pending connections
 
@@ -62,12 +70,24 @@ type Node struct {
 
 	fillavg *statutil.Duration // filling average
 
+	//
+	// publishing
+	//
+
+	regs map[string]*registry.Registry // named registries
+
 	//
 	// rpc
 	//
 
 	rpc *rpcServer
 
+	//
+	// http
+	//
+
+	http *httpServer
+
 	//
 	//  closing
 	//
@@ -123,16 +143,31 @@ func NewNodeContainer(
 
 	n = new(Node)
 
-	n.id = discovery.NewSeedConfig()
-	n.idpk, _ = cipher.PubKeyFromHex(n.id.PublicKey)
 	n.c = c
 	n.fs = newNodeFeeds(n)
 	n.ic = make(map[cipher.PubKey]*Conn)
+	n.regs = make(map[string]*registry.Registry)
 	n.pc = make(map[*Conn]struct{})
 
 	n.config = conf
 	n.config.Config = c.Config() // actual
 
+	// identity
+
+	var id *discovery.SeedConfig
+
+	if id, err = n.config.identity(); err != nil {
+		return nil, err
+	}
+
+	if err = n.setID(id); err != nil {
+		return nil, err
+	}
+
+	n.ps = newNodePeers(n, n.config.peers())
+	n.bs = newNodeBans(n, n.config.bans())
+	n.as = newNodeACL(n.config.ACL)
+
 	n.fillavg = statutil.NewDuration(conf.Config.RollAvgSamples)
 	n.closeq = make(chan struct{})
 
@@ -144,6 +179,13 @@ func NewNodeContainer(
 
 	n.Logger = log.NewLogger(conf.Logger) // logger
 
+	// bans
+
+	if err = n.bs.load(); err != nil {
+		n.Close()
+		return
+	}
+
 	// listen
 
 	if conf.TCP.Listen != "" {
@@ -173,6 +215,26 @@ func NewNodeContainer(
 
 	}
 
+	// http
+
+	if conf.HTTP != "" {
+
+		n.http = n.newHTTP()
+
+		if err = n.http.Listen(conf.HTTP); err != nil {
+			n.Close()
+			return
+		}
+
+	}
+
+	// known peers
+
+	if err = n.ps.load(); err != nil {
+		n.Close()
+		return
+	}
+
 	// discoveries
 
 	for _, address := range conf.TCP.Discovery {
@@ -197,9 +259,16 @@ func NewNodeContainer(
 }
 
 // ID retursn identifier of the Node. The identifier
-// is unique random identifier that used to avoid
-// cross-connections
+// is unique identifier that used to avoid cross-
+// connections and to identify the Node. The Node
+// proves that it owns secret key of the ID during
+// handshake (see also (*Conn).IsEncrypted). The ID
+// is generated once and saved under DataDir (see
+// IDSecKey of the Config for details)
 func (n *Node) ID() (id cipher.PubKey) {
+	n.idmx.RLock()
+	defer n.idmx.RUnlock()
+
 	return n.idpk
 }
 
@@ -212,6 +281,15 @@ func (n *Node) Config() (conf *Config) {
 	return n.config // copy
 }
 
+// HTTPAddress returns listening address of the
+// HTTP API or empty string if the API is turned off
+func (n *Node) HTTPAddress() (address string) {
+	if n.http != nil {
+		address = n.http.Address()
+	}
+	return
+}
+
 // Container returns related Container instance
 func (n *Node) Container() (c *skyobject.Container) {
 	return n.c
@@ -225,8 +303,34 @@ func (n *Node) Container() (c *skyobject.Container) {
 // And to share an updated Root, call the Publish.
 // And don't call the publish for Root objects that
 // alredy saved (that saved before subscription)
+//
+// The Root sent with delta (hashes of objects the
+// previous Root of the head doesn't have) if the
+// Node has the previous Root. Thus, peers that have
+// the previous Root request only new objects
 func (n *Node) Publish(r *registry.Root) {
-	n.fs.broadcastRoot(connRoot{nil, r})
+	n.fs.broadcastRoot(connRoot{r: r, delta: n.rootDelta(r)})
+}
+
+// delta of given Root or nil
+func (n *Node) rootDelta(r *registry.Root) (delta []cipher.SHA256) {
+
+	if r.Seq == 0 || r.Prev == (cipher.SHA256{}) {
+		return // first Root of the head
+	}
+
+	var prev, err = n.c.RootByHash(r.Prev)
+
+	if err != nil {
+		return // removed
+	}
+
+	if delta, err = n.c.Diff(prev, r); err != nil {
+		n.Printf("[ERR] can't get delta of %s: %v", r.Short(), err)
+		return nil
+	}
+
+	return
 }
 
 // ConnectionsOfFeed returns list of connections of given
@@ -326,6 +430,22 @@ func (n *Node) delConnection(c *Conn) {
 	n.fs.delConn(c)
 }
 
+// remove closed connection from its transport
+func (n *Node) delTransportConn(c *Conn) {
+
+	if c.IsTCP() == true {
+		if t := n.getTCP(); t != nil {
+			t.delConn(c)
+		}
+		return
+	}
+
+	if u := n.getUDP(); u != nil {
+		u.delConn(c)
+	}
+
+}
+
 // call under lock of the mx
 func (n *Node) createTCP() {
 
@@ -382,6 +502,15 @@ func (n *Node) acceptConnection(fc *factory.Connection) {
 	n.Debugf(NewInConnPin, "[%s] accept",
 		connString(true, fc.IsTCP(), fc.GetRemoteAddr().String()))
 
+	if host, _, err := net.SplitHostPort(fc.GetRemoteAddr().String()); err == nil {
+		if n.bs.isBannedIP(host) == true {
+			n.Debugf(BanPin, "[%s] reject banned",
+				connString(true, fc.IsTCP(), fc.GetRemoteAddr().String()))
+			fc.Close()
+			return
+		}
+	}
+
 	if _, err := n.wrapConnection(fc, true); err != nil {
 
 		n.Printf("[ERR] [%s] handshake error: %v",
@@ -423,9 +552,22 @@ func (n *Node) wrapConnection(
 		return
 	}
 
+	// check out bans
+
+	if err = n.bs.check(c); err != nil {
+		n.delPendingConnClose(c)
+		return
+	}
+
 	// check out peer id
 
-	if err = n.addConnection(c); err != nil {
+	if isIncoming == true {
+		err = c.acceptConnection(n.closeq) // add and reply
+	} else {
+		err = n.addConnection(c)
+	}
+
+	if err != nil {
 		n.delPendingConnClose(c)
 		return
 	}
@@ -570,6 +712,17 @@ func (n *Node) onRootFilled(r *registry.Root) {
 		orf(n, r)
 	}
 
+	// remove old Root objects of the feed by its
+	// Retention policy (see skyobject.Retention)
+
+	if removed, err := n.c.Retain(r.Pub); err != nil {
+		n.Printf("[ERR] can't retain Root objects of %s: %v",
+			r.Pub.Hex()[:7], err)
+	} else if removed > 0 {
+		n.Debugf(FillPin, "retain %s: %d Root objects removed",
+			r.Pub.Hex()[:7], removed)
+	}
+
 }
 
 func (n *Node) onFillingBreaks(r *registry.Root, reason error) {
@@ -607,6 +760,71 @@ func (n *Node) Stat() (s *Stat) {
 	return
 }
 
+// MarshalJSON implements json.Marshaler interface.
+// The Stat contains maps with keys that can't be
+// used by the encoding/json package. And durations
+// and sizes are represented as human readable strings
+func (s *Stat) MarshalJSON() ([]byte, error) {
+
+	var objects = func(o skyobject.ObjectsStat) map[string]interface{} {
+		return map[string]interface{}{
+			"amount": uint32(o.Amount),
+			"volume": uint32(o.Volume),
+			"human":  o.Amount.String() + ", " + o.Volume.String(),
+		}
+	}
+
+	var rw = func(r skyobject.ReadWriteStat) map[string]interface{} {
+		return map[string]interface{}{"rps": r.RPS, "wps": r.WPS}
+	}
+
+	var root = func(r skyobject.RootStat) map[string]interface{} {
+		return map[string]interface{}{
+			"time": r.Time,
+			"seq":  r.Seq,
+			"hash": r.Hash.Hex(),
+		}
+	}
+
+	var feeds = make(map[string]interface{}, len(s.Feeds))
+
+	for pk, fs := range s.Feeds {
+
+		var heads = make(map[string]interface{}, len(fs.Heads))
+
+		for nonce, hs := range fs.Heads {
+			heads[strconv.FormatUint(nonce, 10)] = map[string]interface{}{
+				"len":   hs.Len,
+				"first": root(hs.First),
+				"last":  root(hs.Last),
+			}
+		}
+
+		feeds[pk.Hex()] = map[string]interface{}{"heads": heads}
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"cxds":             rw(s.CXDS),
+		"cache":            rw(s.Cache),
+		"cache_cleaning":   s.CacheCleaning.String(),
+		"cache_objects":    objects(s.CacheObjects),
+		"all_objects":      objects(s.AllObjects),
+		"used_objects":     objects(s.UsedObjects),
+		"roots_per_second": s.RootsPerSecond,
+		"feeds":            feeds,
+		"gc": map[string]interface{}{
+			"phase":           s.GC.Phase.String(),
+			"cycles":          s.GC.Cycles,
+			"roots":           s.GC.Roots,
+			"objects":         objects(s.GC.Objects),
+			"pending_roots":   s.GC.PendingRoots,
+			"pending_objects": s.GC.PendingObjects,
+			"last_cycle":      s.GC.LastCycle.String(),
+		},
+		"fillavg": s.Fillavg.String(),
+	})
+}
+
 // Close the Node. The Close returns error
 // of (skyobject.Container).Close once.
 func (n *Node) Close() (err error) {
@@ -631,6 +849,10 @@ func (n *Node) Close() (err error) {
 			n.rpc.Close()
 		}
 
+		if n.http != nil {
+			n.http.Close()
+		}
+
 		n.await.Wait()
 
 	})
@@ -0,0 +1,551 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// ErrNoSuchPeer occurs if the Node has not
+// a known peer with given address
+var ErrNoSuchPeer = errors.New("no such peer")
+
+// A PeerState represents state of a known peer
+type PeerState int
+
+// states of a known peer
+const (
+	PeerDisconnected PeerState = iota // waiting for next attempt
+	PeerConnecting                    // connecting
+	PeerConnected                     // connected
+)
+
+// String implements fmt.Stringer interface
+func (p PeerState) String() string {
+	switch p {
+	case PeerDisconnected:
+		return "disconnected"
+	case PeerConnecting:
+		return "connecting"
+	case PeerConnected:
+		return "connected"
+	}
+	return "unknown"
+}
+
+// A PeerInfo represents a known peer of the Node
+// (see (*Node).AddPeer)
+type PeerInfo struct {
+	Address   string          // network address
+	TCP       bool            // tcp or udp
+	Feeds     []cipher.PubKey // feeds to subscribe to
+	State     PeerState       // current state
+	Attempts  int             // failed attempts since last connection
+	Next      time.Time       // time of next attempt, if disconnected
+	LastError string          // last error, if any
+}
+
+// peers file format
+type peerEntry struct {
+	Address string   `json:"address"`
+	TCP     bool     `json:"tcp"`
+	Feeds   []string `json:"feeds"`
+}
+
+// a known peer
+type peer struct {
+	address string
+	tcp     bool
+	feeds   map[cipher.PubKey]struct{}
+
+	c        *Conn     // current connection or nil
+	state    PeerState //
+	attempts int       // failed attempts
+	next     time.Time // next attempt
+	err      error     // last error
+
+	wakeq chan struct{} // reconnect now
+	quitq chan struct{} // removed
+}
+
+func (p *peer) key() string {
+	return peerKey(p.address, p.tcp)
+}
+
+func peerKey(address string, isTCP bool) string {
+	if isTCP == true {
+		return "tcp://" + address
+	}
+	return "udp://" + address
+}
+
+// known peers of the Node
+type nodePeers struct {
+	n *Node
+
+	mx   sync.Mutex
+	path string           // empty for in-memory
+	ps   map[string]*peer // peerKey -> peer
+}
+
+func newNodePeers(n *Node, path string) (p *nodePeers) {
+	p = new(nodePeers)
+	p.n = n
+	p.path = path
+	p.ps = make(map[string]*peer)
+	return
+}
+
+// load peers from file and start them
+func (p *nodePeers) load() (err error) {
+
+	if p.path == "" {
+		return // in-memory
+	}
+
+	var data []byte
+	if data, err = ioutil.ReadFile(p.path); err != nil {
+		if os.IsNotExist(err) == true {
+			err = nil // no peers yet
+		}
+		return
+	}
+
+	var ents []peerEntry
+	if err = json.Unmarshal(data, &ents); err != nil {
+		return
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	for _, ent := range ents {
+
+		var feeds = make([]cipher.PubKey, 0, len(ent.Feeds))
+
+		for _, hex := range ent.Feeds {
+			var pk cipher.PubKey
+			if pk, err = cipher.PubKeyFromHex(hex); err != nil {
+				return
+			}
+			feeds = append(feeds, pk)
+		}
+
+		p.add(ent.Address, ent.TCP, feeds)
+	}
+
+	return
+}
+
+// save peers, call under lock
+func (p *nodePeers) save() (err error) {
+
+	if p.path == "" {
+		return // in-memory
+	}
+
+	var ents = make([]peerEntry, 0, len(p.ps))
+
+	for _, pr := range p.ps {
+
+		var ent = peerEntry{
+			Address: pr.address,
+			TCP:     pr.tcp,
+			Feeds:   make([]string, 0, len(pr.feeds)),
+		}
+
+		for pk := range pr.feeds {
+			ent.Feeds = append(ent.Feeds, pk.Hex())
+		}
+
+		sort.Strings(ent.Feeds)
+		ents = append(ents, ent)
+	}
+
+	sort.Slice(ents, func(i, j int) bool {
+		return peerKey(ents[i].Address, ents[i].TCP) <
+			peerKey(ents[j].Address, ents[j].TCP)
+	})
+
+	var data []byte
+	if data, err = json.MarshalIndent(ents, "", "  "); err != nil {
+		return
+	}
+
+	// write to temporary file and rename after
+
+	var tmp = p.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+
+	if err = os.Rename(tmp, p.path); err != nil {
+		os.Remove(tmp)
+	}
+
+	return
+}
+
+// add a peer and start it, call under lock; if the
+// peer already exists, then given feeds are added
+// to the peer and the peer reconnects immediately
+// if it's disconnected; the add returns true if
+// the peers should be saved
+func (p *nodePeers) add(
+	address string,
+	isTCP bool,
+	feeds []cipher.PubKey,
+) (
+	changed bool,
+) {
+
+	var pr, ok = p.ps[peerKey(address, isTCP)]
+
+	if ok == false {
+
+		pr = &peer{
+			address: address,
+			tcp:     isTCP,
+			feeds:   make(map[cipher.PubKey]struct{}, len(feeds)),
+			wakeq:   make(chan struct{}, 1),
+			quitq:   make(chan struct{}),
+		}
+
+		p.ps[pr.key()] = pr
+		changed = true
+
+		go p.maintain(pr)
+
+	} else {
+
+		select {
+		case pr.wakeq <- struct{}{}:
+		default:
+		}
+
+	}
+
+	for _, pk := range feeds {
+		if _, ok = pr.feeds[pk]; ok == false {
+			pr.feeds[pk] = struct{}{}
+			changed = true
+		}
+	}
+
+	return
+}
+
+// keep connection to given peer
+func (p *nodePeers) maintain(pr *peer) {
+
+	var n = p.n
+
+	for {
+
+		var c, err = p.connect(pr)
+
+		if err == nil {
+
+			p.resubscribe(pr, c)
+
+			select {
+			case <-c.closeq:
+			case <-pr.quitq:
+				return
+			case <-n.closeq:
+				return
+			}
+
+			err = ErrClosed // connection lost
+		}
+
+		var tm = time.NewTimer(p.disconnected(pr, c, err))
+
+		select {
+		case <-tm.C:
+		case <-pr.wakeq:
+			tm.Stop()
+		case <-pr.quitq:
+			tm.Stop()
+			return
+		case <-n.closeq:
+			tm.Stop()
+			return
+		}
+
+	}
+
+}
+
+// connect to the peer, the connect returns
+// existing connection if the Node already
+// connected to the address
+func (p *nodePeers) connect(pr *peer) (c *Conn, err error) {
+
+	var n = p.n
+
+	select {
+	case <-n.closeq:
+		return nil, ErrClosed
+	case <-pr.quitq:
+		return nil, ErrNoSuchPeer
+	default:
+	}
+
+	p.mx.Lock()
+	pr.state = PeerConnecting
+	p.mx.Unlock()
+
+	n.Debugf(PeersPin, "[%s] connecting", pr.key())
+
+	if pr.tcp == true {
+		c, err = n.TCP().Connect(pr.address)
+	} else {
+		c, err = n.UDP().Connect(pr.address)
+	}
+
+	if err != nil {
+		n.Debugf(PeersPin, "[%s] can't connect: %v", pr.key(), err)
+		return
+	}
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	pr.c = c
+	pr.state = PeerConnected
+	pr.attempts = 0
+	pr.err = nil
+
+	return
+}
+
+// subscribe the connection to feeds of the peer
+func (p *nodePeers) resubscribe(pr *peer, c *Conn) {
+
+	p.mx.Lock()
+	var feeds = make([]cipher.PubKey, 0, len(pr.feeds))
+	for pk := range pr.feeds {
+		feeds = append(feeds, pk)
+	}
+	p.mx.Unlock()
+
+	for _, pk := range feeds {
+		if err := c.Subscribe(pk); err != nil {
+			p.n.Debugf(PeersPin, "[%s] can't subscribe to %s: %v",
+				pr.key(), pk.Hex()[:7], err)
+		}
+	}
+
+}
+
+// set disconnected state and return
+// delay before next attempt
+func (p *nodePeers) disconnected(
+	pr *peer,
+	c *Conn,
+	err error,
+) (
+	delay time.Duration,
+) {
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	delay = backoff(p.n.config.ReconnectDelay,
+		p.n.config.MaxReconnectDelay,
+		pr.attempts)
+
+	if c == nil {
+		pr.attempts++ // failed attempt
+	}
+
+	pr.c = nil
+	pr.state = PeerDisconnected
+	pr.next = time.Now().Add(delay)
+	pr.err = err
+
+	return
+}
+
+// exponential backoff with jitter, the delay is
+// random in range [d/2, d], where the d is min
+// multiplied by 2^attempts, but not greater
+// then the max
+func backoff(min, max time.Duration, attempts int) (delay time.Duration) {
+
+	if min <= 0 {
+		return // no delay
+	}
+
+	delay = max
+
+	if attempts < 32 {
+		if d := min << uint(attempts); d > 0 && d < max {
+			delay = d
+		}
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// the connection is subscribed to given feed
+func (p *nodePeers) subscribed(c *Conn, feed cipher.PubKey) {
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	for _, pr := range p.ps {
+
+		if pr.c != c {
+			continue
+		}
+
+		if _, ok := pr.feeds[feed]; ok == true {
+			return
+		}
+
+		pr.feeds[feed] = struct{}{}
+
+		if err := p.save(); err != nil {
+			p.n.Printf("[ERR] can't save peers: %v", err)
+		}
+
+		return
+	}
+
+}
+
+// the connection is unsubscribed from given feed
+func (p *nodePeers) unsubscribed(c *Conn, feed cipher.PubKey) {
+
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	for _, pr := range p.ps {
+
+		if pr.c != c {
+			continue
+		}
+
+		if _, ok := pr.feeds[feed]; ok == false {
+			return
+		}
+
+		delete(pr.feeds, feed)
+
+		if err := p.save(); err != nil {
+			p.n.Printf("[ERR] can't save peers: %v", err)
+		}
+
+		return
+	}
+
+}
+
+// AddPeer adds a known peer. The Node keeps connection
+// to the peer, reconnecting with exponential backoff
+// (see ReconnectDelay and MaxReconnectDelay of the
+// Config) if the connection drops. After every
+// connection the Node subscribes to given feeds and
+// to feeds subscribed to using the connection before.
+// The known peers are saved under DataDir (see Peers
+// constant) or near the DBPath, and are restored when
+// the Node starts. The peers of a Node with DB in
+// memory are not saved. If the peer already known,
+// then given feeds are added to it and the peer
+// reconnects immediately if it's disconnected. The
+// AddPeer doesn't block, use Peers to get state of
+// the peer
+func (n *Node) AddPeer(
+	address string, //        : network address
+	isTCP bool, //            : tcp or udp
+	feeds ...cipher.PubKey, // : feeds to subscribe to
+) (
+	err error, //             : an error
+) {
+
+	n.ps.mx.Lock()
+	defer n.ps.mx.Unlock()
+
+	if n.ps.add(address, isTCP, feeds) == true {
+		err = n.ps.save()
+	}
+
+	return
+}
+
+// DelPeer removes known peer. The Node stops
+// reconnecting to the peer, but the DelPeer
+// doesn't close current connection
+func (n *Node) DelPeer(address string, isTCP bool) (err error) {
+
+	n.ps.mx.Lock()
+	defer n.ps.mx.Unlock()
+
+	var key = peerKey(address, isTCP)
+
+	var pr, ok = n.ps.ps[key]
+
+	if ok == false {
+		return ErrNoSuchPeer
+	}
+
+	close(pr.quitq)
+	delete(n.ps.ps, key)
+
+	return n.ps.save()
+}
+
+// Peers returns known peers (see AddPeer)
+func (n *Node) Peers() (ps []PeerInfo) {
+
+	n.ps.mx.Lock()
+	defer n.ps.mx.Unlock()
+
+	ps = make([]PeerInfo, 0, len(n.ps.ps))
+
+	for _, pr := range n.ps.ps {
+
+		var pi = PeerInfo{
+			Address:  pr.address,
+			TCP:      pr.tcp,
+			Feeds:    make([]cipher.PubKey, 0, len(pr.feeds)),
+			State:    pr.state,
+			Attempts: pr.attempts,
+		}
+
+		for pk := range pr.feeds {
+			pi.Feeds = append(pi.Feeds, pk)
+		}
+
+		sort.Slice(pi.Feeds, func(i, j int) bool {
+			return pi.Feeds[i].Hex() < pi.Feeds[j].Hex()
+		})
+
+		if pr.state == PeerDisconnected {
+			pi.Next = pr.next
+		}
+
+		if pr.err != nil {
+			pi.LastError = pr.err.Error()
+		}
+
+		ps = append(ps, pi)
+	}
+
+	sort.Slice(ps, func(i, j int) bool {
+		return peerKey(ps[i].Address, ps[i].TCP) <
+			peerKey(ps[j].Address, ps[j].TCP)
+	})
+
+	return
+}
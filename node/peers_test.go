@@ -0,0 +1,128 @@
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func Test_backoff(t *testing.T) {
+
+	const min, max = time.Second, time.Minute
+
+	for attempts, want := range []time.Duration{
+		1 * time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+	} {
+		var delay = backoff(min, max, attempts)
+		if delay < want/2 || delay > want {
+			t.Error("wrong delay", attempts, delay)
+		}
+	}
+
+	for _, attempts := range []int{10, 100} {
+		if delay := backoff(min, max, attempts); delay < max/2 || delay > max {
+			t.Error("wrong max delay", attempts, delay)
+		}
+	}
+
+	if backoff(0, max, 10) != 0 {
+		t.Error("unexpected delay")
+	}
+
+}
+
+// wait for connected state of first known peer
+// subscribed to given feed
+func waitTestPeer(t *testing.T, n *Node, feed cipher.PubKey) (c *Conn) {
+
+	t.Helper()
+
+	var deadline = time.Now().Add(10 * TM)
+
+	for time.Now().Before(deadline) {
+
+		var ps = n.Peers()
+
+		if len(ps) == 1 && ps[0].State == PeerConnected {
+			if cs := n.ConnectionsOfFeed(feed); len(cs) == 1 {
+				return cs[0]
+			}
+		}
+
+		time.Sleep(TM / 50)
+	}
+
+	t.Fatal("peer is not connected")
+	return
+}
+
+func TestNode_AddPeer(t *testing.T) {
+
+	var (
+		sn = getTestNode("server")
+
+		conf = getTestConfigNotListen("client")
+		pk   = cipher.PubKey{1, 2, 3}
+	)
+
+	conf.ReconnectDelay = TM / 10
+	conf.MaxReconnectDelay = TM / 5
+
+	var cn, err = NewNode(conf)
+	assertNil(t, err)
+
+	defer sn.Close()
+	defer cn.Close()
+
+	assertNil(t, sn.Share(pk))
+
+	var dir string
+	dir, err = ioutil.TempDir("", "cxo-peers")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	cn.ps.path = filepath.Join(dir, "peers.json")
+
+	assertNil(t, cn.AddPeer(sn.TCP().Address(), true, pk))
+
+	var c = waitTestPeer(t, cn, pk)
+
+	// connection drops
+
+	assertNil(t, c.Close())
+
+	if nc := waitTestPeer(t, cn, pk); nc == c {
+		t.Error("not reconnected")
+	}
+
+	// restore
+
+	var rn = getTestNodeNotListen("restored")
+	defer rn.Close()
+
+	rn.ps.path = cn.ps.path
+	assertNil(t, rn.ps.load())
+
+	var ps = rn.Peers()
+
+	assertTrue(t, len(ps) == 1, "wrong number of peers")
+	assertTrue(t, ps[0].Address == sn.TCP().Address(), "wrong address")
+	assertTrue(t, len(ps[0].Feeds) == 1 && ps[0].Feeds[0] == pk, "wrong feeds")
+
+	// remove
+
+	assertNil(t, cn.DelPeer(sn.TCP().Address(), true))
+	assertTrue(t, len(cn.Peers()) == 0, "peer not removed")
+
+	if err = cn.DelPeer(sn.TCP().Address(), true); err != ErrNoSuchPeer {
+		t.Error("unexpected error", err)
+	}
+
+}
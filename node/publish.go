@@ -0,0 +1,188 @@
+package node
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// AddFeedKey adds secret key of a feed to KeyStore of
+// the Container. The Node can publish Root objects of
+// the feed after that (see PublishJSON). The AddFeedKey
+// starts sharing the feed. The KeyStore must be unlocked
+func (n *Node) AddFeedKey(sk cipher.SecKey) (pk cipher.PubKey, err error) {
+
+	if pk, err = n.c.KeyStore().Add(sk); err != nil {
+		return
+	}
+
+	err = n.Share(pk)
+	return
+}
+
+// DelFeedKey removes secret key of given feed from
+// KeyStore of the Container. The feed is still
+// shared by the Node
+func (n *Node) DelFeedKey(pk cipher.PubKey) (err error) {
+	return n.c.KeyStore().Del(pk)
+}
+
+// FeedKeys returns list of feeds the
+// Node has secret keys of
+func (n *Node) FeedKeys() (pks []cipher.PubKey, err error) {
+	return n.c.KeyStore().Feeds()
+}
+
+// UnlockKeys unlocks KeyStore of the Container
+// using given passphrase
+func (n *Node) UnlockKeys(passphrase []byte) (err error) {
+	return n.c.KeyStore().Unlock(passphrase)
+}
+
+// AddRegistry adds named Registry to the Node.
+// The Registry can be used by the PublishJSON
+func (n *Node) AddRegistry(name string, reg *registry.Registry) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+
+	n.regs[name] = reg
+}
+
+// registry by name, or by hex-encoded RegistryRef
+// if it's not a name of a named Registry
+func (n *Node) registryByName(name string) (reg *registry.Registry, err error) {
+
+	n.mx.Lock()
+	var ok bool
+	reg, ok = n.regs[name]
+	n.mx.Unlock()
+
+	if ok == true {
+		return
+	}
+
+	var rr cipher.SHA256
+	if rr, err = cipher.SHA256FromHex(name); err != nil {
+		return nil, ErrNoSuchRegistry
+	}
+
+	if reg, err = n.c.Registry(registry.RegistryRef(rr)); err != nil {
+		return nil, ErrNoSuchRegistry
+	}
+
+	return
+}
+
+// A JSONRef represents JSON-described
+// object of a Root (see PublishJSON)
+type JSONRef struct {
+	Schema string // name of the Schema
+	Value  string // JSON document (see registry.FromJSON)
+}
+
+// A PublishArgs represents arguments
+// of the PublishJSON
+type PublishArgs struct {
+	Feed       cipher.PubKey // feed (the Node must have its secret key)
+	Nonce      uint64        // head, zero for active or new
+	Registry   string        // name or hex-encoded reference
+	Descriptor []byte        // descriptor of the Root
+	Refs       []JSONRef     // objects of the Root
+
+	// Readers of confidential Root. If the Readers is not
+	// empty, then objects of the Root sealed using new
+	// ContentKey, and the Descriptor is sealed with the
+	// key (see skyobject.SealContentKey)
+	Readers []cipher.PubKey
+}
+
+// PublishJSON creates new Root object of a feed owned by
+// the Node (see AddFeedKey), saves and publishes it. The objects described
+// by JSON documents using Registry added by AddRegistry or
+// saved in DB. If the Nonce is zero, then active head is
+// used or new head is created if the feed has no heads
+func (n *Node) PublishJSON(pa PublishArgs) (r *registry.Root, err error) {
+
+	var reg *registry.Registry
+	if reg, err = n.registryByName(pa.Registry); err != nil {
+		return
+	}
+
+	var up *skyobject.Unpack
+	if r, up, err = n.c.NewRoot(pa.Feed, pa.Nonce, reg); err != nil {
+		return
+	}
+	defer up.Close()
+
+	r.Reg = reg.Reference() // all Refs will be replaced
+	r.Descriptor = pa.Descriptor
+
+	if len(pa.Readers) > 0 {
+
+		var key = skyobject.NewContentKey()
+
+		if err = up.SetKey(&key); err != nil {
+			return
+		}
+
+		r.Descriptor, err = skyobject.SealContentKey(key, pa.Readers,
+			pa.Descriptor)
+		if err != nil {
+			return
+		}
+
+	}
+
+	r.Refs = make([]registry.Dynamic, 0, len(pa.Refs))
+
+	for _, jr := range pa.Refs {
+
+		var dr registry.Dynamic
+		if dr, err = jsonDynamic(up, reg, jr); err != nil {
+			return nil, err
+		}
+
+		r.Refs = append(r.Refs, dr)
+	}
+
+	if err = n.c.Save(up, r); err != nil {
+		return nil, err
+	}
+
+	n.Publish(r)
+	return
+}
+
+func jsonDynamic(
+	pack registry.Pack,
+	reg *registry.Registry,
+	jr JSONRef,
+) (
+	dr registry.Dynamic,
+	err error,
+) {
+
+	var sch registry.Schema
+	if sch, err = reg.SchemaByName(jr.Schema); err != nil {
+		return
+	}
+
+	var (
+		doc interface{}
+		dec = json.NewDecoder(strings.NewReader(jr.Value))
+	)
+
+	dec.UseNumber() // keep big integers
+
+	if err = dec.Decode(&doc); err != nil {
+		return
+	}
+
+	dr.Schema = sch.Reference()
+	dr.Hash, err = registry.AddJSON(pack, sch, doc)
+	return
+}
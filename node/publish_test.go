@@ -0,0 +1,92 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestNode_PublishJSON(t *testing.T) {
+
+	var n = getTestNodeNotListen("test")
+	defer n.Close()
+
+	var (
+		pk, sk = cipher.GenerateKeyPair()
+		reg    = getTestRegistry()
+
+		pa = PublishArgs{
+			Feed:     pk,
+			Registry: "test",
+			Refs: []JSONRef{
+				{"test.User", `{"Name": "Alice", "Age": 21}`},
+				{"test.Feed", `{"Posts": {"values": [{"Head": "hi"}]}}`},
+			},
+		}
+	)
+
+	if _, err := n.PublishJSON(pa); err != ErrNoSuchRegistry {
+		t.Error("unexpected error", err)
+	}
+
+	n.AddRegistry("test", reg)
+
+	if _, err := n.PublishJSON(pa); err != skyobject.ErrNoSuchKey {
+		t.Error("unexpected error", err)
+	}
+
+	if apk, err := n.AddFeedKey(sk); err != nil {
+		t.Fatal(err)
+	} else if apk != pk {
+		t.Fatal("wrong public key")
+	}
+
+	if n.IsSharing(pk) == false {
+		t.Error("not sharing")
+	}
+
+	var r, err = n.PublishJSON(pa)
+	assertNil(t, err)
+
+	if r.Nonce == 0 || r.Seq != 0 || len(r.Refs) != 2 {
+		t.Fatal("wrong Root", r)
+	}
+
+	var usr User
+	var pack, _ = n.c.Pack(r, reg)
+	assertNil(t, r.Refs[0].Value(pack, &usr))
+
+	if usr.Name != "Alice" || usr.Age != 21 {
+		t.Error("wrong User", usr)
+	}
+
+	// by RegistryRef, the same head
+
+	pa.Registry = reg.Reference().String()
+	pa.Refs = pa.Refs[:1]
+
+	var next *registry.Root
+	next, err = n.PublishJSON(pa)
+	assertNil(t, err)
+
+	if next.Nonce != r.Nonce || next.Seq != 1 {
+		t.Error("wrong Root", next)
+	}
+
+	var pks []cipher.PubKey
+	if pks, err = n.FeedKeys(); err != nil {
+		t.Fatal(err)
+	} else if len(pks) != 1 || pks[0] != pk {
+		t.Error("wrong feed keys", pks)
+	}
+
+	assertNil(t, n.DelFeedKey(pk))
+
+	if _, err = n.PublishJSON(pa); err != skyobject.ErrNoSuchKey {
+		t.Error("unexpected error", err)
+	}
+
+}
@@ -1,12 +1,18 @@
 package node
 
 import (
+	"encoding/json"
 	"errors"
 	"net"
 	"net/rpc"
+	"os"
+	"sort"
+	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
 
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject"
 	"github.com/skycoin/cxo/skyobject/registry"
 )
 
@@ -152,6 +158,230 @@ func (r *RPC) Stat(_ struct{}, stat *Stat) (err error) {
 	return
 }
 
+// A BackupArgs represents arguments
+// of the Backup RPC method
+type BackupArgs struct {
+	Path  string          // path to archive on machine of the Node
+	Feeds []cipher.PubKey // feeds to backup (empty for all)
+}
+
+// Backup is RPC method. The archive written to given
+// path by the Node. The path must not exist. See
+// skyobject.Container.Backup for details
+func (r *RPC) Backup(ba BackupArgs, _ *struct{}) (err error) {
+
+	if ba.Path == "" {
+		return errors.New("empty path")
+	}
+
+	var fl *os.File
+	if fl, err = os.OpenFile(ba.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY,
+		0600); err != nil {
+
+		return
+	}
+
+	if err = r.n.c.Backup(fl, ba.Feeds...); err == nil {
+		err = fl.Sync()
+	}
+
+	if cerr := fl.Close(); err == nil {
+		err = cerr
+	}
+
+	if err != nil {
+		os.Remove(ba.Path) // clean up
+	}
+
+	return
+}
+
+// AddFeedKey is RPC method. It adds secret key of a feed
+// to the Node. The reply is public key of the feed
+func (r *RPC) AddFeedKey(sk cipher.SecKey, pk *cipher.PubKey) (err error) {
+	var x cipher.PubKey
+	if x, err = r.n.AddFeedKey(sk); err != nil {
+		return
+	}
+	*pk = x
+	return
+}
+
+// DelFeedKey is RPC method
+func (r *RPC) DelFeedKey(pk cipher.PubKey, _ *struct{}) (err error) {
+	return r.n.DelFeedKey(pk)
+}
+
+// FeedKeys is RPC method
+func (r *RPC) FeedKeys(_ struct{}, pks *[]cipher.PubKey) (err error) {
+	var x []cipher.PubKey
+	if x, err = r.n.FeedKeys(); err != nil {
+		return
+	}
+	*pks = x
+	return
+}
+
+// UnlockKeys is RPC method
+func (r *RPC) UnlockKeys(passphrase []byte, _ *struct{}) (err error) {
+	return r.n.UnlockKeys(passphrase)
+}
+
+// Publish is RPC method. It creates, saves and publishes
+// new Root object (see PublishJSON of the Node)
+func (r *RPC) Publish(pa PublishArgs, z *registry.Root) (err error) {
+	var x *registry.Root
+	if x, err = r.n.PublishJSON(pa); err != nil {
+		return
+	}
+	*z = *x
+	return
+}
+
+// A RetentionArgs represents arguments
+// of the SetRetention RPC method
+type RetentionArgs struct {
+	Feed      cipher.PubKey       // the feed
+	Retention skyobject.Retention // the policy
+}
+
+// A RetentionInfo represents reply
+// of the Retention RPC method
+type RetentionInfo struct {
+	Retention skyobject.Retention // the policy
+	Own       bool                // false if default policy used
+}
+
+// Retention is RPC method
+func (r *RPC) Retention(pk cipher.PubKey, ri *RetentionInfo) (_ error) {
+	ri.Retention, ri.Own = r.n.c.Retention(pk)
+	return
+}
+
+// SetRetention is RPC method. The Retention is not saved
+// and is lost after restart of the Node
+func (r *RPC) SetRetention(ra RetentionArgs, _ *struct{}) (err error) {
+	return r.n.c.SetRetention(ra.Feed, ra.Retention)
+}
+
+// DelRetention is RPC method
+func (r *RPC) DelRetention(pk cipher.PubKey, _ *struct{}) (_ error) {
+	r.n.c.DelRetention(pk)
+	return
+}
+
+// Retain is RPC method. It removes Root objects of
+// given feed by its Retention policy immediately
+func (r *RPC) Retain(pk cipher.PubKey, removed *int) (err error) {
+	*removed, err = r.n.c.Retain(pk)
+	return
+}
+
+// An ACLArgs represents arguments
+// of the SetACL RPC method
+type ACLArgs struct {
+	Feed cipher.PubKey // the feed
+	ACL  ACL           // the ACL
+}
+
+// An ACLInfo represents reply
+// of the ACL RPC method
+type ACLInfo struct {
+	ACL ACL  // the ACL
+	Own bool // false if the feed is open to any peer
+}
+
+// ACL is RPC method
+func (r *RPC) ACL(pk cipher.PubKey, ai *ACLInfo) (_ error) {
+	ai.ACL, ai.Own = r.n.ACL(pk)
+	return
+}
+
+// SetACL is RPC method. The ACL is not saved
+// and is lost after restart of the Node
+func (r *RPC) SetACL(aa ACLArgs, _ *struct{}) (err error) {
+	return r.n.SetACL(aa.Feed, aa.ACL)
+}
+
+// DelACL is RPC method
+func (r *RPC) DelACL(pk cipher.PubKey, _ *struct{}) (_ error) {
+	r.n.DelACL(pk)
+	return
+}
+
+// ACLs is RPC method
+func (r *RPC) ACLs(_ struct{}, acls *ACLs) (_ error) {
+	*acls = r.n.ACLs()
+	return
+}
+
+// A PeerArgs represents arguments of
+// the AddPeer and DelPeer RPC methods
+type PeerArgs struct {
+	Address string          // network address
+	TCP     bool            // tcp or udp
+	Feeds   []cipher.PubKey // feeds to subscribe to (AddPeer only)
+}
+
+// AddPeer is RPC method
+func (r *RPC) AddPeer(pa PeerArgs, _ *struct{}) (err error) {
+	return r.n.AddPeer(pa.Address, pa.TCP, pa.Feeds...)
+}
+
+// DelPeer is RPC method
+func (r *RPC) DelPeer(pa PeerArgs, _ *struct{}) (err error) {
+	return r.n.DelPeer(pa.Address, pa.TCP)
+}
+
+// Peers is RPC method
+func (r *RPC) Peers(_ struct{}, ps *[]PeerInfo) (_ error) {
+	*ps = r.n.Peers()
+	return
+}
+
+// A BanArgs represents arguments of
+// the Ban and Unban RPC methods
+type BanArgs struct {
+	PeerID   cipher.PubKey // PeerID or blank
+	IP       string        // IP address or empty
+	Duration time.Duration // zero for permanent (Ban only)
+	Reason   string        // reason (Ban only)
+}
+
+// Ban is RPC method
+func (r *RPC) Ban(ba BanArgs, _ *struct{}) (err error) {
+	return r.n.Ban(ba.PeerID, ba.IP, ba.Duration, ba.Reason)
+}
+
+// Unban is RPC method
+func (r *RPC) Unban(ba BanArgs, _ *struct{}) (err error) {
+	return r.n.Unban(ba.PeerID, ba.IP)
+}
+
+// Bans is RPC method
+func (r *RPC) Bans(_ struct{}, bans *[]Ban) (_ error) {
+	*bans = r.n.Bans()
+	return
+}
+
+// Score is RPC method
+func (r *RPC) Score(id cipher.PubKey, score *int) (_ error) {
+	*score = r.n.Score(id)
+	return
+}
+
+// ID is RPC method
+func (r *RPC) ID(_ struct{}, id *cipher.PubKey) (_ error) {
+	*id = r.n.ID()
+	return
+}
+
+// RotateID is RPC method
+func (r *RPC) RotateID(_ struct{}, id *cipher.PubKey) (err error) {
+	*id, err = r.n.RotateID()
+	return
+}
+
 // A TCPRPC represents RPC object
 // of TCP transport of the Node
 type TCPRPC struct {
@@ -219,6 +449,18 @@ func (t *TCPRPC) RemoteFeeds(address string, rfs *[]cipher.PubKey) (err error) {
 	return errors.New("to TCP transport")
 }
 
+// Stat is RPC method
+func (t *TCPRPC) Stat(address string, cs *ConnStat) (err error) {
+	if tcp := t.n.getTCP(); tcp != nil {
+		if c := tcp.getConn(address); c != nil {
+			*cs = c.Stat()
+			return
+		}
+		return errors.New("no such connection")
+	}
+	return errors.New("to TCP transport")
+}
+
 // Address is RPC method
 func (t *TCPRPC) Address(_ struct{}, address *string) (_ error) {
 	if tcp := t.n.getTCP(); tcp != nil {
@@ -289,6 +531,18 @@ func (u *UDPRPC) RemoteFeeds(address string, rfs *[]cipher.PubKey) (err error) {
 	return errors.New("to UDP transport")
 }
 
+// Stat is RPC method
+func (u *UDPRPC) Stat(address string, cs *ConnStat) (err error) {
+	if udp := u.n.getUDP(); udp != nil {
+		if c := udp.getConn(address); c != nil {
+			*cs = c.Stat()
+			return
+		}
+		return errors.New("no such connection")
+	}
+	return errors.New("to UDP transport")
+}
+
 // Address is RPC method
 func (u *UDPRPC) Address(_ struct{}, address *string) (_ error) {
 	if tcp := u.n.getTCP(); tcp != nil {
@@ -350,3 +604,294 @@ func (r *RootRPC) Last(feed cipher.PubKey, z *registry.Root) (err error) {
 	*z = *x
 	return
 }
+
+// Object returns encoded object by hash (RPC method)
+func (r *RootRPC) Object(key cipher.SHA256, val *[]byte) (err error) {
+	var v []byte
+	if v, _, err = r.n.c.Get(key, 0); err != nil {
+		return
+	}
+	*val = v
+	return
+}
+
+// A DecodeArgs represents arguments of
+// the Decode RPC method
+type DecodeArgs struct {
+	Registry registry.RegistryRef // registry of the object
+	Schema   registry.SchemaRef   // schema of the object
+	Hash     cipher.SHA256        // the object
+	Depth    int                  // depth of references expansion
+}
+
+// Decode object with given schema and return it
+// as JSON document (RPC method). See registry.ToJSON
+// for details
+func (r *RootRPC) Decode(da DecodeArgs, doc *string) (err error) {
+
+	var reg *registry.Registry
+	if reg, err = r.n.c.Registry(da.Registry); err != nil {
+		return
+	}
+
+	var sch registry.Schema
+	if sch, err = reg.SchemaByReference(da.Schema); err != nil {
+		return
+	}
+
+	var val []byte
+	if val, _, err = r.n.c.Get(da.Hash, 0); err != nil {
+		return
+	}
+
+	var pack registry.Pack
+	if pack, err = r.n.c.Pack(nil, reg); err != nil {
+		return
+	}
+
+	var x interface{}
+	if x, err = registry.ToJSON(pack, sch, val, da.Depth); err != nil {
+		return
+	}
+
+	var b []byte
+	if b, err = json.MarshalIndent(x, "", "  "); err != nil {
+		return
+	}
+
+	*doc = string(b)
+	return
+}
+
+// Heads of given feed (RPC method)
+func (r *RootRPC) Heads(feed cipher.PubKey, heads *[]uint64) (err error) {
+	var hs []uint64
+	if hs, err = r.n.c.Heads(feed); err != nil {
+		return
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i] < hs[j] })
+	*heads = hs
+	return
+}
+
+// A RootsArgs represents arguments of
+// the Roots RPC method
+type RootsArgs struct {
+	Feed    cipher.PubKey // feed
+	Nonce   uint64        // head
+	From    uint64        // seq to start from (zero is last for Descend)
+	Limit   int           // max Root objects (zero for all)
+	Descend bool          // iterate descending order
+}
+
+// Roots of given head (RPC method). The Roots returns page
+// of Root objects starting from given seq. If the Descend
+// is true, then the Roots descends from the seq
+func (r *RootRPC) Roots(ra RootsArgs, rs *[]registry.Root) (err error) {
+
+	var seqs []uint64
+
+	// collect seq numbers first, the Root method of
+	// the Container can't be used inside the Tx
+
+	err = r.n.c.DB().IdxDB().Tx(func(fs data.Feeds) (err error) {
+
+		var hs data.Heads
+		if hs, err = fs.Heads(ra.Feed); err != nil {
+			return
+		}
+
+		var roots data.Roots
+		if roots, err = hs.Roots(ra.Nonce); err != nil {
+			return
+		}
+
+		var iterate, skip = roots.Ascend, func(seq uint64) bool {
+			return seq < ra.From
+		}
+
+		if ra.Descend == true {
+			iterate, skip = roots.Descend, func(seq uint64) bool {
+				return ra.From != 0 && seq > ra.From
+			}
+		}
+
+		return iterate(func(dr *data.Root) (err error) {
+			if skip(dr.Seq) == true {
+				return
+			}
+			if ra.Limit > 0 && len(seqs) == ra.Limit {
+				return data.ErrStopIteration
+			}
+			seqs = append(seqs, dr.Seq)
+			return
+		})
+
+	})
+
+	if err != nil {
+		return
+	}
+
+	var page = make([]registry.Root, 0, len(seqs))
+
+	for _, seq := range seqs {
+
+		var x *registry.Root
+		if x, err = r.n.c.Root(ra.Feed, ra.Nonce, seq); err != nil {
+			return
+		}
+
+		page = append(page, *x)
+	}
+
+	*rs = page
+	return
+}
+
+// A SchemaInfo represents brief
+// information about a Schema
+type SchemaInfo struct {
+	Name   string             // name of the Schema
+	Ref    registry.SchemaRef // reference
+	Kind   string             // kind
+	Fields []string           // fields of a struct
+}
+
+// A RegistryInfo represents brief
+// information about a Registry
+type RegistryInfo struct {
+	Ref     registry.RegistryRef // reference
+	Schemas []SchemaInfo         // registered schemas
+}
+
+// Registry of Root (RPC method)
+func (r *RootRPC) Registry(rs RootSelector, ri *RegistryInfo) (err error) {
+
+	var x *registry.Root
+	if x, err = r.n.c.Root(rs.Feed, rs.Nonce, rs.Seq); err != nil {
+		return
+	}
+
+	var reg *registry.Registry
+	if reg, err = r.n.c.Registry(x.Reg); err != nil {
+		return
+	}
+
+	ri.Ref = reg.Reference()
+	ri.Schemas = nil
+
+	for _, sch := range reg.Schemas() {
+
+		var si = SchemaInfo{
+			Name: sch.Name(),
+			Ref:  sch.Reference(),
+			Kind: sch.Kind().String(),
+		}
+
+		for _, fl := range sch.Fields() {
+			si.Fields = append(si.Fields, fl.String())
+		}
+
+		ri.Schemas = append(ri.Schemas, si)
+	}
+
+	return
+}
+
+// A RefsArgs represents arguments
+// of the Refs RPC method
+type RefsArgs struct {
+	Registry registry.RegistryRef // registry
+	Hash     cipher.SHA256        // hash of the Refs
+	Schema   registry.SchemaRef   // schema of elements (optional)
+	From     int                  // first index
+	To       int                  // last index (exclusive), zero for all
+	Depth    int                  // depth of references expansion
+}
+
+// A RefsElement represents element of a Refs.
+// The Value is JSON document (see registry.ToJSON)
+// or empty string if schema of elements is not set
+type RefsElement struct {
+	Index int           // index
+	Hash  cipher.SHA256 // hash of the element
+	Value string        // JSON or empty string
+}
+
+// A RefsPage represents elements of
+// a Refs in given range of indices
+type RefsPage struct {
+	Length   int           // length of the Refs
+	Elements []RefsElement // the elements
+}
+
+// Refs returns elements of a Refs in given range (RPC
+// method). The Refs walks one level of the Refs only
+// and expands elements only if the schema is set
+func (r *RootRPC) Refs(ra RefsArgs, rp *RefsPage) (err error) {
+
+	var reg *registry.Registry
+	if reg, err = r.n.c.Registry(ra.Registry); err != nil {
+		return
+	}
+
+	var sch registry.Schema
+	if ra.Schema.IsBlank() == false {
+		if sch, err = reg.SchemaByReference(ra.Schema); err != nil {
+			return
+		}
+	}
+
+	var pack registry.Pack
+	if pack, err = r.n.c.Pack(nil, reg); err != nil {
+		return
+	}
+
+	var refs = registry.Refs{Hash: ra.Hash}
+
+	if rp.Length, err = refs.Len(pack); err != nil {
+		return
+	}
+
+	rp.Elements = nil
+
+	if ra.From >= rp.Length {
+		return // empty page
+	}
+
+	err = refs.AscendFrom(pack, ra.From,
+		func(i int, hash cipher.SHA256) (err error) {
+
+			if ra.To > 0 && i >= ra.To {
+				return registry.ErrStopIteration
+			}
+
+			var el = RefsElement{Index: i, Hash: hash}
+
+			if sch != nil {
+
+				var val []byte
+				if val, err = pack.Get(hash); err != nil {
+					return
+				}
+
+				var x interface{}
+				if x, err = registry.ToJSON(pack, sch, val, ra.Depth); err != nil {
+					return
+				}
+
+				var b []byte
+				if b, err = json.Marshal(x); err != nil {
+					return
+				}
+
+				el.Value = string(b)
+			}
+
+			rp.Elements = append(rp.Elements, el)
+			return
+		})
+
+	return
+}
@@ -2,9 +2,11 @@ package node
 
 import (
 	"net/rpc"
+	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
 
+	"github.com/skycoin/cxo/skyobject"
 	"github.com/skycoin/cxo/skyobject/registry"
 )
 
@@ -115,6 +117,203 @@ func (r *RPCClientNode) Stat() (stat *Stat, err error) {
 	return &s, nil
 }
 
+// Backup writes archive of given feeds (or all feeds if
+// the feeds argument is empty) to given path. The path is
+// path on machine of the Node, and the path must not exist
+func (r *RPCClientNode) Backup(
+	path string, //            : path to archive
+	feeds ...cipher.PubKey, // : feeds to backup
+) (
+	err error, //              : an error
+) {
+
+	err = r.r.c.Call("node.Backup", BackupArgs{path, feeds}, &struct{}{})
+	return
+}
+
+// AddFeedKey adds secret key of a feed to the Node.
+// The Node starts sharing the feed
+func (r *RPCClientNode) AddFeedKey(sk cipher.SecKey) (pk cipher.PubKey,
+	err error) {
+
+	err = r.r.c.Call("node.AddFeedKey", sk, &pk)
+	return
+}
+
+// DelFeedKey removes secret key of given feed from the Node
+func (r *RPCClientNode) DelFeedKey(pk cipher.PubKey) (err error) {
+	return r.r.c.Call("node.DelFeedKey", pk, &struct{}{})
+}
+
+// FeedKeys returns feeds the Node has secret keys of
+func (r *RPCClientNode) FeedKeys() (pks []cipher.PubKey, err error) {
+	err = r.r.c.Call("node.FeedKeys", struct{}{}, &pks)
+	return
+}
+
+// UnlockKeys unlocks KeyStore of the Node
+func (r *RPCClientNode) UnlockKeys(passphrase []byte) (err error) {
+	return r.r.c.Call("node.UnlockKeys", passphrase, &struct{}{})
+}
+
+// Publish new Root object (see PublishArgs)
+func (r *RPCClientNode) Publish(pa PublishArgs) (z *registry.Root, err error) {
+	var x registry.Root
+	if err = r.r.c.Call("node.Publish", pa, &x); err != nil {
+		return
+	}
+	return &x, nil
+}
+
+// AddPeer adds known peer (see (*Node).AddPeer)
+func (r *RPCClientNode) AddPeer(
+	address string, //        : network address
+	isTCP bool, //            : tcp or udp
+	feeds ...cipher.PubKey, // : feeds to subscribe to
+) (
+	err error, //             : an error
+) {
+
+	err = r.r.c.Call("node.AddPeer", PeerArgs{address, isTCP, feeds},
+		&struct{}{})
+	return
+}
+
+// DelPeer removes known peer
+func (r *RPCClientNode) DelPeer(address string, isTCP bool) (err error) {
+	return r.r.c.Call("node.DelPeer", PeerArgs{Address: address, TCP: isTCP},
+		&struct{}{})
+}
+
+// Peers returns known peers
+func (r *RPCClientNode) Peers() (ps []PeerInfo, err error) {
+	err = r.r.c.Call("node.Peers", struct{}{}, &ps)
+	return
+}
+
+// Ban peer by PeerID and (or) by IP address,
+// zero duration means permanent ban
+func (r *RPCClientNode) Ban(
+	id cipher.PubKey, // : PeerID or blank
+	ip string, //        : IP address or empty
+	d time.Duration, //  : duration, zero for permanent
+	reason string, //    : reason of the ban
+) (
+	err error, //        : an error
+) {
+
+	err = r.r.c.Call("node.Ban", BanArgs{id, ip, d, reason}, &struct{}{})
+	return
+}
+
+// Unban peer by PeerID and (or) by IP address
+func (r *RPCClientNode) Unban(id cipher.PubKey, ip string) (err error) {
+	return r.r.c.Call("node.Unban", BanArgs{PeerID: id, IP: ip}, &struct{}{})
+}
+
+// Bans returns list of active bans
+func (r *RPCClientNode) Bans() (bans []Ban, err error) {
+	err = r.r.c.Call("node.Bans", struct{}{}, &bans)
+	return
+}
+
+// Score returns score of violations of given peer
+func (r *RPCClientNode) Score(id cipher.PubKey) (score int, err error) {
+	err = r.r.c.Call("node.Score", id, &score)
+	return
+}
+
+// ID returns identity of the Node
+func (r *RPCClientNode) ID() (id cipher.PubKey, err error) {
+	err = r.r.c.Call("node.ID", struct{}{}, &id)
+	return
+}
+
+// RotateID generates new identity of the Node
+// and returns it
+func (r *RPCClientNode) RotateID() (id cipher.PubKey, err error) {
+	err = r.r.c.Call("node.RotateID", struct{}{}, &id)
+	return
+}
+
+// Retention returns Retention policy of given feed,
+// the own is false if the feed has not its own policy
+func (r *RPCClientNode) Retention(
+	pk cipher.PubKey, //             : the feed
+) (
+	rt skyobject.Retention, //       : the policy
+	own bool, //                     : own or default
+	err error, //                    : an error
+) {
+
+	var ri RetentionInfo
+	if err = r.r.c.Call("node.Retention", pk, &ri); err != nil {
+		return
+	}
+
+	return ri.Retention, ri.Own, nil
+}
+
+// SetRetention sets Retention policy of given feed
+func (r *RPCClientNode) SetRetention(
+	pk cipher.PubKey,
+	rt skyobject.Retention,
+) (
+	err error,
+) {
+
+	err = r.r.c.Call("node.SetRetention", RetentionArgs{pk, rt}, &struct{}{})
+	return
+}
+
+// DelRetention removes Retention policy of given
+// feed, default policy will be used for the feed
+func (r *RPCClientNode) DelRetention(pk cipher.PubKey) (err error) {
+	return r.r.c.Call("node.DelRetention", pk, &struct{}{})
+}
+
+// Retain removes Root objects of given feed by its Retention
+// policy immediately, the removed is number of removed Root
+func (r *RPCClientNode) Retain(pk cipher.PubKey) (removed int, err error) {
+	err = r.r.c.Call("node.Retain", pk, &removed)
+	return
+}
+
+// ACL returns ACL of given feed, the own
+// is false if the feed is open to any peer
+func (r *RPCClientNode) ACL(
+	pk cipher.PubKey, // : the feed
+) (
+	acl ACL, //          : the ACL
+	own bool, //         : own or open
+	err error, //        : an error
+) {
+
+	var ai ACLInfo
+	if err = r.r.c.Call("node.ACL", pk, &ai); err != nil {
+		return
+	}
+
+	return ai.ACL, ai.Own, nil
+}
+
+// SetACL sets ACL of given feed
+func (r *RPCClientNode) SetACL(pk cipher.PubKey, acl ACL) (err error) {
+	return r.r.c.Call("node.SetACL", ACLArgs{pk, acl}, &struct{}{})
+}
+
+// DelACL removes ACL of given feed, the
+// feed will be open to any peer
+func (r *RPCClientNode) DelACL(pk cipher.PubKey) (err error) {
+	return r.r.c.Call("node.DelACL", pk, &struct{}{})
+}
+
+// ACLs returns all ACLs set
+func (r *RPCClientNode) ACLs() (acls ACLs, err error) {
+	err = r.r.c.Call("node.ACLs", struct{}{}, &acls)
+	return
+}
+
 // A RPCClientTCP implements RPC
 // methods related to TCP transport
 type RPCClientTCP struct {
@@ -157,6 +356,12 @@ func (r *RPCClientTCP) RemoteFeeds(
 	return
 }
 
+// Stat of connection
+func (r *RPCClientTCP) Stat(address string) (cs ConnStat, err error) {
+	err = r.r.c.Call("tcp.Stat", address, &cs)
+	return
+}
+
 // Address of TCP listener
 func (r *RPCClientTCP) Address() (address string, err error) {
 	err = r.r.c.Call("tcp.Address", struct{}{}, &address)
@@ -205,6 +410,12 @@ func (r *RPCClientUDP) RemoteFeeds(
 	return
 }
 
+// Stat of connection
+func (r *RPCClientUDP) Stat(address string) (cs ConnStat, err error) {
+	err = r.r.c.Call("udp.Stat", address, &cs)
+	return
+}
+
 // Address of UDP listener
 func (r *RPCClientUDP) Address() (address string, err error) {
 	err = r.r.c.Call("udp.Address", struct{}{}, &address)
@@ -262,3 +473,66 @@ func (r *RPCClientRoot) Last(
 	}
 	return &x, nil
 }
+
+// Object returns encoded object by hash
+func (r *RPCClientRoot) Object(key cipher.SHA256) (val []byte, err error) {
+	err = r.r.c.Call("root.Object", key, &val)
+	return
+}
+
+// Decode object with given schema. The
+// result is JSON document
+func (r *RPCClientRoot) Decode(
+	reg registry.RegistryRef, // : registry
+	sch registry.SchemaRef, //   : schema of the object
+	key cipher.SHA256, //        : hash of the object
+	depth int, //                : depth of references expansion
+) (
+	doc string, //               : JSON document
+	err error, //                : an error
+) {
+
+	err = r.r.c.Call("root.Decode", DecodeArgs{reg, sch, key, depth}, &doc)
+	return
+}
+
+// Heads of given feed
+func (r *RPCClientRoot) Heads(feed cipher.PubKey) (heads []uint64, err error) {
+	err = r.r.c.Call("root.Heads", feed, &heads)
+	return
+}
+
+// Roots returns page of Root objects of given head
+// starting from given seq (see RootsArgs)
+func (r *RPCClientRoot) Roots(ra RootsArgs) (rs []registry.Root, err error) {
+	err = r.r.c.Call("root.Roots", ra, &rs)
+	return
+}
+
+// Registry of Root object
+func (r *RPCClientRoot) Registry(
+	feed cipher.PubKey,
+	nonce uint64,
+	seq uint64,
+) (
+	ri *RegistryInfo,
+	err error,
+) {
+
+	var x RegistryInfo
+	err = r.r.c.Call("root.Registry", RootSelector{feed, nonce, seq}, &x)
+	if err != nil {
+		return
+	}
+	return &x, nil
+}
+
+// Refs returns elements of a Refs in
+// given range (see RefsArgs)
+func (r *RPCClientRoot) Refs(ra RefsArgs) (rp *RefsPage, err error) {
+	var x RefsPage
+	if err = r.r.c.Call("root.Refs", ra, &x); err != nil {
+		return
+	}
+	return &x, nil
+}
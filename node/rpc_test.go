@@ -0,0 +1,154 @@
+package node
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+
+	"github.com/skycoin/cxo/skyobject"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestRootRPC(t *testing.T) {
+
+	var n = getTestNodeNotListen("test")
+	defer n.Close()
+
+	var (
+		pk, sk = cipher.GenerateKeyPair()
+		reg    = getTestRegistry()
+		c      = n.Container()
+		rr     = &RootRPC{n}
+
+		up   *skyobject.Unpack
+		feed Feed
+		err  error
+	)
+
+	assertNil(t, n.Share(pk))
+
+	up, err = c.Unpack(sk, reg)
+	assertNil(t, err)
+
+	var r = new(registry.Root)
+	r.Pub = pk
+	r.Nonce = 9021
+
+	for i := 0; i < 5; i++ {
+
+		assertNil(t, feed.Posts.AppendValues(up, Post{
+			Head: fmt.Sprintf("Head #%d", i),
+			Body: fmt.Sprintf("Body #%d", i),
+		}))
+
+		r.Refs = []registry.Dynamic{dynamicByValue(t, up, "test.Feed", feed)}
+		assertNil(t, c.Save(up, r))
+	}
+
+	var feedSch, postSch registry.Schema
+
+	feedSch, err = reg.SchemaByName("test.Feed")
+	assertNil(t, err)
+
+	postSch, err = reg.SchemaByName("test.Post")
+	assertNil(t, err)
+
+	t.Run("object", func(t *testing.T) {
+
+		var val []byte
+		assertNil(t, rr.Object(r.Refs[0].Hash, &val))
+
+		if string(val) != string(encoder.Serialize(feed)) {
+			t.Error("wrong object")
+		}
+
+		var doc string
+		assertNil(t, rr.Decode(DecodeArgs{
+			Registry: reg.Reference(),
+			Schema:   feedSch.Reference(),
+			Hash:     r.Refs[0].Hash,
+			Depth:    -1,
+		}, &doc))
+
+		if strings.Contains(doc, "Head #4") == false {
+			t.Error("wrong decoded object", doc)
+		}
+
+	})
+
+	t.Run("roots", func(t *testing.T) {
+
+		var heads []uint64
+		assertNil(t, rr.Heads(pk, &heads))
+
+		if len(heads) != 1 || heads[0] != r.Nonce {
+			t.Error("wrong heads", heads)
+		}
+
+		var rs []registry.Root
+		assertNil(t, rr.Roots(RootsArgs{
+			Feed:  pk,
+			Nonce: r.Nonce,
+			From:  1,
+			Limit: 2,
+		}, &rs))
+
+		if len(rs) != 2 || rs[0].Seq != 1 || rs[1].Seq != 2 {
+			t.Error("wrong page", rs)
+		}
+
+		assertNil(t, rr.Roots(RootsArgs{
+			Feed:    pk,
+			Nonce:   r.Nonce,
+			Limit:   2,
+			Descend: true,
+		}, &rs))
+
+		if len(rs) != 2 || rs[0].Seq != 4 || rs[1].Seq != 3 {
+			t.Error("wrong page", rs)
+		}
+
+	})
+
+	t.Run("registry", func(t *testing.T) {
+
+		var ri RegistryInfo
+		assertNil(t, rr.Registry(RootSelector{pk, r.Nonce, r.Seq}, &ri))
+
+		if ri.Ref != reg.Reference() || len(ri.Schemas) != 3 {
+			t.Fatal("wrong registry info", ri)
+		}
+
+		if ri.Schemas[0].Name != "test.Feed" || len(ri.Schemas[0].Fields) != 1 {
+			t.Error("wrong schema info", ri.Schemas[0])
+		}
+
+	})
+
+	t.Run("refs", func(t *testing.T) {
+
+		var rp RefsPage
+		assertNil(t, rr.Refs(RefsArgs{
+			Registry: reg.Reference(),
+			Hash:     feed.Posts.Hash,
+			Schema:   postSch.Reference(),
+			From:     3,
+			To:       10,
+		}, &rp))
+
+		if rp.Length != 5 || len(rp.Elements) != 2 {
+			t.Fatal("wrong page", rp)
+		}
+
+		if el := rp.Elements[0]; el.Index != 3 ||
+			strings.Contains(el.Value, "Head #3") == false {
+
+			t.Error("wrong element", el)
+		}
+
+	})
+
+}
@@ -0,0 +1,137 @@
+package node
+
+import (
+	"crypto/aes"
+	gcipher "crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// encrypted messege
+//
+// [ 8 nonce counter ][ sealed messege + tag ]
+//
+
+const nonceCounterSize = 8
+
+// a connCipher encrypts and decrypts raw messeges
+// of a Conn using AES-256-GCM. There are two keys
+// derived from ECDH shared secret: one for every
+// direction. The nonce is a counter that sent with
+// every messege in plain, and a messege with counter
+// less or equal to the last received is rejected.
+// Thus, it's impossible to replay a messege.
+//
+// The replay check requires ordered delivery without
+// losses. TCP guarantees it. And UDP connections of
+// the factory are reliable too: they acknowledge and
+// resend lost packets and reorder received packets
+// by their sequence numbers before delivering them
+type connCipher struct {
+	smx  sync.Mutex   // lock for sealing
+	seal gcipher.AEAD // outgoing
+	sn   uint64       // outgoing counter (under lock)
+	open gcipher.AEAD // incoming
+	rn   uint64       // last incoming counter (receiving goroutine only)
+}
+
+// newConnCipher creates connCipher using secret key of
+// this node, id of the peer and hash of entire handshake
+// (see msg.AuthHash); the isIncoming used to choose key
+// for every direction
+func newConnCipher(
+	sk cipher.SecKey, //           : secret key of this node
+	peer cipher.PubKey, //         : peer id
+	transcript cipher.SHA256, //   : hash of handshake
+	isIncoming bool, //            : is incoming connection
+) (
+	cc *connCipher, //             : the cipher
+	err error, //                  : an error
+) {
+
+	if err = peer.Verify(); err != nil {
+		return
+	}
+
+	var shared = append(cipher.ECDH(peer, sk), transcript[:]...)
+
+	var (
+		master = cipher.SumSHA256(shared)
+
+		synKey = cipher.SumSHA256(append(master[:], 's', 'y', 'n'))
+		ackKey = cipher.SumSHA256(append(master[:], 'a', 'c', 'k'))
+	)
+
+	cc = new(connCipher)
+
+	// the initiator seals using the synKey
+	// and the acceptor seals using the ackKey
+
+	if isIncoming == true {
+		synKey, ackKey = ackKey, synKey
+	}
+
+	if cc.seal, err = newAEAD(synKey); err != nil {
+		return
+	}
+
+	cc.open, err = newAEAD(ackKey)
+	return
+}
+
+func newAEAD(key cipher.SHA256) (aead gcipher.AEAD, err error) {
+
+	var block gcipher.Block
+	if block, err = aes.NewCipher(key[:]); err != nil {
+		return
+	}
+
+	return gcipher.NewGCM(block)
+}
+
+func (cc *connCipher) nonce(n uint64) (nonce []byte) {
+	nonce = make([]byte, cc.seal.NonceSize())
+	binary.LittleEndian.PutUint64(nonce, n)
+	return
+}
+
+// encrypt given raw messege; the lock must be held
+// by caller since messeges must be sent in order
+func (cc *connCipher) encrypt(raw []byte) (enc []byte) {
+
+	cc.sn++
+
+	enc = make([]byte, nonceCounterSize,
+		nonceCounterSize+len(raw)+cc.seal.Overhead())
+
+	binary.LittleEndian.PutUint64(enc, cc.sn)
+
+	return cc.seal.Seal(enc, cc.nonce(cc.sn), raw, enc[:nonceCounterSize])
+}
+
+// decrypt received messege
+func (cc *connCipher) decrypt(enc []byte) (raw []byte, err error) {
+
+	if len(enc) < nonceCounterSize+cc.open.Overhead() {
+		return nil, errors.New("invalid encrypted messege: small size")
+	}
+
+	var n = binary.LittleEndian.Uint64(enc)
+
+	if n <= cc.rn {
+		return nil, errors.New("invalid encrypted messege: replayed")
+	}
+
+	raw, err = cc.open.Open(nil, cc.nonce(n), enc[nonceCounterSize:],
+		enc[:nonceCounterSize])
+
+	if err != nil {
+		return
+	}
+
+	cc.rn = n
+	return
+}
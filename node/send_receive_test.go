@@ -376,3 +376,279 @@ func Test_send_receive_refs(t *testing.T) {
 	_ = rr
 
 }
+
+// filling using batch requests and prefetching
+func Test_send_receive_batch(t *testing.T) {
+
+	t.Run("one by one", func(t *testing.T) {
+		testSendReceiveBatch(t, 1, 0)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		testSendReceiveBatch(t, MaxRequestObjects, 0)
+	})
+
+	t.Run("prefetch", func(t *testing.T) {
+		testSendReceiveBatch(t, MaxRequestObjects, MaxPrefetchVolume)
+	})
+
+	t.Run("small prefetch", func(t *testing.T) {
+		testSendReceiveBatch(t, 4, 128)
+	})
+
+}
+
+func testSendReceiveBatch(t *testing.T, maxObjects, maxPrefetch int) {
+
+	var (
+		fr, onRootFilled = onRootFilledToChannel(100)
+		sn               = getTestNode("sender")
+		rconf            = getTestConfig("receiver")
+	)
+
+	rconf.TCP.Listen, rconf.UDP.Listen = "", ""       // don't listen
+	rconf.OnRootFilled = onRootFilled                 // callback
+	rconf.OnFillingBreaks = onFillingBreaksTestLog(t) // log
+
+	rconf.MaxRequestObjects = maxObjects
+	rconf.MaxPrefetchVolume = maxPrefetch
+
+	var rn, err = NewNode(rconf)
+	assertNil(t, err)
+
+	defer sn.Close()
+	defer rn.Close()
+
+	var pk, sk = cipher.GenerateKeyPair()
+
+	assertNil(t, sn.Share(pk))
+	assertNil(t, rn.Share(pk))
+
+	var (
+		sc = sn.Container()
+		up *skyobject.Unpack
+	)
+
+	up, err = sc.Unpack(sk, getTestRegistry())
+	assertNil(t, err)
+
+	var (
+		r    = new(registry.Root)
+		feed Feed
+	)
+
+	r.Nonce = 9021
+	r.Pub = pk
+
+	for i := 0; i < 256; i++ {
+		assertNil(t, feed.Posts.AppendValues(up, Post{
+			Head: fmt.Sprintf("Head #%d", i),
+			Body: fmt.Sprintf("Body #%d", i),
+		}))
+	}
+
+	r.Refs = append(r.Refs, dynamicByValue(t, up, "test.Feed", feed))
+
+	assertNil(t, sc.Save(up, r))
+
+	var c *Conn
+	c, err = rn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	assertNil(t, c.Subscribe(pk))
+
+	var rr *registry.Root
+
+	select {
+	case rr = <-fr:
+	case <-time.After(64 * TM):
+		t.Fatal("slow")
+	}
+
+	assertTrue(t, rr.Hash == r.Hash, "wrong Root received")
+
+	// all objects of the Root should be received
+
+	var rc = rn.Container()
+
+	assertNil(t, sc.Walk(r, func(key cipher.SHA256, _ int) (bool, error) {
+		if _, _, err := rc.Get(key, 0); err != nil {
+			t.Error("missing object:", key.Hex()[:7], err)
+		}
+		return true, nil
+	}))
+
+}
+
+// next Root of a head sent with delta
+func Test_send_receive_delta(t *testing.T) {
+
+	var (
+		fr, onRootFilled = onRootFilledToChannel(100)
+		sn               = getTestNode("sender")
+		rconf            = getTestConfig("receiver")
+	)
+
+	rconf.TCP.Listen, rconf.UDP.Listen = "", ""       // don't listen
+	rconf.OnRootFilled = onRootFilled                 // callback
+	rconf.OnFillingBreaks = onFillingBreaksTestLog(t) // log
+
+	var rn, err = NewNode(rconf)
+	assertNil(t, err)
+
+	defer sn.Close()
+	defer rn.Close()
+
+	var pk, sk = cipher.GenerateKeyPair()
+
+	assertNil(t, sn.Share(pk))
+	assertNil(t, rn.Share(pk))
+
+	var (
+		sc = sn.Container()
+		up *skyobject.Unpack
+	)
+
+	up, err = sc.Unpack(sk, getTestRegistry())
+	assertNil(t, err)
+
+	var (
+		r    = new(registry.Root)
+		feed Feed
+	)
+
+	r.Nonce = 9021
+	r.Pub = pk
+
+	var appendPosts = func(n int) {
+		for i := 0; i < n; i++ {
+			assertNil(t, feed.Posts.AppendValues(up, Post{
+				Head: fmt.Sprintf("Head #%d", i),
+				Body: fmt.Sprintf("Body #%d", i),
+				Time: time.Now().UnixNano(),
+			}))
+		}
+		r.Refs = []registry.Dynamic{dynamicByValue(t, up, "test.Feed", feed)}
+		assertNil(t, sc.Save(up, r))
+	}
+
+	var waitRoot = func(seq uint64) {
+		select {
+		case rr := <-fr:
+			assertTrue(t, rr.Seq == seq, "wrong Root filled")
+		case <-time.After(64 * TM):
+			t.Fatal("slow")
+		}
+	}
+
+	appendPosts(128)
+
+	var c *Conn
+	c, err = rn.TCP().Connect(sn.TCP().Address())
+	assertNil(t, err)
+
+	assertNil(t, c.Subscribe(pk))
+	waitRoot(0)
+
+	// next Root
+
+	appendPosts(3)
+
+	var delta = sn.rootDelta(r)
+	assertTrue(t, len(delta) > 0, "empty delta")
+	assertTrue(t, len(delta) < 128, "too big delta")
+
+	sn.Publish(r)
+	waitRoot(1)
+
+	var rc = rn.Container()
+
+	assertNil(t, sc.Walk(r, func(key cipher.SHA256, _ int) (bool, error) {
+		if _, _, err := rc.Get(key, 0); err != nil {
+			t.Error("missing object:", key.Hex()[:7], err)
+		}
+		return true, nil
+	}))
+
+}
+
+func Test_send_receive_history(t *testing.T) {
+
+	var (
+		filled = make(chan uint64, 10)
+		sn     = getTestNode("sender")
+		rconf  = getTestConfig("receiver")
+	)
+
+	rconf.TCP.Listen, rconf.UDP.Listen = "", "" // don't listen
+	rconf.OnRootFilled = func(_ *Node, r *registry.Root) {
+		filled <- r.Seq
+	}
+	rconf.OnFillingBreaks = onFillingBreaksTestLog(t) // log
+
+	var rn, err = NewNode(rconf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sn.Close()
+	defer rn.Close()
+
+	var pk, sk = cipher.GenerateKeyPair()
+
+	assertNil(t, sn.Share(pk))
+	assertNil(t, rn.Share(pk))
+
+	var (
+		reg = getTestRegistry()
+		sc  = sn.Container()
+
+		up *skyobject.Unpack
+	)
+
+	if up, err = sc.Unpack(sk, reg); err != nil {
+		t.Fatal(err)
+	}
+
+	var r = &registry.Root{Pub: pk, Nonce: 9021}
+
+	for i := 0; i < 5; i++ {
+		r.Refs = []registry.Dynamic{
+			dynamicByValue(t, up, "test.User", User{"Alice", uint32(i), nil}),
+		}
+		assertNil(t, sc.Save(up, r))
+	}
+
+	var c *Conn
+	if c, err = rn.TCP().Connect(sn.TCP().Address()); err != nil {
+		t.Fatal(err)
+	}
+
+	// from seq 1, limited by 3 Root objects, and the last one
+
+	assertNil(t, c.SubscribeFrom(pk, 0, 1, 0, 3))
+
+	for _, want := range []uint64{1, 2, 3, 4} {
+
+		select {
+		case seq := <-filled:
+			if seq != want {
+				t.Fatalf("wrong order: want %d, got %d", want, seq)
+			}
+		case <-time.After(4 * TM):
+			t.Fatal("slow")
+		}
+
+	}
+
+	var lr *registry.Root
+	if lr, err = rn.Container().LastRoot(pk, 9021); err != nil {
+		t.Fatal(err)
+	}
+
+	if lr.Seq != 4 {
+		t.Error("wrong last Root", lr.Seq)
+	}
+
+}
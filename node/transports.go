@@ -91,6 +91,16 @@ func (t *TCP) addAcceptedConnection(c *Conn) {
 	t.cs[c.Address()] = c
 }
 
+// remove closed connection
+func (t *TCP) delConn(c *Conn) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+
+	if t.cs[c.Address()] == c {
+		delete(t.cs, c.Address())
+	}
+}
+
 // Connect to given TCP address. The method blocks. If connection
 // with given address already exists, then the Connect returns this
 // existing connection.
@@ -157,8 +167,10 @@ func (t *TCP) discovery() (d *discovery.MessengerFactory) {
 // If Discovery is nil, then it will be created
 func (t *TCP) ConnectToDiscoveryServer(address string) (err error) {
 
+	var id, _, _ = t.n.identity()
+
 	err = t.discovery().ConnectWithConfig(address, &discovery.ConnConfig{
-		SeedConfig: t.n.id,
+		SeedConfig: id,
 
 		Reconnect:     true,
 		ReconnectWait: time.Second * 30,
@@ -369,6 +381,16 @@ func (u *UDP) addAcceptedConnection(c *Conn) {
 	u.cs[c.Address()] = c
 }
 
+// remove closed connection
+func (u *UDP) delConn(c *Conn) {
+	u.mx.Lock()
+	defer u.mx.Unlock()
+
+	if u.cs[c.Address()] == c {
+		delete(u.cs, c.Address())
+	}
+}
+
 // Connect to given UDP address. If connection with given
 // address already exists, then the Connect returns this
 // existing connection.
@@ -435,8 +457,10 @@ func (u *UDP) discovery() (d *discovery.MessengerFactory) {
 // If Discovery is nil, then it will be created
 func (u *UDP) ConnectToDiscoveryServer(address string) (err error) {
 
+	var id, _, _ = u.n.identity()
+
 	err = u.discovery().ConnectWithConfig(address, &discovery.ConnConfig{
-		SeedConfig: u.n.id,
+		SeedConfig: id,
 
 		Reconnect:     true,
 		ReconnectWait: time.Second * 30,
@@ -0,0 +1,688 @@
+package skyobject
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// backup related errors
+var (
+	ErrInvalidBackup = errors.New("invalid backup")
+	ErrNotEmptyDB    = errors.New("DB is not empty")
+)
+
+// BackupVersion is version of the backup format
+const BackupVersion uint32 = 1
+
+// backup format
+//
+//	[ 8 magic ][ 4 version ]
+//	records
+//	[ 1 end ][ 32 sha256 of all previous bytes ]
+//
+// records are
+//
+//	[ 1 feed ][ 33 pk ]
+//	[ 1 head ][ 33 pk ][ 8 nonce ]
+//	[ 1 root ][ 33 pk ][ 8 nonce ][ 4 len ][ encoded data.Root ]
+//	[ 1 object ][ 32 key ][ 4 rc ][ 4 len ][ value ]
+//
+// all numbers are big-endian; feeds, heads and Root objects
+// are first; rc of an object is number of references
+// to the object from the backup (not from the Container)
+const backupMagic = "CXOBCKUP"
+
+// types of records
+const (
+	backupTypeFeed byte = 1 + iota
+	backupTypeHead
+	backupTypeRoot
+	backupTypeObject
+	backupTypeEnd
+)
+
+// a Root of a backup
+type backupRoot struct {
+	pk    cipher.PubKey
+	nonce uint64
+	dr    *data.Root
+}
+
+// a head of a backup
+type backupHead struct {
+	pk    cipher.PubKey
+	nonce uint64
+}
+
+// walk Root objects counting references; the keys
+// are objects in walking order without repeats
+func (c *Container) walkRCs(
+	roots []cipher.SHA256, //         : hashes of Root objects to walk
+) (
+	keys []cipher.SHA256, //          : objects
+	rcs map[cipher.SHA256]uint32, // : references counters
+	err error, //                     : an error
+) {
+
+	rcs = make(map[cipher.SHA256]uint32)
+
+	for _, hash := range roots {
+
+		var r *registry.Root
+		if r, err = c.rootByHash(hash); err != nil {
+			return
+		}
+
+		err = c.Walk(r, func(
+			key cipher.SHA256,
+			_ int,
+		) (
+			deepper bool,
+			_ error,
+		) {
+
+			var rc = rcs[key]
+
+			if rc == 0 {
+				keys = append(keys, key)
+			}
+
+			rc++
+			rcs[key] = rc
+
+			return rc == 1, nil // go deepper for first look
+		})
+
+		if err != nil {
+			return
+		}
+
+	}
+
+	return
+}
+
+func backupHashes(roots []backupRoot) (hashes []cipher.SHA256) {
+	hashes = make([]cipher.SHA256, 0, len(roots))
+	for _, br := range roots {
+		hashes = append(hashes, br.dr.Hash)
+	}
+	return
+}
+
+// Backup writes consistent archive of Root objects of given
+// feeds with all related objects to given io.Writer. If
+// the feeds argument is empty, then all feeds are used. The
+// Backup blocks removing of Root objects (DelRoot, DelHead,
+// DelFeed and the garbage collector) until it returns. New
+// Root objects can be added during the Backup, but the
+// archive contains only Root objects that exist at the
+// moment the Backup starts. Use the Restore function to
+// load the archive
+func (c *Container) Backup(
+	w io.Writer, //               : write to
+	feeds ...cipher.PubKey, //    : feeds to backup (or all)
+) (
+	err error, //                 : an error
+) {
+
+	c.Index.dmx.Lock()
+	defer c.Index.dmx.Unlock()
+
+	var (
+		heads []backupHead
+		roots []backupRoot
+	)
+
+	if len(feeds) == 0 {
+		feeds = c.Feeds()
+	}
+
+	// snapshot of the IdxDB
+
+	err = c.db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+
+		for _, pk := range feeds {
+
+			var hs data.Heads
+			if hs, err = fs.Heads(pk); err != nil {
+				return
+			}
+
+			err = hs.Iterate(func(nonce uint64) (err error) {
+
+				heads = append(heads, backupHead{pk, nonce})
+
+				var rs data.Roots
+				if rs, err = hs.Roots(nonce); err != nil {
+					return
+				}
+
+				return rs.Ascend(func(dr *data.Root) (_ error) {
+					var cp = *dr // copy
+					roots = append(roots, backupRoot{pk, nonce, &cp})
+					return
+				})
+
+			})
+
+			if err != nil {
+				return
+			}
+
+		}
+
+		return
+	})
+
+	if err != nil {
+		return
+	}
+
+	var (
+		keys []cipher.SHA256
+		rcs  map[cipher.SHA256]uint32
+	)
+
+	if keys, rcs, err = c.walkRCs(backupHashes(roots)); err != nil {
+		return
+	}
+
+	var bw = newBackupWriter(w)
+
+	bw.header()
+
+	for _, pk := range feeds {
+		bw.feed(pk)
+	}
+
+	for _, bh := range heads {
+		bw.head(bh.pk, bh.nonce)
+	}
+
+	for _, br := range roots {
+		bw.root(br.pk, br.nonce, br.dr)
+	}
+
+	for _, key := range keys {
+
+		var val []byte
+		if val, _, err = c.Get(key, 0); err != nil {
+			return
+		}
+
+		bw.object(key, rcs[key], val)
+
+		if bw.err != nil {
+			return bw.err
+		}
+
+	}
+
+	return bw.end()
+}
+
+// Restore loads archive created by the Backup method of
+// a Container to DB described by given Config. The DB must
+// be empty (e.g. use fresh DataDir). The Restore checks
+// hashes of all objects, checksum of the archive, and
+// verifies references counters walking restored Root
+// objects. If the Restore fails, then the DB can contain
+// a part of the archive and should be removed
+func Restore(conf *Config, r io.Reader) (err error) {
+
+	var c *Container
+	if c, err = NewContainer(conf); err != nil {
+		return
+	}
+
+	defer func() {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	return c.restore(r)
+}
+
+func (c *Container) restore(r io.Reader) (err error) {
+
+	if all, _ := c.db.CXDS().Amount(); all != 0 || len(c.Feeds()) != 0 {
+		return ErrNotEmptyDB
+	}
+
+	var br = newBackupReader(r, c.conf.MaxObjectSize)
+
+	if err = br.header(); err != nil {
+		return
+	}
+
+	var (
+		roots []backupRoot
+		rcs   = make(map[cipher.SHA256]uint32)
+	)
+
+	for {
+
+		var tp byte
+		if tp, err = br.byte(); err != nil {
+			return
+		}
+
+		switch tp {
+
+		case backupTypeFeed:
+
+			var pk cipher.PubKey
+			if pk, err = br.pubKey(); err != nil {
+				return
+			}
+
+			err = c.db.IdxDB().Tx(func(fs data.Feeds) error {
+				return fs.Add(pk)
+			})
+
+		case backupTypeHead:
+
+			var bh backupHead
+			if bh.pk, err = br.pubKey(); err != nil {
+				return
+			}
+			if bh.nonce, err = br.uint64(); err != nil {
+				return
+			}
+
+			err = c.db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+				var hs data.Heads
+				if hs, err = fs.Heads(bh.pk); err != nil {
+					return
+				}
+				_, err = hs.Add(bh.nonce)
+				return
+			})
+
+		case backupTypeRoot:
+
+			var rt backupRoot
+			if rt, err = br.root(); err != nil {
+				return
+			}
+
+			err = c.db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+				var hs data.Heads
+				if hs, err = fs.Heads(rt.pk); err != nil {
+					return
+				}
+				var rs data.Roots
+				if rs, err = hs.Roots(rt.nonce); err != nil {
+					return
+				}
+				return rs.Set(rt.dr)
+			})
+
+			roots = append(roots, rt)
+
+		case backupTypeObject:
+
+			var (
+				key cipher.SHA256
+				rc  uint32
+				val []byte
+			)
+
+			if key, rc, val, err = br.object(); err != nil {
+				return
+			}
+
+			if cipher.SumSHA256(val) != key {
+				return fmt.Errorf("%v: wrong hash of object %s",
+					ErrInvalidBackup, key.Hex()[:7])
+			}
+
+			if _, ok := rcs[key]; ok == true || rc == 0 {
+				return fmt.Errorf("%v: repeated object or zero rc %s",
+					ErrInvalidBackup, key.Hex()[:7])
+			}
+
+			rcs[key] = rc
+			_, err = c.db.CXDS().Set(key, val, int(rc))
+
+		case backupTypeEnd:
+
+			if err = br.end(); err != nil {
+				return
+			}
+
+			return c.restoreVerify(roots, rcs)
+
+		default:
+
+			return fmt.Errorf("%v: unknown record type %d",
+				ErrInvalidBackup, tp)
+
+		}
+
+		if err != nil {
+			return
+		}
+
+	}
+
+}
+
+// verify references counters of restored objects
+func (c *Container) restoreVerify(
+	roots []backupRoot,
+	rcs map[cipher.SHA256]uint32,
+) (
+	err error,
+) {
+
+	var keys []cipher.SHA256
+	var got map[cipher.SHA256]uint32
+
+	if keys, got, err = c.walkRCs(backupHashes(roots)); err != nil {
+		return
+	}
+
+	if len(keys) != len(rcs) {
+		return fmt.Errorf("%v: %d objects are not used by Root objects",
+			ErrInvalidBackup, len(rcs)-len(keys))
+	}
+
+	for _, key := range keys {
+		if rc := rcs[key]; rc != got[key] {
+			return fmt.Errorf("%v: wrong rc of %s: %d, want %d",
+				ErrInvalidBackup, key.Hex()[:7], rc, got[key])
+		}
+	}
+
+	return
+}
+
+// a backupWriter keeps first error and
+// hashes all written bytes
+type backupWriter struct {
+	w   *bufio.Writer
+	h   hash.Hash
+	buf []byte
+	err error
+}
+
+func newBackupWriter(w io.Writer) (bw *backupWriter) {
+	bw = new(backupWriter)
+	bw.h = sha256.New()
+	bw.w = bufio.NewWriter(io.MultiWriter(w, bw.h))
+	return
+}
+
+func (b *backupWriter) write(p []byte) {
+	if b.err == nil {
+		_, b.err = b.w.Write(p)
+	}
+}
+
+func (b *backupWriter) header() {
+	b.buf = append(b.buf[:0], backupMagic...)
+	b.buf = appendUint32(b.buf, BackupVersion)
+	b.write(b.buf)
+}
+
+func (b *backupWriter) feed(pk cipher.PubKey) {
+	b.buf = append(b.buf[:0], backupTypeFeed)
+	b.buf = append(b.buf, pk[:]...)
+	b.write(b.buf)
+}
+
+func (b *backupWriter) head(pk cipher.PubKey, nonce uint64) {
+	b.buf = append(b.buf[:0], backupTypeHead)
+	b.buf = append(b.buf, pk[:]...)
+	b.buf = appendUint64(b.buf, nonce)
+	b.write(b.buf)
+}
+
+func (b *backupWriter) root(pk cipher.PubKey, nonce uint64, dr *data.Root) {
+	var p = dr.Encode()
+	b.buf = append(b.buf[:0], backupTypeRoot)
+	b.buf = append(b.buf, pk[:]...)
+	b.buf = appendUint64(b.buf, nonce)
+	b.buf = appendUint32(b.buf, uint32(len(p)))
+	b.write(b.buf)
+	b.write(p)
+}
+
+func (b *backupWriter) object(key cipher.SHA256, rc uint32, val []byte) {
+	b.buf = append(b.buf[:0], backupTypeObject)
+	b.buf = append(b.buf, key[:]...)
+	b.buf = appendUint32(b.buf, rc)
+	b.buf = appendUint32(b.buf, uint32(len(val)))
+	b.write(b.buf)
+	b.write(val)
+}
+
+func (b *backupWriter) end() (err error) {
+
+	b.write([]byte{backupTypeEnd})
+
+	if b.err != nil {
+		return b.err
+	}
+
+	if err = b.w.Flush(); err != nil {
+		return
+	}
+
+	// the sum is not hashed
+	var sum = b.h.Sum(nil)
+
+	if _, err = b.w.Write(sum); err != nil {
+		return
+	}
+
+	return b.w.Flush()
+}
+
+// a backupReader hashes all read bytes
+type backupReader struct {
+	r   io.Reader
+	h   hash.Hash
+	buf []byte
+	max int // max object size
+
+	invalid error // ErrInvalidBackup or ErrInvalidBundle
+}
+
+func newBackupReader(r io.Reader, max int) (br *backupReader) {
+	br = new(backupReader)
+	br.max = max
+	br.invalid = ErrInvalidBackup
+	br.h = sha256.New()
+	br.r = io.TeeReader(bufio.NewReader(r), br.h)
+	return
+}
+
+func (b *backupReader) read(n int) (p []byte, err error) {
+
+	if cap(b.buf) < n {
+		b.buf = make([]byte, n)
+	}
+
+	p = b.buf[:n]
+
+	if _, err = io.ReadFull(b.r, p); err == io.EOF ||
+		err == io.ErrUnexpectedEOF {
+
+		err = fmt.Errorf("%v: unexpected end", b.invalid)
+	}
+
+	return
+}
+
+func (b *backupReader) header() (err error) {
+
+	var p []byte
+	if p, err = b.read(len(backupMagic) + 4); err != nil {
+		return
+	}
+
+	if string(p[:len(backupMagic)]) != backupMagic {
+		return fmt.Errorf("%v: wrong magic", ErrInvalidBackup)
+	}
+
+	if vers := binary.BigEndian.Uint32(p[len(backupMagic):]); vers !=
+		BackupVersion {
+
+		return fmt.Errorf("%v: unsupported version %d",
+			ErrInvalidBackup, vers)
+	}
+
+	return
+}
+
+func (b *backupReader) byte() (tp byte, err error) {
+	var p []byte
+	if p, err = b.read(1); err != nil {
+		return
+	}
+	return p[0], nil
+}
+
+func (b *backupReader) uint32() (u uint32, err error) {
+	var p []byte
+	if p, err = b.read(4); err != nil {
+		return
+	}
+	return binary.BigEndian.Uint32(p), nil
+}
+
+func (b *backupReader) uint64() (u uint64, err error) {
+	var p []byte
+	if p, err = b.read(8); err != nil {
+		return
+	}
+	return binary.BigEndian.Uint64(p), nil
+}
+
+func (b *backupReader) pubKey() (pk cipher.PubKey, err error) {
+	var p []byte
+	if p, err = b.read(len(pk)); err != nil {
+		return
+	}
+	copy(pk[:], p)
+	return
+}
+
+func (b *backupReader) root() (br backupRoot, err error) {
+
+	if br.pk, err = b.pubKey(); err != nil {
+		return
+	}
+
+	if br.nonce, err = b.uint64(); err != nil {
+		return
+	}
+
+	var n uint32
+	if n, err = b.uint32(); err != nil {
+		return
+	}
+
+	if n > 1024 {
+		err = fmt.Errorf("%v: too large Root record", ErrInvalidBackup)
+		return
+	}
+
+	var p []byte
+	if p, err = b.read(int(n)); err != nil {
+		return
+	}
+
+	br.dr = new(data.Root)
+
+	if err = br.dr.Decode(p); err != nil {
+		return
+	}
+
+	if err = br.dr.Validate(); err != nil {
+		err = fmt.Errorf("%v: %v", ErrInvalidBackup, err)
+	}
+
+	return
+}
+
+func (b *backupReader) object() (
+	key cipher.SHA256,
+	rc uint32,
+	val []byte,
+	err error,
+) {
+
+	var p []byte
+	if p, err = b.read(len(key)); err != nil {
+		return
+	}
+	copy(key[:], p)
+
+	if rc, err = b.uint32(); err != nil {
+		return
+	}
+
+	var n uint32
+	if n, err = b.uint32(); err != nil {
+		return
+	}
+
+	if int(n) > b.max {
+		err = &ObjectIsTooLargeError{key}
+		return
+	}
+
+	// the value is copied, because the
+	// CXDS can keep the slice
+
+	val = make([]byte, n)
+
+	if _, err = io.ReadFull(b.r, val); err != nil {
+		err = fmt.Errorf("%v: unexpected end", ErrInvalidBackup)
+	}
+
+	return
+}
+
+func (b *backupReader) end() (err error) {
+
+	var sum = b.h.Sum(nil)
+
+	var p []byte
+	if p, err = b.read(len(sum)); err != nil {
+		return
+	}
+
+	if string(p) != string(sum) {
+		return fmt.Errorf("%v: wrong checksum", ErrInvalidBackup)
+	}
+
+	return
+}
+
+func appendUint32(p []byte, u uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], u)
+	return append(p, b[:]...)
+}
+
+func appendUint64(p []byte, u uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], u)
+	return append(p, b[:]...)
+}
@@ -0,0 +1,140 @@
+package skyobject
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func testRestoreConfig(t *testing.T) (conf *Config, clean func()) {
+	t.Helper()
+
+	var dir, err = ioutil.TempDir("", "cxo-restore")
+	assertNil(t, err)
+
+	conf = NewConfig()
+	conf.DataDir = dir
+
+	return conf, func() { os.RemoveAll(dir) }
+}
+
+func TestContainer_Backup(t *testing.T) {
+
+	var c = getTestContainer()
+	defer c.Close()
+
+	var (
+		pk1, r1 = saveTestRoots(t, c, 10)
+		pk2, _  = saveTestRoots(t, c, 5)
+
+		buf bytes.Buffer
+	)
+
+	t.Run("restore", func(t *testing.T) {
+
+		buf.Reset()
+		assertNil(t, c.Backup(&buf))
+
+		var conf, clean = testRestoreConfig(t)
+		defer clean()
+
+		assertNil(t, Restore(conf, &buf))
+
+		var rc, err = NewContainer(conf)
+		assertNil(t, err)
+		defer rc.Close()
+
+		assertTrue(t, rc.HasFeed(pk1) && rc.HasFeed(pk2), "missing feeds")
+
+		for _, pk := range []cipher.PubKey{pk1, pk2} {
+
+			var last, r *registry.Root
+
+			last, err = c.LastRoot(pk, r1.Nonce)
+			assertNil(t, err)
+
+			r, err = rc.Root(pk, r1.Nonce, last.Seq)
+			assertNil(t, err)
+
+			assertNil(t, rc.Walk(r, func(key cipher.SHA256, _ int) (bool, error) {
+				var val, _, err = c.Get(key, 0)
+				if err != nil {
+					return false, err
+				}
+				var rval, rrc, rerr = rc.Get(key, 0)
+				if rerr != nil {
+					return false, rerr
+				}
+				assertTrue(t, bytes.Equal(val, rval), "wrong value")
+				assertTrue(t, rrc > 0, "zero rc")
+				return true, nil
+			}))
+
+		}
+
+		// objects with zero rc are not restored
+		var all, used = rc.db.CXDS().Amount()
+
+		assertTrue(t, all > 0 && all == used, "wrong amount of objects")
+
+	})
+
+	t.Run("feeds", func(t *testing.T) {
+
+		buf.Reset()
+		assertNil(t, c.Backup(&buf, pk2))
+
+		var conf, clean = testRestoreConfig(t)
+		defer clean()
+
+		assertNil(t, Restore(conf, &buf))
+
+		var rc, err = NewContainer(conf)
+		assertNil(t, err)
+		defer rc.Close()
+
+		assertTrue(t, rc.HasFeed(pk1) == false, "unexpected feed")
+		assertTrue(t, rc.HasFeed(pk2) == true, "missing feed")
+
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+
+		buf.Reset()
+		assertNil(t, c.Backup(&buf))
+
+		var p = buf.Bytes()
+		p[len(p)/2]++
+
+		var conf, clean = testRestoreConfig(t)
+		defer clean()
+
+		assertTrue(t, Restore(conf, bytes.NewReader(p)) != nil,
+			"missing error")
+
+	})
+
+	t.Run("not empty", func(t *testing.T) {
+
+		buf.Reset()
+		assertNil(t, c.Backup(&buf))
+
+		var conf, clean = testRestoreConfig(t)
+		defer clean()
+
+		var p = buf.Bytes()
+
+		assertNil(t, Restore(conf, bytes.NewReader(p)))
+
+		if err := Restore(conf, bytes.NewReader(p)); err != ErrNotEmptyDB {
+			t.Error("wrong error:", err)
+		}
+
+	})
+
+}
@@ -0,0 +1,496 @@
+package skyobject
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// bundle related errors
+var (
+	ErrInvalidBundle = errors.New("invalid bundle")
+	ErrEmptyBundle   = errors.New("empty bundle")
+)
+
+// BundleVersion is version of the bundle format
+const BundleVersion uint32 = 1
+
+// bundle format
+//
+//	[ 8 magic ][ 4 version ][ 4 roots ]
+//	[ 33 pk ][ 65 sig ][ 4 len ][ encoded Root ] (roots times)
+//	[ 4 objects ]
+//	[ 32 key ][ 4 len ][ value ]                 (objects times)
+//
+// all numbers are big-endian; the objects are all objects
+// of the Root objects (including Registries) except the
+// Root objects itself; every object is stored once
+const bundleMagic = "CXOBUNDL"
+
+// a bundled Root
+type bundleRoot struct {
+	pk  cipher.PubKey
+	sig cipher.Sig
+	val []byte
+}
+
+// Export writes bundle of given Root objects with all
+// related objects and Registries to given io.Writer.
+// The Root objects must be full and saved in this
+// Container. Use the Import method to load the bundle
+// into another Container. The Export blocks removing
+// Root objects until it returns (see Backup). See also
+// ExportHead and ExportFeed methods
+func (c *Container) Export(
+	w io.Writer, //              : write to
+	roots ...*registry.Root, //  : Root objects to export
+) (
+	err error, //                : an error
+) {
+
+	c.Index.dmx.Lock()
+	defer c.Index.dmx.Unlock()
+
+	return c.export(w, roots)
+}
+
+// ExportHead exports all Root objects of given head
+// (see Export)
+func (c *Container) ExportHead(
+	w io.Writer,
+	pk cipher.PubKey,
+	nonce uint64,
+) (
+	err error,
+) {
+
+	c.Index.dmx.Lock()
+	defer c.Index.dmx.Unlock()
+
+	var roots []*registry.Root
+	if roots, err = c.exportRoots(pk, nonce); err != nil {
+		return
+	}
+
+	return c.export(w, roots)
+}
+
+// ExportFeed exports all Root objects of all heads
+// of given feed (see Export)
+func (c *Container) ExportFeed(w io.Writer, pk cipher.PubKey) (err error) {
+
+	c.Index.dmx.Lock()
+	defer c.Index.dmx.Unlock()
+
+	var heads []uint64
+	if heads, err = c.Heads(pk); err != nil {
+		return
+	}
+
+	var roots, hr []*registry.Root
+
+	for _, nonce := range heads {
+		if hr, err = c.exportRoots(pk, nonce); err != nil {
+			return
+		}
+		roots = append(roots, hr...)
+	}
+
+	return c.export(w, roots)
+}
+
+// Root objects of a head in ascending order
+func (c *Container) exportRoots(
+	pk cipher.PubKey,
+	nonce uint64,
+) (
+	roots []*registry.Root,
+	err error,
+) {
+
+	var seqs []uint64
+
+	err = c.db.IdxDB().Tx(func(fs data.Feeds) (err error) {
+
+		var hs data.Heads
+		if hs, err = fs.Heads(pk); err != nil {
+			return
+		}
+
+		var rs data.Roots
+		if rs, err = hs.Roots(nonce); err != nil {
+			return
+		}
+
+		return rs.Ascend(func(dr *data.Root) (_ error) {
+			seqs = append(seqs, dr.Seq)
+			return
+		})
+
+	})
+
+	if err != nil {
+		return
+	}
+
+	roots = make([]*registry.Root, 0, len(seqs))
+
+	for _, seq := range seqs {
+
+		var r *registry.Root
+		if r, err = c.Root(pk, nonce, seq); err != nil {
+			return
+		}
+
+		roots = append(roots, r)
+	}
+
+	return
+}
+
+func (c *Container) export(w io.Writer, roots []*registry.Root) (err error) {
+
+	if len(roots) == 0 {
+		return ErrEmptyBundle
+	}
+
+	var (
+		hashes = make([]cipher.SHA256, 0, len(roots))
+		isRoot = make(map[cipher.SHA256]struct{}, len(roots))
+	)
+
+	for _, r := range roots {
+
+		if r.IsFull == false {
+			return errors.New("can't export non-full Root: " + r.Short())
+		}
+
+		hashes = append(hashes, r.Hash)
+		isRoot[r.Hash] = struct{}{}
+	}
+
+	var keys []cipher.SHA256
+	if keys, _, err = c.walkRCs(hashes); err != nil {
+		return
+	}
+
+	var bw = newBackupWriter(w)
+
+	bw.buf = append(bw.buf[:0], bundleMagic...)
+	bw.buf = appendUint32(bw.buf, BundleVersion)
+	bw.buf = appendUint32(bw.buf, uint32(len(roots)))
+	bw.write(bw.buf)
+
+	for _, r := range roots {
+
+		var val []byte
+		if val, _, err = c.Get(r.Hash, 0); err != nil {
+			return
+		}
+
+		bw.buf = append(bw.buf[:0], r.Pub[:]...)
+		bw.buf = append(bw.buf, r.Sig[:]...)
+		bw.buf = appendUint32(bw.buf, uint32(len(val)))
+		bw.write(bw.buf)
+		bw.write(val)
+	}
+
+	bw.write(appendUint32(bw.buf[:0], uint32(len(keys)-len(isRoot))))
+
+	for _, key := range keys {
+
+		if _, ok := isRoot[key]; ok == true {
+			continue
+		}
+
+		var val []byte
+		if val, _, err = c.Get(key, 0); err != nil {
+			return
+		}
+
+		bw.buf = append(bw.buf[:0], key[:]...)
+		bw.buf = appendUint32(bw.buf, uint32(len(val)))
+		bw.write(bw.buf)
+		bw.write(val)
+
+		if bw.err != nil {
+			return bw.err
+		}
+
+	}
+
+	if bw.err != nil {
+		return bw.err
+	}
+
+	return bw.w.Flush()
+}
+
+// Import loads bundle created by the Export method. The
+// Import verifies signatures of Root objects and hashes
+// of all objects. Feeds of the Root objects are added to
+// the Container. Root objects the Container already has
+// are skipped. Objects the Container already has are not
+// duplicated, the Import increments their references
+// counters instead. The Import reads entire bundle to
+// memory. It returns imported Root objects (full)
+func (c *Container) Import(
+	r io.Reader, //              : read from
+) (
+	roots []*registry.Root, //   : imported Root objects
+	err error, //                : an error
+) {
+
+	var (
+		br   = newBackupReader(r, c.conf.MaxObjectSize)
+		brs  []bundleRoot
+		objs map[cipher.SHA256][]byte
+	)
+
+	br.invalid = ErrInvalidBundle
+
+	if brs, objs, err = br.bundle(); err != nil {
+		return
+	}
+
+	for _, b := range brs {
+
+		if err = c.AddFeed(b.pk); err != nil {
+			return
+		}
+
+		var rt *registry.Root
+		if rt, err = c.ReceivedRoot(b.pk, b.sig, b.val); err != nil {
+			return
+		}
+
+		if rt.Pub != b.pk {
+			err = fmt.Errorf("%v: wrong feed of Root %s", ErrInvalidBundle,
+				rt.Short())
+			return
+		}
+
+		if rt.IsFull == true {
+			continue // already have
+		}
+
+		objs[rt.Hash] = b.val
+
+		if err = c.importRoot(rt, objs); err != nil {
+			return
+		}
+
+		roots = append(roots, rt)
+
+	}
+
+	return
+}
+
+// an importPack obtains objects from
+// a bundle, or from the Container
+type importPack struct {
+	*Pack
+	objs map[cipher.SHA256][]byte
+}
+
+func (i *importPack) Get(key cipher.SHA256) (val []byte, err error) {
+	var ok bool
+	if val, ok = i.objs[key]; ok == true {
+		return
+	}
+	return i.Pack.Get(key)
+}
+
+func (c *Container) importRoot(
+	r *registry.Root,
+	objs map[cipher.SHA256][]byte,
+) (
+	err error,
+) {
+
+	var reg *registry.Registry
+
+	if val, ok := objs[cipher.SHA256(r.Reg)]; ok == true {
+		reg, err = registry.DecodeRegistry(val)
+	} else {
+		reg, err = c.Registry(r.Reg)
+	}
+
+	if err != nil {
+		return
+	}
+
+	var pack = &importPack{c.getPack(reg), objs}
+
+	// check out all objects first to be sure that
+	// the bundle (or the Container) has all of them
+
+	err = c.walkRoot(pack, r, func(
+		key cipher.SHA256,
+		_ int,
+	) (
+		deepper bool,
+		err error,
+	) {
+
+		var rc int
+		switch _, rc, err = c.Get(key, 0); err {
+		case nil:
+			return rc == 0, nil // the zero means removed children
+		case data.ErrNotFound:
+			if _, ok := objs[key]; ok == false {
+				return false, fmt.Errorf("%v: missing object %s of Root %s",
+					ErrInvalidBundle, key.Hex()[:7], r.Short())
+			}
+			return true, nil
+		}
+
+		return // DB failure
+	})
+
+	if err != nil {
+		return
+	}
+
+	// save; go deepper if object is new for the Container
+
+	err = c.walkRoot(pack, r, func(
+		key cipher.SHA256,
+		_ int,
+	) (
+		deepper bool,
+		err error,
+	) {
+
+		var rc int
+
+		if val, ok := objs[key]; ok == true {
+			rc, err = c.Set(key, val, 1)
+		} else {
+			rc, err = c.Inc(key, 1)
+		}
+
+		return rc == 1, err
+	})
+
+	if err != nil {
+		return
+	}
+
+	r.IsFull = true
+
+	_, err = c.AddRoot(r)
+	return
+}
+
+// read bundle
+func (b *backupReader) bundle() (
+	brs []bundleRoot,
+	objs map[cipher.SHA256][]byte,
+	err error,
+) {
+
+	var p []byte
+	if p, err = b.read(len(bundleMagic) + 8); err != nil {
+		return
+	}
+
+	if string(p[:len(bundleMagic)]) != bundleMagic {
+		err = fmt.Errorf("%v: wrong magic", ErrInvalidBundle)
+		return
+	}
+
+	p = p[len(bundleMagic):]
+
+	if vers := binary.BigEndian.Uint32(p); vers != BundleVersion {
+		err = fmt.Errorf("%v: unsupported version %d", ErrInvalidBundle, vers)
+		return
+	}
+
+	var n = binary.BigEndian.Uint32(p[4:])
+
+	if n == 0 {
+		err = ErrEmptyBundle
+		return
+	}
+
+	for i := uint32(0); i < n; i++ {
+
+		var br bundleRoot
+
+		if br.pk, err = b.pubKey(); err != nil {
+			return
+		}
+
+		if p, err = b.read(len(br.sig)); err != nil {
+			return
+		}
+		copy(br.sig[:], p)
+
+		if br.val, err = b.value(); err != nil {
+			return
+		}
+
+		brs = append(brs, br)
+	}
+
+	if n, err = b.uint32(); err != nil {
+		return
+	}
+
+	objs = make(map[cipher.SHA256][]byte)
+
+	for i := uint32(0); i < n; i++ {
+
+		var key cipher.SHA256
+
+		if p, err = b.read(len(key)); err != nil {
+			return
+		}
+		copy(key[:], p)
+
+		var val []byte
+		if val, err = b.value(); err != nil {
+			return
+		}
+
+		if cipher.SumSHA256(val) != key {
+			err = fmt.Errorf("%v: wrong hash of object %s", ErrInvalidBundle,
+				key.Hex()[:7])
+			return
+		}
+
+		objs[key] = val
+	}
+
+	return
+}
+
+// read length-prefixed value
+func (b *backupReader) value() (val []byte, err error) {
+
+	var n uint32
+	if n, err = b.uint32(); err != nil {
+		return
+	}
+
+	if int(n) > b.max {
+		err = ErrObjectIsTooLarge
+		return
+	}
+
+	val = make([]byte, n) // copy, because the CXDS can keep the slice
+
+	if _, err = io.ReadFull(b.r, val); err != nil {
+		err = fmt.Errorf("%v: unexpected end", b.invalid)
+	}
+
+	return
+}
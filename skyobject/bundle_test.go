@@ -0,0 +1,155 @@
+package skyobject
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestContainer_Import(t *testing.T) {
+
+	var c = getTestContainer()
+	defer c.Close()
+
+	var (
+		pk1, r1 = saveTestRoots(t, c, 10)
+		pk2, _  = saveTestRoots(t, c, 5)
+
+		buf bytes.Buffer
+	)
+
+	t.Run("feed", func(t *testing.T) {
+
+		var ic = getTestContainer()
+		defer ic.Close()
+
+		buf.Reset()
+		assertNil(t, c.ExportFeed(&buf, pk1))
+
+		var p = buf.Bytes()
+
+		var roots, err = ic.Import(bytes.NewReader(p))
+		assertNil(t, err)
+
+		assertTrue(t, len(roots) == 10, "wrong number of imported Root objects")
+
+		var last *registry.Root
+		last, err = ic.LastRoot(pk1, r1.Nonce)
+		assertNil(t, err)
+
+		assertTrue(t, last.Hash == r1.Hash, "wrong last Root")
+
+		// the same objects (the source has the same
+		// objects in another feed, thus rcs differ)
+
+		for _, r := range roots {
+
+			assertTrue(t, r.IsFull == true, "not full Root")
+
+			assertNil(t, ic.Walk(r, func(key cipher.SHA256, _ int) (bool, error) {
+				var val, _, err = c.Get(key, 0)
+				if err != nil {
+					return false, err
+				}
+				var ival, irc, ierr = ic.Get(key, 0)
+				if ierr != nil {
+					return false, ierr
+				}
+				assertTrue(t, bytes.Equal(val, ival), "wrong value")
+				assertTrue(t, irc > 0, "zero rc")
+				return true, nil
+			}))
+
+		}
+
+		var _, rc, _ = ic.Get(cipher.SHA256(r1.Reg), 0)
+		assertTrue(t, rc == 10, "wrong rc of Registry")
+
+		// import twice
+
+		roots, err = ic.Import(bytes.NewReader(p))
+		assertNil(t, err)
+
+		assertTrue(t, len(roots) == 0, "imported twice")
+
+	})
+
+	t.Run("root", func(t *testing.T) {
+
+		var ic = getTestContainer()
+		defer ic.Close()
+
+		var last, err = c.LastRoot(pk2, r1.Nonce)
+		assertNil(t, err)
+
+		buf.Reset()
+		assertNil(t, c.Export(&buf, last))
+
+		var roots []*registry.Root
+		roots, err = ic.Import(&buf)
+		assertNil(t, err)
+
+		assertTrue(t, len(roots) == 1, "wrong number of imported Root objects")
+		assertTrue(t, roots[0].Hash == last.Hash, "wrong Root")
+
+		// with shared objects (the Registry)
+
+		buf.Reset()
+		assertNil(t, c.ExportHead(&buf, pk1, r1.Nonce))
+
+		roots, err = ic.Import(&buf)
+		assertNil(t, err)
+
+		assertTrue(t, len(roots) == 10, "wrong number of imported Root objects")
+
+		var _, rc, _ = ic.Get(cipher.SHA256(r1.Reg), 0)
+		assertTrue(t, rc == 11, "wrong rc of Registry")
+
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+
+		buf.Reset()
+		assertNil(t, c.ExportHead(&buf, pk2, r1.Nonce))
+
+		var p = buf.Bytes()
+
+		// signature of first Root
+		var sig = append([]byte{}, p...)
+		sig[len(bundleMagic)+4+4+len(cipher.PubKey{})]++
+
+		// last object
+		var obj = append([]byte{}, p...)
+		obj[len(obj)-1]++
+
+		for _, b := range [][]byte{sig, obj, p[:len(p)/2]} {
+
+			var ic = getTestContainer()
+
+			if _, err := ic.Import(bytes.NewReader(b)); err == nil {
+				t.Error("missing error")
+			}
+
+			if _, err := ic.LastRoot(pk2, r1.Nonce); err == nil {
+				t.Error("unexpected Root")
+			}
+
+			ic.Close()
+		}
+
+	})
+
+	t.Run("empty", func(t *testing.T) {
+
+		buf.Reset()
+
+		if err := c.Export(&buf); err != ErrEmptyBundle {
+			t.Error("wrong error:", err)
+		}
+
+	})
+
+}
@@ -0,0 +1,330 @@
+package skyobject
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// errors of confidential feeds
+var (
+	ErrNotConfidential = errors.New("not a confidential Root")
+	ErrNotReader       = errors.New("not a reader of the confidential Root")
+)
+
+// Confidential feeds
+//
+// Objects of a confidential Root are sealed using
+// AES-256-GCM with a ContentKey. The ContentKey is
+// distributed to readers inside the Descriptor of
+// the Root, sealed for every reader using ECDH of
+// public key of the reader and an ephemeral key.
+// Nodes that are not readers of the Root store and
+// replicate sealed objects, but can't read them.
+//
+// Only objects without references are sealed, because
+// nodes walk Root objects to replicate them (see
+// registry.Sealer). Thus, nodes of Refs, and objects
+// with Ref, Refs or Dynamic fields are not sealed.
+// Keep secret data in objects without references.
+//
+// sealed object
+//
+//     magic    [8]byte
+//     nonce    [12]byte
+//     sealed   []byte    (the magic is additional data)
+//
+// descriptor of a confidential Root
+//
+//     magic    [8]byte
+//     encoded  confidentialDescriptor
+//
+const (
+	sealedMagic       = "CXOSEAL1"
+	confidentialMagic = "CXOCONF1"
+)
+
+// A ContentKey is symmetric key that used to seal
+// objects of a confidential Root
+type ContentKey [32]byte
+
+// NewContentKey generates random ContentKey
+func NewContentKey() (key ContentKey) {
+	if _, err := rand.Read(key[:]); err != nil {
+		panic(err)
+	}
+	return
+}
+
+// key of a reader
+type readerKey struct {
+	Reader cipher.PubKey // public key of the reader
+	Sealed []byte        // sealed ContentKey (nonce + sealed)
+}
+
+type confidentialDescriptor struct {
+	Ephemeral  cipher.PubKey // ephemeral public key
+	Readers    []readerKey   // the ContentKey sealed for readers
+	Descriptor []byte        // descriptor of end-user
+}
+
+func newContentAEAD(key []byte) (aead gocipher.AEAD, err error) {
+
+	var block gocipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+
+	return gocipher.NewGCM(block)
+}
+
+// seal given value using random nonce,
+// the result is nonce + sealed value
+func sealValue(aead gocipher.AEAD, val, ad []byte) (sealed []byte, err error) {
+
+	var nonce = make([]byte, aead.NonceSize(),
+		aead.NonceSize()+len(val)+aead.Overhead())
+
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+
+	return aead.Seal(nonce, nonce, val, ad), nil
+}
+
+// open value sealed by the sealValue
+func openValue(aead gocipher.AEAD, sealed, ad []byte) (val []byte, err error) {
+
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.New("sealed value is too short")
+	}
+
+	var ns = aead.NonceSize()
+	return aead.Open(nil, sealed[:ns], sealed[ns:], ad)
+}
+
+// key to seal ContentKey for given reader
+func readerAEAD(
+	pk cipher.PubKey,
+	sk cipher.SecKey,
+) (
+	aead gocipher.AEAD,
+	err error,
+) {
+
+	var shared = cipher.SumSHA256(cipher.ECDH(pk, sk))
+	return newContentAEAD(shared[:])
+}
+
+// IsConfidential returns true if given
+// descriptor is descriptor of confidential
+// Root (see SealContentKey)
+func IsConfidential(descriptor []byte) bool {
+	return bytes.HasPrefix(descriptor, []byte(confidentialMagic))
+}
+
+// SealContentKey seals given ContentKey for every of given
+// readers. The result should be used as Descriptor of the
+// Root, objects of which sealed by the ContentKey. Given
+// descriptor is descriptor of end-user and can be obtained
+// using the OpenContentKey
+func SealContentKey(
+	key ContentKey, //            : the key
+	readers []cipher.PubKey, //   : public keys of readers
+	descriptor []byte, //         : descriptor of end-user
+) (
+	sealed []byte, //             : descriptor of the Root
+	err error, //                 : an error
+) {
+
+	var (
+		pk, sk = cipher.GenerateKeyPair() // ephemeral
+
+		cd = confidentialDescriptor{
+			Ephemeral:  pk,
+			Readers:    make([]readerKey, 0, len(readers)),
+			Descriptor: descriptor,
+		}
+	)
+
+	for _, reader := range readers {
+
+		if err = reader.Verify(); err != nil {
+			return
+		}
+
+		var aead gocipher.AEAD
+		if aead, err = readerAEAD(reader, sk); err != nil {
+			return
+		}
+
+		var rk = readerKey{Reader: reader}
+		if rk.Sealed, err = sealValue(aead, key[:], pk[:]); err != nil {
+			return
+		}
+
+		cd.Readers = append(cd.Readers, rk)
+	}
+
+	sealed = append([]byte(confidentialMagic), encoder.Serialize(cd)...)
+	return
+}
+
+// OpenContentKey returns ContentKey sealed by the
+// SealContentKey using secret key of a reader. It
+// returns ErrNotConfidential if given descriptor
+// is not descriptor of a confidential Root, and
+// ErrNotReader if the ContentKey is not sealed for
+// public key of given secret key. The descriptor
+// is descriptor of end-user
+func OpenContentKey(
+	sealed []byte, //     : descriptor of the Root
+	sk cipher.SecKey, //  : secret key of a reader
+) (
+	key ContentKey, //    : the key
+	descriptor []byte, // : descriptor of end-user
+	err error, //         : an error
+) {
+
+	if IsConfidential(sealed) == false {
+		err = ErrNotConfidential
+		return
+	}
+
+	var cd confidentialDescriptor
+	err = encoder.DeserializeRaw(sealed[len(confidentialMagic):], &cd)
+	if err != nil {
+		return
+	}
+
+	var pk = cipher.PubKeyFromSecKey(sk)
+
+	for _, rk := range cd.Readers {
+
+		if rk.Reader != pk {
+			continue
+		}
+
+		var aead gocipher.AEAD
+		if aead, err = readerAEAD(cd.Ephemeral, sk); err != nil {
+			return
+		}
+
+		var val []byte
+		if val, err = openValue(aead, rk.Sealed, cd.Ephemeral[:]); err != nil {
+			return
+		}
+
+		if len(val) != len(key) {
+			err = errors.New("invalid length of sealed ContentKey")
+			return
+		}
+
+		copy(key[:], val)
+		return key, cd.Descriptor, nil
+	}
+
+	err = ErrNotReader
+	return
+}
+
+// SetKey sets ContentKey to the Pack. The Pack opens sealed
+// objects using the key. The Unpack seals new objects without
+// references using the key. Use nil to turn it off
+func (p *Pack) SetKey(key *ContentKey) (err error) {
+
+	if key == nil {
+		p.aead = nil
+		return
+	}
+
+	p.aead, err = newContentAEAD(key[:])
+	return
+}
+
+// seal value if the Pack has a key
+func (p *Pack) seal(val []byte) (sealed []byte, err error) {
+
+	if p.aead == nil {
+		return val, nil
+	}
+
+	var ad = []byte(sealedMagic)
+
+	if sealed, err = sealValue(p.aead, val, ad); err != nil {
+		return
+	}
+
+	return append(ad, sealed...), nil
+}
+
+// open sealed value if the Pack has a key; a value
+// that can't be opened is returned as is, since it
+// can be an object that is not sealed
+func (p *Pack) open(val []byte) []byte {
+
+	if p.aead == nil || bytes.HasPrefix(val, []byte(sealedMagic)) == false {
+		return val
+	}
+
+	var ad = []byte(sealedMagic)
+
+	if opened, err := openValue(p.aead, val[len(ad):], ad); err == nil {
+		return opened
+	}
+
+	return val
+}
+
+// Seal implements registry.Sealer interface
+func (p *Pack) Seal(val []byte) (key cipher.SHA256, err error) {
+
+	if val, err = p.seal(val); err != nil {
+		return
+	}
+
+	return p.Add(val)
+}
+
+// Seal implements registry.Sealer interface
+func (u *Unpack) Seal(val []byte) (key cipher.SHA256, err error) {
+
+	if val, err = u.seal(val); err != nil {
+		return
+	}
+
+	return u.Add(val) // use Add of the Unpack
+}
+
+// ConfidentialPack returns Pack of given confidential Root
+// that opens sealed objects of the Root using ContentKey
+// sealed for given reader (see OpenContentKey). If given
+// Registry is nil, then it's obtained from DB
+func (c *Container) ConfidentialPack(
+	r *registry.Root, //       : the Root
+	reg *registry.Registry, // : registry or nil
+	sk cipher.SecKey, //       : secret key of a reader
+) (
+	p *Pack, //                : the Pack
+	err error, //              : an error
+) {
+
+	var key ContentKey
+	if key, _, err = OpenContentKey(r.Descriptor, sk); err != nil {
+		return
+	}
+
+	if p, err = c.Pack(r, reg); err != nil {
+		return
+	}
+
+	err = p.SetKey(&key)
+	return
+}
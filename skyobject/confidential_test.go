@@ -0,0 +1,154 @@
+package skyobject
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestSealContentKey(t *testing.T) {
+
+	var (
+		key = NewContentKey()
+
+		apk, ask = cipher.GenerateKeyPair()
+		bpk, bsk = cipher.GenerateKeyPair()
+		_, osk   = cipher.GenerateKeyPair()
+
+		desc = []byte("end-user descriptor")
+	)
+
+	var sealed, err = SealContentKey(key, []cipher.PubKey{apk, bpk}, desc)
+	assertNil(t, err)
+
+	assertTrue(t, IsConfidential(sealed) == true, "not confidential")
+	assertTrue(t, IsConfidential(desc) == false, "confidential")
+
+	for _, sk := range []cipher.SecKey{ask, bsk} {
+
+		var (
+			opened ContentKey
+			od     []byte
+		)
+
+		opened, od, err = OpenContentKey(sealed, sk)
+		assertNil(t, err)
+
+		assertTrue(t, opened == key, "wrong key")
+		assertTrue(t, bytes.Equal(od, desc), "wrong descriptor")
+	}
+
+	if _, _, err = OpenContentKey(sealed, osk); err != ErrNotReader {
+		t.Error("unexpected error", err)
+	}
+
+	if _, _, err = OpenContentKey(desc, ask); err != ErrNotConfidential {
+		t.Error("unexpected error", err)
+	}
+
+}
+
+func TestContainer_ConfidentialPack(t *testing.T) {
+
+	var (
+		sc, rc = getTestContainer(), getTestContainer() // source and relay
+
+		pk, sk   = cipher.GenerateKeyPair() // feed
+		rpk, rsk = cipher.GenerateKeyPair() // reader
+
+		key = NewContentKey()
+	)
+
+	defer sc.Close()
+	defer rc.Close()
+
+	assertNil(t, sc.AddFeed(pk))
+	assertNil(t, rc.AddFeed(pk))
+
+	var up, err = sc.Unpack(sk, testRegistry)
+	assertNil(t, err)
+	defer up.Close()
+
+	assertNil(t, up.SetKey(&key))
+
+	var r = new(registry.Root)
+
+	r.Pub = pk
+	r.Nonce = 9021
+
+	r.Descriptor, err = SealContentKey(key, []cipher.PubKey{rpk}, nil)
+	assertNil(t, err)
+
+	var (
+		usr  = User{Name: "Alice", Age: 19}
+		feed = Feed{Head: "Alices' feed", Info: "an average feed"}
+		post = Post{Head: "Head", Body: "secret"}
+	)
+
+	assertNil(t, feed.Posts.AppendValues(up, post))
+
+	r.Refs = []registry.Dynamic{
+		createDynamic(up, testRegistry, "test.User", &usr),
+		createDynamic(up, testRegistry, "test.Feed", &feed),
+	}
+
+	assertNil(t, sc.Save(up, r))
+
+	// sealed in DB
+
+	var val []byte
+	val, _, err = sc.Get(r.Refs[0].Hash, 0)
+	assertNil(t, err)
+
+	assertTrue(t, bytes.Contains(val, []byte("Alice")) == false, "not sealed")
+
+	// not sealed (has references)
+
+	val, _, err = sc.Get(r.Refs[1].Hash, 0)
+	assertNil(t, err)
+
+	assertTrue(t, bytes.Contains(val, []byte("Alices' feed")) == true,
+		"sealed")
+
+	// the relay fills the Root without the key
+
+	testFillRoot(t, sc, rc, r)
+	testFillDBs(t, sc, rc)
+
+	// the reader
+
+	var pack *Pack
+	pack, err = rc.ConfidentialPack(r, nil, rsk)
+	assertNil(t, err)
+
+	var (
+		gu User
+		gf Feed
+		gp Post
+	)
+
+	assertNil(t, r.Refs[0].Value(pack, &gu))
+	assertTrue(t, gu == usr, "wrong User")
+
+	assertNil(t, r.Refs[1].Value(pack, &gf))
+	_, err = gf.Posts.ValueByIndex(pack, 0, &gp)
+	assertNil(t, err)
+	assertTrue(t, gp == post, "wrong Post")
+
+	// not a reader
+
+	if _, err = rc.ConfidentialPack(r, nil, sk); err != ErrNotReader {
+		t.Error("unexpected error", err)
+	}
+
+	pack, err = rc.Pack(r, nil)
+	assertNil(t, err)
+
+	if err = r.Refs[0].Value(pack, &gu); err == nil {
+		t.Error("missing error")
+	}
+
+}
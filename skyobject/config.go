@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/skycoin/cxo/data"
 	"github.com/skycoin/cxo/node/log"
@@ -35,9 +37,16 @@ const (
 
 	MaxFillingParallel int = 10 // ten parallel subtrees
 
+	// garbage collector
+
+	GCInterval time.Duration = 0                      // turned off
+	GCBudget   time.Duration = 100 * time.Millisecond // 100ms per run
+
 	// DB related constants
-	CXDS  string = "cxds.db" // default CXDS file name
-	IdxDB string = "idx.db"  // default IdxDB file name
+	CXDS     string = "cxds.db" // default CXDS file name
+	IdxDB    string = "idx.db"  // default IdxDB file name
+	DBDriver string = "bolt"    // default DB driver
+	Keys     string = "keys.db" // default KeyStore file name
 
 	PackSavePin       log.Pin = 1 << iota // show time of (*Pack).Save in logs
 	CleanUpVerbosePin                     // show collecting and removing times
@@ -168,6 +177,48 @@ type Config struct {
 	// to number of connections that used to fill a Root.
 	MaxFillingParallel int
 
+	// garbage collector
+
+	// GCInterval is interval between runs of the garbage
+	// collector. Set it to zero to turn the collector off.
+	// In this case, it's possible to call the RunGC method
+	// manually. A run of the collector takes GCBudget and
+	// yields. E.g. a cycle of the collector can take many
+	// runs. By default the collector is turned off
+	GCInterval time.Duration
+	// GCBudget is max working time of a run. The collector
+	// checks the budget between removals. Thus, a run can
+	// take a bit more time. Looking for objects with zero
+	// rc (mark) is not interruptable. The GCBudget should
+	// be greater then zero if the GCInterval is not zero
+	GCBudget time.Duration
+	// GCKeepLast is number of last Root objects of a head
+	// the collector keeps. Zero means that the GCKeepLast
+	// policy is not used. Last Root of a head never
+	// removed by the collector. See also GCKeepFor
+	GCKeepLast int
+	// GCKeepFor is duration the collector keeps Root objects.
+	// A Root older then the GCKeepFor can be removed. Zero
+	// means that the GCKeepFor policy is not used. If both
+	// GCKeepLast and GCKeepFor are set, then a Root removed
+	// if it's not one of last GCKeepLast Root objects and
+	// it's older then the GCKeepFor. If both are zero, then
+	// the collector removes only objects with zero rc
+	GCKeepFor time.Duration
+	// GCMaxVolume is volume ceiling of used objects of the
+	// CXDS. If used volume exceeds this value, then the
+	// collector removes oldest Root objects (except last
+	// Root of every head) ignoring GCKeepLast and GCKeepFor,
+	// until the volume is less or there are Root objects
+	// that can be removed. Zero means no ceiling
+	GCMaxVolume int
+	// Retention is Retention policies of feeds. The
+	// GCKeepLast and GCKeepFor are used for feeds
+	// not listed here. The policies are used by the
+	// collector and by the Retain method of the
+	// Container. See also SetRetention method
+	Retention Retentions
+
 	// DB configs
 
 	// CheckSizes force Container to check sizes of objects
@@ -192,6 +243,24 @@ type Config struct {
 	// be sure that path created. The DBPath used for tests
 	// and examples. But it can be used for other
 	DBPath string
+	// DBDriver is name of registered driver of CXDS and
+	// IdxDB (see data.RegisterCXDS and data.RegisterIdxDB).
+	// Builtin drivers are "bolt" (default), "lsm" (CXDS
+	// only) and "memory". Third-party drivers should be
+	// registered before NewContainer called. For the
+	// "bolt" driver paths of the CXDS and the IdxDB are
+	// described above. For other drivers the paths are
+	// "cxds.<driver>" and "idx.<driver>" under the DataDir,
+	// or the DBPath with ".cxds.<driver>" and ".idx.<driver>"
+	// extensions. The field ignored if the InMemoryDB is
+	// true or the DB is provided
+	DBDriver string
+	// CXDSDriver is name of driver of the CXDS, if it's
+	// not the same as the DBDriver. For example, the "lsm"
+	// driver is CXDS only. The LSM-tree based CXDS is better
+	// for write-heavy workloads and large volumes. An
+	// existing CXDS can't be opened using another driver
+	CXDSDriver string
 	// DataDir will be created if it's not empty. If DB field
 	// of the config is nil, InMemoryDB is false and DBPath
 	// is empty, then database will be created under the
@@ -221,6 +290,13 @@ func NewConfig() (conf *Config) {
 
 	conf.MaxObjectSize = MaxObjectSize
 
+	conf.DBDriver = DBDriver
+
+	// garbage collector
+
+	conf.GCInterval = GCInterval
+	conf.GCBudget = GCBudget
+
 	// data dir
 	conf.DataDir = DataDir()
 
@@ -242,6 +318,41 @@ func (c *Config) FromFlags() {
 		"db-path",
 		c.DBPath,
 		"path to database")
+	flag.StringVar(&c.DBDriver,
+		"db-driver",
+		c.DBDriver,
+		"name of DB driver: bolt or memory")
+	flag.StringVar(&c.CXDSDriver,
+		"cxds-driver",
+		c.CXDSDriver,
+		"name of CXDS driver if it's not the same: bolt, lsm or memory")
+
+	// garbage collector
+
+	flag.DurationVar(&c.GCInterval,
+		"gc-interval",
+		c.GCInterval,
+		"interval between runs of garbage collector (0 - turned off)")
+	flag.DurationVar(&c.GCBudget,
+		"gc-budget",
+		c.GCBudget,
+		"max working time of a run of garbage collector")
+	flag.IntVar(&c.GCKeepLast,
+		"gc-keep-last",
+		c.GCKeepLast,
+		"keep last n Root objects of a head (0 - keep all)")
+	flag.DurationVar(&c.GCKeepFor,
+		"gc-keep-for",
+		c.GCKeepFor,
+		"keep Root objects newer then this duration (0 - keep all)")
+	flag.IntVar(&c.GCMaxVolume,
+		"gc-max-volume",
+		c.GCMaxVolume,
+		"volume ceiling of used objects in bytes (0 - no ceiling)")
+	flag.Var(&c.Retention,
+		"retention",
+		"retention policy of a feed: feed:all|last=n,for=duration,full"+
+			" (can be repeated)")
 }
 
 // Validate the Config
@@ -297,5 +408,113 @@ func (c *Config) Validate() error {
 			c.MaxObjectSize)
 	}
 
+	if c.DB == nil && c.InMemoryDB == false {
+
+		var cxName, _ = c.cxds()
+		var idxName, _ = c.idxdb()
+
+		if hasDriver(data.CXDSDrivers(), cxName) == false {
+			return fmt.Errorf(
+				"skyobject.Config: unknown CXDS driver %q (registered: %s)",
+				cxName, strings.Join(data.CXDSDrivers(), ", "))
+		}
+
+		if hasDriver(data.IdxDBDrivers(), idxName) == false {
+			return fmt.Errorf(
+				"skyobject.Config: unknown IdxDB driver %q (registered: %s)",
+				idxName, strings.Join(data.IdxDBDrivers(), ", "))
+		}
+
+	}
+
+	if c.GCInterval < 0 {
+		return fmt.Errorf("skyobject.Config.GCInterval is negative: %s",
+			c.GCInterval)
+	}
+
+	if c.GCInterval > 0 && c.GCBudget <= 0 {
+		return fmt.Errorf("skyobject.Config.GCBudget is not positive: %s",
+			c.GCBudget)
+	}
+
+	if c.GCKeepLast < 0 {
+		return fmt.Errorf("skyobject.Config.GCKeepLast is negative: %d",
+			c.GCKeepLast)
+	}
+
+	if c.GCKeepFor < 0 {
+		return fmt.Errorf("skyobject.Config.GCKeepFor is negative: %s",
+			c.GCKeepFor)
+	}
+
+	if c.GCMaxVolume < 0 {
+		return fmt.Errorf("skyobject.Config.GCMaxVolume is negative: %d",
+			c.GCMaxVolume)
+	}
+
+	for pk, rt := range c.Retention {
+		if err := rt.Validate(); err != nil {
+			return fmt.Errorf("skyobject.Config.Retention of %s: %v",
+				pk.Hex()[:7], err)
+		}
+	}
+
 	return nil
 }
+
+func hasDriver(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// name of the DB driver
+func (c *Config) dbDriver() string {
+	if c.DBDriver == "" {
+		return DBDriver
+	}
+	return c.DBDriver
+}
+
+// driver name and path of the CXDS
+func (c *Config) cxds() (driver, path string) {
+
+	if driver = c.CXDSDriver; driver == "" {
+		driver = c.dbDriver()
+	}
+
+	switch {
+	case c.DBPath != "" && driver == DBDriver:
+		path = c.DBPath + ".cxds"
+	case c.DBPath != "":
+		path = c.DBPath + ".cxds." + driver
+	case driver == DBDriver:
+		path = filepath.Join(c.DataDir, CXDS)
+	default:
+		path = filepath.Join(c.DataDir, "cxds."+driver)
+	}
+
+	return
+}
+
+// driver name and path of the IdxDB
+func (c *Config) idxdb() (driver, path string) {
+
+	driver = c.dbDriver()
+
+	switch {
+	case c.DBPath != "" && driver == DBDriver:
+		path = c.DBPath + ".idx"
+	case c.DBPath != "":
+		path = c.DBPath + ".idx." + driver
+	case driver == DBDriver:
+		path = filepath.Join(c.DataDir, IdxDB)
+	default:
+		path = filepath.Join(c.DataDir, "idx."+driver)
+	}
+
+	return
+}
@@ -2,7 +2,6 @@ package skyobject
 
 import (
 	"log"
-	"path/filepath"
 
 	"github.com/skycoin/skycoin/src/cipher"
 
@@ -21,7 +20,11 @@ type Container struct {
 	Cache // cache of the Container
 	Index // memory mapped IdxDB
 
-	db *data.DB // database
+	db   *data.DB  // database
+	gc   gc        // garbage collector
+	keys *KeyStore // secret keys of owned feeds
+
+	retentions retentions // Retention policies of feeds
 
 	conf *Config // configurations
 
@@ -67,6 +70,9 @@ func NewContainer(conf *Config) (c *Container, err error) {
 		}
 	}()
 
+	c.keys = newKeyStore(conf.keyStore())
+	c.retentions.init(conf.Retention)
+
 	// check size of objects
 	if err = c.checkSize(); err != nil {
 		return
@@ -79,6 +85,9 @@ func NewContainer(conf *Config) (c *Container, err error) {
 		return
 	}
 
+	// start garbage collector
+	c.initGC()
+
 	return // done
 }
 
@@ -104,22 +113,19 @@ func (c *Container) createDB(conf *Config) (err error) {
 
 	} else {
 
-		if conf.DBPath == "" {
-			c.cxPath = filepath.Join(conf.DataDir, CXDS)
-			c.idxPath = filepath.Join(conf.DataDir, IdxDB)
-		} else {
-			c.cxPath = conf.DBPath + ".cxds"
-			c.idxPath = conf.DBPath + ".idx"
-		}
+		var cxName, idxName string
+
+		cxName, c.cxPath = conf.cxds()
+		idxName, c.idxPath = conf.idxdb()
 
 		var cx data.CXDS
 		var idx data.IdxDB
 
-		if cx, err = cxds.NewDriveCXDS(c.cxPath); err != nil {
+		if cx, err = data.OpenCXDS(cxName, c.cxPath); err != nil {
 			return
 		}
 
-		if idx, err = idxdb.NewDriveIdxDB(c.idxPath); err != nil {
+		if idx, err = data.OpenIdxDB(idxName, c.idxPath); err != nil {
 			cx.Close()
 			return
 		}
@@ -133,6 +139,29 @@ func (c *Container) createDB(conf *Config) (err error) {
 	return
 }
 
+// OpenDB opens databases using given Config without a
+// Container (without cache, index and garbage collector).
+// It used by tools that work with DB of a stopped node
+// (see cxoutils.Fsck). If the Config is nil, then default
+// config is used
+func OpenDB(conf *Config) (db *data.DB, err error) {
+
+	if conf == nil {
+		conf = NewConfig() // default
+	}
+
+	if err = conf.Validate(); err != nil {
+		return
+	}
+
+	var c Container
+	if err = c.createDB(conf); err != nil {
+		return
+	}
+
+	return c.db, nil
+}
+
 type rcs struct {
 	rc uint32 // saved rc (DB)
 	cc uint32 // correct rc (determined by walking)
@@ -354,6 +383,9 @@ func (c *Container) RootByHash(
 // with user-provided DB.
 func (c *Container) Close() (err error) {
 
+	// stop the garbage collector first
+	c.gc.close()
+
 	// the Cache.Close closes CXDS
 	if err = c.Cache.Close(); err == nil {
 		err = c.db.Close()
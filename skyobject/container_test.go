@@ -0,0 +1,101 @@
+package skyobject
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/data/cxds"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestNewContainer_lsm(t *testing.T) {
+
+	var dir, err = ioutil.TempDir("", "cxo-lsm")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	var conf = NewConfig()
+	conf.DataDir = dir
+	conf.CXDSDriver = cxds.LSMDriver
+
+	var c *Container
+	if c, err = NewContainer(conf); err != nil {
+		t.Fatal(err)
+	}
+
+	var _, r = saveTestRoots(t, c, 8)
+	assertNil(t, c.Close())
+
+	// reopen
+
+	if c, err = NewContainer(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var got *registry.Root
+	if got, err = c.RootByHash(r.Hash); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTrue(t, got.Seq == r.Seq, "wrong Root")
+	assertNil(t, c.Walk(got, func(cipher.SHA256, int) (bool, error) {
+		return true, nil
+	}))
+
+}
+
+func TestNewContainer_driver(t *testing.T) {
+
+	var dir, err = ioutil.TempDir("", "cxo-driver")
+	assertNil(t, err)
+	defer os.RemoveAll(dir)
+
+	// third-party driver
+
+	var opened string
+
+	data.RegisterCXDS("test", func(path string) (data.CXDS, error) {
+		opened = path
+		return cxds.NewMemoryCXDS(), nil
+	})
+
+	var conf = NewConfig()
+	conf.DataDir = dir
+	conf.CXDSDriver = "test"
+
+	var c *Container
+	if c, err = NewContainer(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	assertTrue(t, opened == filepath.Join(dir, "cxds.test"), "wrong path")
+
+}
+
+func TestConfig_Validate_driver(t *testing.T) {
+
+	var conf = NewConfig()
+
+	for _, driver := range []string{"", DBDriver, cxds.MemoryDriver} {
+		conf.DBDriver = driver
+		assertNil(t, conf.Validate())
+	}
+
+	conf.DBDriver = cxds.LSMDriver // CXDS only
+	assertTrue(t, conf.Validate() != nil, "missing error")
+
+	conf.DBDriver = DBDriver
+	conf.CXDSDriver = cxds.LSMDriver
+	assertNil(t, conf.Validate())
+
+	conf.CXDSDriver = "leveldb"
+	assertTrue(t, conf.Validate() != nil, "missing error")
+
+}
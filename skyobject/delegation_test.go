@@ -0,0 +1,106 @@
+package skyobject
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// save Root and pass it to the rc
+func testReceiveRoot(
+	t *testing.T,
+	sc, rc *Container,
+	up *Unpack,
+	r *registry.Root,
+) error {
+
+	t.Helper()
+
+	assertNil(t, sc.Save(up, r))
+
+	var _, err = rc.ReceivedRoot(r.Pub, r.Sig, r.Encode())
+	return err
+}
+
+func TestContainer_delegation(t *testing.T) {
+
+	var (
+		sc, rc   = getTestContainer(), getTestContainer()
+		pk, sk   = cipher.GenerateKeyPair()
+		dpk, dsk = cipher.GenerateKeyPair()
+	)
+
+	defer sc.Close()
+	defer rc.Close()
+
+	assertNil(t, sc.AddFeed(pk))
+	assertNil(t, rc.AddFeed(pk))
+
+	var up, err = sc.Unpack(sk, testRegistry)
+	assertNil(t, err)
+
+	var dup *Unpack
+	dup, err = sc.Unpack(dsk, testRegistry)
+	assertNil(t, err)
+
+	var r = &registry.Root{Pub: pk, Nonce: 1}
+
+	// not delegated yet
+
+	if err = sc.Save(dup, r); err == nil {
+		t.Error("missing error")
+	}
+
+	// delegate
+
+	var d = &registry.DelegationSet{
+		Feed:        pk,
+		Version:     1,
+		Delegations: []registry.Delegation{{Delegate: dpk, Nonce: 1}},
+	}
+	d.Sign(sk)
+
+	r.SetDelegationSet(d)
+
+	assertNil(t, testReceiveRoot(t, sc, rc, up, r))
+	assertNil(t, testReceiveRoot(t, sc, rc, dup, r))
+
+	// another head
+
+	var or = &registry.Root{Pub: pk, Nonce: 2}
+	or.SetDelegationSet(d)
+
+	if err = sc.Save(dup, or); err != registry.ErrNotDelegated {
+		t.Error("unexpected error", err)
+	}
+
+	// revoke
+
+	var revoked = *r // with old DelegationSet
+
+	d.Version, d.Delegations = 2, nil
+	d.Sign(sk)
+
+	r.SetDelegationSet(d)
+
+	assertNil(t, testReceiveRoot(t, sc, rc, up, r))
+
+	if err = sc.Save(dup, &revoked); err != ErrRevokedDelegation {
+		t.Error("unexpected error", err)
+	}
+
+	// the rc rejects Root signed before
+	// using the revoked DelegationSet
+
+	revoked.Seq, revoked.Prev = r.Seq+1, r.Hash
+	revoked.Hash = cipher.SumSHA256(revoked.Encode())
+	revoked.Sig = cipher.SignHash(revoked.Hash, dsk)
+
+	_, err = rc.ReceivedRoot(pk, revoked.Sig, revoked.Encode())
+	if err != ErrRevokedDelegation {
+		t.Error("unexpected error", err)
+	}
+
+}
@@ -0,0 +1,79 @@
+package skyobject
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// Diff returns hashes of objects of the next Root,
+// that the prev Root doesn't have. E.g. objects
+// added by the next Root. Subtrees the Root objects
+// share are not walked. The keys are in walking order
+// of the next Root, without repeats. The next Root
+// itself is not included. Registry of the next Root
+// is included if it's not the same as Registry of the
+// prev. Both Root objects must be full. The prev can be
+// nil, in this case the Diff returns all objects of the
+// next Root.
+//
+// The Diff used to send delta of a Root to peers that
+// have previous Root of the same head. But it can be
+// used for any two Root objects (of any feeds and
+// heads)
+func (c *Container) Diff(
+	prev *registry.Root, // : previous Root or nil
+	next *registry.Root, // : next Root
+) (
+	keys []cipher.SHA256, // : new objects
+	err error, //            : an error
+) {
+
+	var have = make(map[cipher.SHA256]struct{})
+
+	if prev != nil {
+
+		err = c.Walk(prev, func(
+			key cipher.SHA256,
+			_ int,
+		) (
+			deepper bool,
+			_ error,
+		) {
+
+			if _, ok := have[key]; ok == true {
+				return // already walked
+			}
+
+			have[key] = struct{}{}
+			return true, nil
+		})
+
+		if err != nil {
+			return
+		}
+
+	}
+
+	have[next.Hash] = struct{}{} // skip the next Root itself
+
+	err = c.Walk(next, func(
+		key cipher.SHA256,
+		_ int,
+	) (
+		deepper bool,
+		_ error,
+	) {
+
+		if _, ok := have[key]; ok == true {
+			return // the prev has it, or already walked
+		}
+
+		have[key] = struct{}{}
+		keys = append(keys, key)
+
+		return true, nil
+	})
+
+	return
+}
@@ -0,0 +1,118 @@
+package skyobject
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// all objects of given Root
+func walkKeys(
+	t *testing.T,
+	c *Container,
+	r *registry.Root,
+) (
+	keys map[cipher.SHA256]struct{},
+) {
+
+	t.Helper()
+
+	keys = make(map[cipher.SHA256]struct{})
+
+	assertNil(t, c.Walk(r, func(key cipher.SHA256, _ int) (bool, error) {
+		keys[key] = struct{}{}
+		return true, nil
+	}))
+
+	return
+}
+
+func TestContainer_Diff(t *testing.T) {
+
+	var c = getTestContainer()
+	defer c.Close()
+
+	var pk, next = saveTestRoots(t, c, 64)
+
+	var prev, err = c.Root(pk, next.Nonce, next.Seq-1)
+	assertNil(t, err)
+
+	var (
+		pks = walkKeys(t, c, prev)
+		nks = walkKeys(t, c, next)
+
+		keys []cipher.SHA256
+	)
+
+	t.Run("delta", func(t *testing.T) {
+
+		if keys, err = c.Diff(prev, next); err != nil {
+			t.Fatal(err)
+		}
+
+		var got = make(map[cipher.SHA256]struct{})
+
+		for _, key := range keys {
+
+			if _, ok := got[key]; ok == true {
+				t.Fatal("repeated key", key.Hex()[:7])
+			}
+
+			got[key] = struct{}{}
+
+			if _, ok := pks[key]; ok == true {
+				t.Fatal("the prev has the key", key.Hex()[:7])
+			}
+
+			if _, ok := nks[key]; ok == false {
+				t.Fatal("the next has not the key", key.Hex()[:7])
+			}
+
+		}
+
+		assertTrue(t, len(keys) > 0, "empty diff")
+		assertTrue(t, len(keys) < len(nks)/2, "too big diff")
+
+		// all new objects except the Root
+
+		for key := range nks {
+
+			if _, ok := pks[key]; ok == true || key == next.Hash {
+				continue
+			}
+
+			if _, ok := got[key]; ok == false {
+				t.Fatal("missing key", key.Hex()[:7])
+			}
+
+		}
+
+	})
+
+	t.Run("nil", func(t *testing.T) {
+
+		if keys, err = c.Diff(nil, next); err != nil {
+			t.Fatal(err)
+		}
+
+		assertTrue(t, len(keys) == len(nks)-1, "wrong number of keys")
+
+		for _, key := range keys {
+			assertTrue(t, key != next.Hash, "the Root in the diff")
+		}
+
+	})
+
+	t.Run("same", func(t *testing.T) {
+
+		if keys, err = c.Diff(next, next); err != nil {
+			t.Fatal(err)
+		}
+
+		assertTrue(t, len(keys) == 0, "not empty diff")
+
+	})
+
+}
@@ -11,6 +11,8 @@ var (
 	ErrObjectIsTooLarge = errors.New("object is too large (see MaxObjectSize)")
 	ErrTerminated       = errors.New("terminated")
 	ErrBlankRegistryRef = errors.New("blank registry reference")
+
+	ErrRevokedDelegation = errors.New("delegation is revoked")
 )
 
 // ObjectIsTooLargeError represents error that
@@ -0,0 +1,504 @@
+package skyobject
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject/statutil"
+)
+
+// A GCPhase represents phase of
+// the garbage collector
+type GCPhase int
+
+// phases of the garbage collector
+const (
+	GCIdle  GCPhase = iota // waiting for next cycle
+	GCRoots                // removing Root objects by policy
+	GCMark                 // looking for objects with zero rc
+	GCSweep                // removing objects with zero rc
+)
+
+// String implements fmt.Stringer interface
+func (g GCPhase) String() string {
+	switch g {
+	case GCIdle:
+		return "idle"
+	case GCRoots:
+		return "roots"
+	case GCMark:
+		return "mark"
+	case GCSweep:
+		return "sweep"
+	}
+	return fmt.Sprintf("GCPhase<%d>", g)
+}
+
+// A GCStat represents statistic of
+// the garbage collector of the Container
+type GCStat struct {
+	// Phase is current phase of the
+	// garbage collector
+	Phase GCPhase
+	// Cycles is number of completed cycles
+	Cycles int
+	// Roots is total number of removed Root objects
+	Roots int
+	// Objects is total amount and volume of removed
+	// objects (including Root objects and Registries)
+	Objects ObjectsStat
+
+	// PendingRoots is number of Root objects
+	// that will be removed by current cycle
+	PendingRoots int
+	// PendingObjects is number of objects
+	// that will be removed by current cycle
+	PendingObjects int
+
+	// LastCycle is working time of last
+	// completed cycle (excluding pauses)
+	LastCycle time.Duration
+}
+
+// a Root to remove
+type gcRoot struct {
+	pk    cipher.PubKey
+	nonce uint64
+	seq   uint64
+	hash  cipher.SHA256
+}
+
+// a gc is garbage collector of the Container;
+// a cycle of the gc is
+//
+//	(1) select Root objects to remove by policy
+//	    (GCKeepLast and GCKeepFor, or Retention of
+//	    a feed) and remove them
+//	(2) remove oldest Root objects while used volume
+//	    of the CXDS exceeds GCMaxVolume
+//	(3) find objects with zero rc (mark)
+//	(4) remove them if they are not cached (sweep)
+//
+// The (1), (2) and (4) yield after the GCBudget and
+// continues next time. The (3) is a read-only pass
+// over the CXDS. Last Root of a head never removed
+// by the gc
+type gc struct {
+	mx sync.Mutex // lock for steps
+
+	c *Container // back reference
+
+	phase GCPhase         // current phase
+	roots []gcRoot        // roots to remove
+	keys  []cipher.SHA256 // objects to remove (with zero rc)
+	ct    time.Duration   // working time of current cycle
+
+	smx  sync.Mutex // lock for the stat
+	stat GCStat     // statistic
+
+	quit   chan struct{}
+	done   chan struct{}
+	closeo sync.Once
+}
+
+// initialize the gc and start it if the
+// GCInterval is greater then zero
+func (c *Container) initGC() {
+
+	c.gc.c = c
+
+	c.gc.quit = make(chan struct{})
+	c.gc.done = make(chan struct{})
+
+	if c.conf.GCInterval <= 0 {
+		close(c.gc.done) // not started
+		return
+	}
+
+	go c.gc.loop(c.conf.GCInterval, c.conf.GCBudget)
+}
+
+func (g *gc) loop(interval, budget time.Duration) {
+
+	defer close(g.done)
+
+	var (
+		tk = time.NewTicker(interval)
+		tc = tk.C
+	)
+
+	defer tk.Stop()
+
+	for {
+
+		select {
+		case <-tc:
+		case <-g.quit:
+			return
+		}
+
+		// yield after the budget
+		if _, err := g.step(budget); err != nil {
+			fatal("[ERR] [GC] DB failure:", err) // DB failure
+		}
+
+	}
+
+}
+
+// close the gc and wait its goroutine
+// and RunGC if it's in progress
+func (g *gc) close() {
+	g.closeo.Do(func() {
+		close(g.quit)
+	})
+	<-g.done
+
+	g.mx.Lock()
+	g.mx.Unlock()
+}
+
+func (g *gc) isClosed() (closed bool) {
+	select {
+	case <-g.quit:
+		closed = true
+	default:
+	}
+	return
+}
+
+// step performs next step of the gc; if given budget is
+// zero or negative, then the step performs entire cycle;
+// the done reply is true if cycle has been completed
+func (g *gc) step(budget time.Duration) (done bool, err error) {
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	var (
+		tp       = time.Now()
+		deadline time.Time
+	)
+
+	if budget > 0 {
+		deadline = tp.Add(budget)
+	}
+
+	defer func() {
+		g.ct += time.Now().Sub(tp)
+		g.updateStat(done)
+	}()
+
+	for {
+
+		switch g.phase {
+
+		case GCIdle:
+
+			if g.roots, err = g.c.gcPolicyRoots(); err != nil {
+				return
+			}
+			g.phase = GCRoots
+
+		case GCRoots:
+
+			if err = g.removeRoots(deadline); err != nil {
+				return
+			}
+
+			if len(g.roots) > 0 {
+				return // out of budget
+			}
+
+			var r gcRoot
+			var ok bool
+
+			if r, ok, err = g.c.gcVolumeRoot(); err != nil {
+				return
+			}
+
+			if ok == true {
+				g.roots = append(g.roots, r)
+				break // remove the Root (and check volume again)
+			}
+
+			g.phase = GCMark
+
+		case GCMark:
+
+			if g.keys, err = g.c.gcMark(); err != nil {
+				return
+			}
+			g.phase = GCSweep
+
+		case GCSweep:
+
+			if err = g.sweep(deadline); err != nil {
+				return
+			}
+
+			if len(g.keys) > 0 {
+				return // out of budget
+			}
+
+			g.phase = GCIdle
+			done = true
+			return
+
+		}
+
+		if isExpired(deadline) == true {
+			return
+		}
+
+	}
+
+}
+
+func isExpired(deadline time.Time) bool {
+	return deadline.IsZero() == false && time.Now().After(deadline)
+}
+
+// remove Root objects from the g.roots
+func (g *gc) removeRoots(deadline time.Time) (err error) {
+
+	// remove one Root at least to be sure
+	// that the gc makes progress
+
+	for i := 0; len(g.roots) > 0; i++ {
+
+		if i > 0 && isExpired(deadline) == true || g.isClosed() == true {
+			return
+		}
+
+		var r = g.roots[0]
+
+		// the last Root could be removed by user
+		// and the r can be the last now
+
+		var last uint64
+		if last, err = g.c.LastRootSeq(r.pk, r.nonce); err != nil {
+			err = nil // removed by user
+		} else if last != r.seq {
+
+			err = g.c.DelRoot(r.pk, r.nonce, r.seq)
+
+			switch err {
+			case nil:
+				g.smx.Lock()
+				g.stat.Roots++
+				g.smx.Unlock()
+			case data.ErrNotFound, data.ErrNoSuchFeed, data.ErrNoSuchHead:
+				err = nil // already removed
+			default:
+				return // DB failure
+			}
+
+		}
+
+		g.roots = g.roots[1:]
+
+	}
+
+	g.roots = nil // GC
+	return
+}
+
+// remove objects from the g.keys
+func (g *gc) sweep(deadline time.Time) (err error) {
+
+	// remove one object at least (see removeRoots)
+
+	for i := 0; len(g.keys) > 0; i++ {
+
+		if i > 0 && isExpired(deadline) == true || g.isClosed() == true {
+			return
+		}
+
+		var (
+			key = g.keys[0]
+			vol int
+			del bool
+		)
+
+		if del, vol, err = g.c.gcDel(key); err != nil {
+			return
+		}
+
+		if del == true {
+			g.smx.Lock()
+			g.stat.Objects.Amount++
+			g.stat.Objects.Volume += statutil.Volume(vol)
+			g.smx.Unlock()
+		}
+
+		g.keys = g.keys[1:]
+
+	}
+
+	g.keys = nil // GC
+	return
+}
+
+// under lock of the g.mx
+func (g *gc) updateStat(done bool) {
+
+	g.smx.Lock()
+	defer g.smx.Unlock()
+
+	g.stat.Phase = g.phase
+	g.stat.PendingRoots = len(g.roots)
+	g.stat.PendingObjects = len(g.keys)
+
+	if done == true {
+		g.stat.Cycles++
+		g.stat.LastCycle = g.ct
+		g.ct = 0
+	}
+}
+
+func (g *gc) getStat() (s GCStat) {
+
+	g.smx.Lock()
+	defer g.smx.Unlock()
+
+	return g.stat
+}
+
+// Root objects to remove by policy
+func (c *Container) gcPolicyRoots() (rs []gcRoot, err error) {
+	return c.retentionRoots(nil) // all feeds
+}
+
+// the oldest Root (except last Root of a head) to remove
+// if used volume of the CXDS exceeds the GCMaxVolume
+func (c *Container) gcVolumeRoot() (r gcRoot, ok bool, err error) {
+
+	if c.conf.GCMaxVolume <= 0 {
+		return
+	}
+
+	if _, used := c.db.CXDS().Volume(); used <= c.conf.GCMaxVolume {
+		return
+	}
+
+	var oldest int64
+
+	err = c.db.IdxDB().Tx(func(feeds data.Feeds) (err error) {
+
+		return feeds.Iterate(func(pk cipher.PubKey) (err error) {
+
+			var hs data.Heads
+			if hs, err = feeds.Heads(pk); err != nil {
+				return
+			}
+
+			return hs.Iterate(func(nonce uint64) (err error) {
+
+				var roots data.Roots
+				if roots, err = hs.Roots(nonce); err != nil {
+					return
+				}
+
+				if roots.Len() < 2 {
+					return // keep last Root
+				}
+
+				// the first is the oldest
+				return roots.Ascend(func(dr *data.Root) (err error) {
+
+					if ok == false || dr.Time < oldest {
+						r = gcRoot{pk, nonce, dr.Seq, dr.Hash}
+						oldest, ok = dr.Time, true
+					}
+
+					return data.ErrStopIteration
+				})
+
+			})
+
+		})
+
+	})
+
+	return
+}
+
+// find objects with zero rc
+func (c *Container) gcMark() (keys []cipher.SHA256, err error) {
+
+	err = c.db.CXDS().Iterate(
+		func(key cipher.SHA256, rc uint32, _ []byte) (err error) {
+			if rc == 0 {
+				keys = append(keys, key)
+			}
+			return
+		})
+
+	return
+}
+
+// gcDel removes object with zero rc if it is not cached; the
+// lock of the Cache used to be sure that the object will not
+// be resurrected during removing
+func (c *Container) gcDel(
+	key cipher.SHA256, // : key of the object
+) (
+	del bool, //          : removed
+	vol int, //           : volume of the object
+	err error, //         : a DB error
+) {
+
+	c.Cache.mx.Lock()
+	defer c.Cache.mx.Unlock()
+
+	// wanted, filling or cached with
+	// write-behind rc changes
+
+	if _, ok := c.Cache.is[key]; ok == true {
+		return
+	}
+
+	var (
+		val []byte
+		rc  uint32
+	)
+
+	if val, rc, err = c.db.CXDS().Get(key, 0); err != nil {
+		if err == data.ErrNotFound {
+			err = nil // already removed
+		}
+		return
+	}
+
+	if rc > 0 {
+		return // resurrected
+	}
+
+	if err = c.db.CXDS().Del(key); err != nil {
+		return
+	}
+
+	return true, len(val), nil
+}
+
+// RunGC performs entire cycle of the garbage collector
+// ignoring GCBudget. If the garbage collector is in
+// progress, then the RunGC continues current cycle.
+// The RunGC can be used even if the GCInterval is zero
+// and the garbage collector is not started. See also
+// GCKeepLast, GCKeepFor and GCMaxVolume fields of the
+// Config
+func (c *Container) RunGC() (err error) {
+
+	if c.gc.isClosed() == true {
+		return ErrTerminated
+	}
+
+	_, err = c.gc.step(0)
+	return
+}
@@ -0,0 +1,235 @@
+package skyobject
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// save n Root objects with growing feed
+func saveTestRoots(
+	t *testing.T,
+	c *Container,
+	n int,
+) (
+	pk cipher.PubKey,
+	r *registry.Root,
+) {
+
+	t.Helper()
+
+	var sk cipher.SecKey
+	pk, sk = cipher.GenerateKeyPair()
+
+	assertNil(t, c.AddFeed(pk))
+
+	var up, err = c.Unpack(sk, testRegistry)
+	assertNil(t, err)
+
+	r = new(registry.Root)
+
+	r.Pub = pk
+	r.Nonce = 9021
+
+	var feed = Feed{
+		Head: "Alices' feed",
+		Info: "an average feed",
+	}
+
+	r.Refs = []registry.Dynamic{
+		createDynamic(up, testRegistry, "test.Feed", &feed),
+	}
+
+	for i := 0; i < n; i++ {
+
+		assertNil(t, feed.Posts.AppendValues(up, Post{
+			Head: fmt.Sprintf("Head #%d", i),
+			Body: fmt.Sprintf("Body #%d", i),
+		}))
+
+		assertNil(t, r.Refs[0].SetValue(up, &feed))
+		assertNil(t, c.Save(up, r))
+	}
+
+	return
+}
+
+func headLen(c *Container, pk cipher.PubKey, nonce uint64) int {
+	return c.Stat().Feeds[pk].Heads[nonce].Len
+}
+
+// objects with zero rc should be removed or cached
+func testGCSwept(t *testing.T, c *Container) {
+	t.Helper()
+
+	assertNil(t, c.db.CXDS().Iterate(
+		func(key cipher.SHA256, rc uint32, _ []byte) (_ error) {
+			if rc == 0 && c.IsCached(key) == false {
+				t.Error("object with zero rc is not removed:", key.Hex()[:7])
+			}
+			return
+		}))
+
+	if t.Failed() == true {
+		t.FailNow()
+	}
+}
+
+func testGCLastRoot(t *testing.T, c *Container, r *registry.Root) {
+	t.Helper()
+
+	var last, err = c.LastRoot(r.Pub, r.Nonce)
+	assertNil(t, err)
+
+	assertTrue(t, last.Seq == r.Seq, "wrong last Root")
+	assertNil(t, c.Walk(last, func(cipher.SHA256, int) (bool, error) {
+		return true, nil
+	}))
+}
+
+func TestContainer_RunGC(t *testing.T) {
+
+	t.Run("zero rc", func(t *testing.T) {
+
+		var c = getTestContainer()
+		defer c.Close()
+
+		var pk, r = saveTestRoots(t, c, 10)
+
+		// all Root objects are kept
+		assertNil(t, c.RunGC())
+		assertTrue(t, headLen(c, pk, r.Nonce) == 10, "wrong number of roots")
+
+		// remove manually
+		assertNil(t, c.DelRoot(pk, r.Nonce, 0))
+		assertNil(t, c.RunGC())
+
+		testGCSwept(t, c)
+		testGCLastRoot(t, c, r)
+
+		var gs = c.Stat().GC
+
+		assertTrue(t, gs.Cycles == 2, "wrong number of cycles")
+		assertTrue(t, gs.Phase == GCIdle, "wrong phase")
+		assertTrue(t, gs.Objects.Amount > 0, "objects are not removed")
+	})
+
+	t.Run("keep last", func(t *testing.T) {
+
+		var conf = getTestConfig()
+		conf.GCKeepLast = 3
+
+		var c, err = NewContainer(conf)
+		assertNil(t, err)
+		defer c.Close()
+
+		var pk, r = saveTestRoots(t, c, 10)
+
+		assertNil(t, c.RunGC())
+		assertTrue(t, headLen(c, pk, r.Nonce) == 3, "wrong number of roots")
+
+		testGCSwept(t, c)
+		testGCLastRoot(t, c, r)
+
+		assertTrue(t, c.Stat().GC.Roots == 7, "wrong number of removed roots")
+	})
+
+	t.Run("keep for", func(t *testing.T) {
+
+		var conf = getTestConfig()
+		conf.GCKeepFor = time.Hour
+
+		var c, err = NewContainer(conf)
+		assertNil(t, err)
+		defer c.Close()
+
+		var pk, r = saveTestRoots(t, c, 5)
+
+		// all Root objects are newer
+		assertNil(t, c.RunGC())
+		assertTrue(t, headLen(c, pk, r.Nonce) == 5, "wrong number of roots")
+	})
+
+	t.Run("max volume", func(t *testing.T) {
+
+		var conf = getTestConfig()
+		conf.GCMaxVolume = 1 // any
+
+		var c, err = NewContainer(conf)
+		assertNil(t, err)
+		defer c.Close()
+
+		var pk, r = saveTestRoots(t, c, 10)
+
+		// last Root is kept anyway
+		assertNil(t, c.RunGC())
+		assertTrue(t, headLen(c, pk, r.Nonce) == 1, "wrong number of roots")
+
+		testGCSwept(t, c)
+		testGCLastRoot(t, c, r)
+	})
+
+}
+
+func TestContainer_gcBudget(t *testing.T) {
+
+	var conf = getTestConfig()
+	conf.GCKeepLast = 1
+
+	var c, err = NewContainer(conf)
+	assertNil(t, err)
+	defer c.Close()
+
+	var pk, r = saveTestRoots(t, c, 10)
+
+	var (
+		done  bool
+		steps int
+	)
+
+	for done == false {
+		if done, err = c.gc.step(time.Nanosecond); err != nil {
+			t.Fatal(err)
+		}
+		steps++
+	}
+
+	assertTrue(t, steps > 1, "the budget is ignored")
+	assertTrue(t, headLen(c, pk, r.Nonce) == 1, "wrong number of roots")
+
+	testGCSwept(t, c)
+	testGCLastRoot(t, c, r)
+
+	var gs = c.Stat().GC
+	assertTrue(t, gs.Cycles == 1, "wrong number of cycles")
+	assertTrue(t, gs.PendingRoots == 0, "pending roots")
+	assertTrue(t, gs.PendingObjects == 0, "pending objects")
+}
+
+func TestContainer_gcLoop(t *testing.T) {
+
+	var conf = getTestConfig()
+	conf.GCKeepLast = 1
+	conf.GCInterval = 10 * time.Millisecond
+
+	var c, err = NewContainer(conf)
+	assertNil(t, err)
+
+	var pk, r = saveTestRoots(t, c, 5)
+
+	var tm = time.Now().Add(5 * time.Second)
+
+	for headLen(c, pk, r.Nonce) != 1 {
+		if time.Now().After(tm) {
+			t.Fatal("slow or stopped garbage collector")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assertNil(t, c.Close())
+	assertTrue(t, c.RunGC() == ErrTerminated, "missing ErrTerminated")
+}
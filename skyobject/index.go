@@ -48,7 +48,8 @@ func (i *indexHeads) setActive() {
 // The Index keeps information about last Root
 // objects for fast access
 type Index struct {
-	mx sync.Mutex
+	mx  sync.Mutex
+	dmx sync.RWMutex // removing lock (see Container.Backup)
 
 	c *Container // back reference (for db.IdxDB and for the Cache)
 
@@ -57,6 +58,8 @@ type Index struct {
 	feeds  map[cipher.PubKey]*indexHeads
 	feedsl []cipher.PubKey // change on write
 
+	dsets map[cipher.PubKey]uint64 // latest known versions of DelegationSet
+
 	stat   *indexStat
 	closeo sync.Once // close once
 }
@@ -67,6 +70,7 @@ func (i *Index) load(c *Container) (err error) {
 	i.stat = newIndexStat(c.conf.RollAvgSamples)
 
 	i.feeds = make(map[cipher.PubKey]*indexHeads)
+	i.dsets = make(map[cipher.PubKey]uint64)
 	i.c = c
 
 	err = i.c.db.IdxDB().Tx(func(feeds data.Feeds) (err error) {
@@ -131,6 +135,28 @@ func (i *Index) load(c *Container) (err error) {
 		return
 	}
 
+	// versions of DelegationSet of last Root objects
+
+	for _, hs := range i.feeds {
+		for _, dr := range hs.h {
+			if dr == nil {
+				continue
+			}
+			var val []byte
+			if val, _, err = c.Get(dr.Hash, 0); err == data.ErrNotFound {
+				err = nil
+				continue // not filled yet
+			} else if err != nil {
+				return
+			}
+			var r *registry.Root
+			if r, err = registry.DecodeRoot(val); err != nil {
+				return
+			}
+			i.trackDelegations(r)
+		}
+	}
+
 	i.loadTime = time.Now().UnixNano()
 
 	return
@@ -251,21 +277,68 @@ func (i *Index) receivedRoot(
 	err error,
 ) {
 
-	var hash = cipher.SumSHA256(val)
-	if err = cipher.VerifySignature(pk, sig, hash); err != nil {
+	if r, err = registry.DecodeRoot(val); err != nil {
 		return
 	}
 
-	if r, err = registry.DecodeRoot(val); err != nil {
+	if r.Pub != pk {
+		return nil, errors.New("wrong feed of Root: " + r.Short())
+	}
+
+	r.Hash = cipher.SumSHA256(val) // set the hash
+	r.Sig = sig                    // set the signature
+
+	if err = i.verifyRoot(r); err != nil {
+		return nil, err
+	}
+
+	// the DelegationSet is signed by owner of the
+	// feed, thus it can be tracked before the Root
+	// will be filled
+	i.trackDelegations(r)
+
+	return
+}
+
+// under lock, verify signature and delegations
+func (i *Index) verifyRoot(r *registry.Root) (err error) {
+
+	var (
+		signer cipher.PubKey
+		d      *registry.DelegationSet
+	)
+
+	if signer, d, err = r.VerifySignature(); err != nil {
 		return
 	}
 
-	r.Hash = hash // set the hash
-	r.Sig = sig   // set the signature
+	if signer != r.Pub && d.Version < i.dsets[r.Pub] {
+		return ErrRevokedDelegation
+	}
 
 	return
 }
 
+// under lock, keep latest version of DelegationSet
+// of feed of given Root; the Root must be verified
+func (i *Index) trackDelegations(r *registry.Root) {
+
+	if len(r.Delegations) == 0 {
+		return
+	}
+
+	var d, err = registry.DecodeDelegationSet(r.Delegations)
+
+	if err != nil {
+		return // never happens for verified Root
+	}
+
+	if d.Version > i.dsets[r.Pub] {
+		i.dsets[r.Pub] = d.Version
+	}
+
+}
+
 // PreviewRoot method used by node package to check
 // a root recevied for preview-request. The method
 // doesn't check feed, e.g. the ReceviedRoot method
@@ -411,6 +484,8 @@ func (i *Index) addRoot(r *registry.Root) (alreadyHave bool, err error) {
 		hs.activen = r.Nonce
 	}
 
+	i.trackDelegations(r)
+
 	// add to stat
 	i.stat.addRoot()
 
@@ -433,6 +508,8 @@ func (i *Index) addSavedRoot(r *registry.Root, dr *data.Root) {
 		hs.activen = r.Nonce
 	}
 
+	i.trackDelegations(r)
+
 	// add to stat
 	i.stat.addRoot()
 
@@ -640,6 +717,9 @@ func (i *Index) delFeedLock(
 // DelFeed deletes feed with all heads and Root objects
 func (i *Index) DelFeed(pk cipher.PubKey) (err error) {
 
+	i.dmx.RLock()
+	defer i.dmx.RUnlock()
+
 	// with lock
 	var rhs []cipher.SHA256
 	if rhs, err = i.delFeedLock(pk); err != nil {
@@ -732,6 +812,9 @@ func (i *Index) delHeadLock(
 // Root of the head is held, returning ErrRootIsHeld error
 func (i *Index) DelHead(pk cipher.PubKey, nonce uint64) (err error) {
 
+	i.dmx.RLock()
+	defer i.dmx.RUnlock()
+
 	// with lock
 
 	var rhs []cipher.SHA256
@@ -892,7 +975,9 @@ func (i *Index) delPackWalkFunc(
 		// use Get instead of Inc(hash, -1) to get value
 		// if it will be deleted by the -1
 
-		if val, rc, err = i.c.getNoCache(hash, -1); err != nil {
+		if val, rc, err = i.c.getNoCache(hash, -1); err == data.ErrNotFound {
+			return false, nil // missing object (e.g. not full Root)
+		} else if err != nil {
 			return
 		}
 
@@ -939,6 +1024,9 @@ func (i *Index) delRootRelatedValues(rootHash cipher.SHA256) (err error) {
 // Root doesn't exist
 func (i *Index) DelRoot(pk cipher.PubKey, nonce, seq uint64) (err error) {
 
+	i.dmx.RLock()
+	defer i.dmx.RUnlock()
+
 	// with lock
 	var rootHash cipher.SHA256
 	if rootHash, err = i.delRootLock(pk, nonce, seq); err != nil {
@@ -0,0 +1,386 @@
+package skyobject
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// errors of the KeyStore
+var (
+	ErrKeyStoreLocked    = errors.New("key store is locked")
+	ErrInvalidPassphrase = errors.New("invalid passphrase")
+	ErrInvalidKeyStore   = errors.New("invalid key store")
+	ErrNoSuchKey         = errors.New("no secret key of the feed")
+)
+
+// key store file format
+//
+//     magic    [8]byte
+//     salt     [32]byte
+//     nonce    [12]byte
+//     sealed   []byte    (AES-256-GCM, the magic is additional data)
+//
+// where the sealed is encoded []keyStoreEntry, and key of
+// the AES is derived from passphrase using PBKDF2-SHA256
+const (
+	keyStoreMagic      = "CXOKEYS1"
+	keyStoreSaltSize   = 32
+	keyStoreIterations = 1 << 16
+)
+
+type keyStoreEntry struct {
+	Pub cipher.PubKey
+	Sec cipher.SecKey
+}
+
+// A KeyStore keeps secret keys of feeds the Container
+// owns. The KeyStore of a Container with DB on disk
+// encrypted and saved under DataDir (see Keys
+// constant) or near the DBPath. Such KeyStore is
+// locked by default and should be unlocked using
+// a passphrase before use. The KeyStore of a Container
+// with DB in memory (or with DB provided by user) is
+// kept in memory only and is unlocked by default
+type KeyStore struct {
+	mx sync.Mutex
+
+	path string // empty for in-memory
+
+	salt []byte // salt of the key
+	key  []byte // encryption key, nil if locked
+
+	keys map[cipher.PubKey]cipher.SecKey
+}
+
+// path to key store file or empty string for in-memory
+func (c *Config) keyStore() (path string) {
+
+	switch {
+	case c.DB != nil || c.InMemoryDB == true:
+		return
+	case c.DBPath != "":
+		path = c.DBPath + ".keys"
+	default:
+		path = filepath.Join(c.DataDir, Keys)
+	}
+
+	return
+}
+
+func newKeyStore(path string) (k *KeyStore) {
+
+	k = new(KeyStore)
+	k.path = path
+
+	if path == "" {
+		k.keys = make(map[cipher.PubKey]cipher.SecKey) // unlocked
+	}
+
+	return
+}
+
+// IsLocked returns true if the KeyStore is locked
+func (k *KeyStore) IsLocked() bool {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	return k.keys == nil
+}
+
+// Unlock the KeyStore using given passphrase. If key store
+// file doesn't exist, then it will be created. The Unlock
+// returns ErrInvalidPassphrase if the passphrase is wrong.
+// The Unlock of in-memory KeyStore does nothing
+func (k *KeyStore) Unlock(passphrase []byte) (err error) {
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if k.keys != nil {
+		return // already unlocked
+	}
+
+	var sealed []byte
+
+	if sealed, err = ioutil.ReadFile(k.path); os.IsNotExist(err) == true {
+
+		// create new one
+
+		k.salt = make([]byte, keyStoreSaltSize)
+
+		if _, err = rand.Read(k.salt); err != nil {
+			return
+		}
+
+		k.key = keyStoreKey(passphrase, k.salt)
+		k.keys = make(map[cipher.PubKey]cipher.SecKey)
+
+		if err = k.save(); err != nil {
+			k.lock()
+		}
+
+		return
+
+	} else if err != nil {
+		return
+	}
+
+	var hl = len(keyStoreMagic) + keyStoreSaltSize
+
+	if len(sealed) < hl ||
+		string(sealed[:len(keyStoreMagic)]) != keyStoreMagic {
+
+		return ErrInvalidKeyStore
+	}
+
+	var (
+		salt = sealed[len(keyStoreMagic):hl]
+		key  = keyStoreKey(passphrase, salt)
+		aead gocipher.AEAD
+	)
+
+	if aead, err = keyStoreAEAD(key); err != nil {
+		return
+	}
+
+	if len(sealed) < hl+aead.NonceSize() {
+		return ErrInvalidKeyStore
+	}
+
+	var (
+		nonce = sealed[hl : hl+aead.NonceSize()]
+		plain []byte
+	)
+
+	plain, err = aead.Open(nil, nonce, sealed[hl+aead.NonceSize():],
+		[]byte(keyStoreMagic))
+
+	if err != nil {
+		return ErrInvalidPassphrase
+	}
+
+	var ents []keyStoreEntry
+	if err = encoder.DeserializeRaw(plain, &ents); err != nil {
+		return ErrInvalidKeyStore
+	}
+
+	k.keys = make(map[cipher.PubKey]cipher.SecKey, len(ents))
+
+	for _, ent := range ents {
+		k.keys[ent.Pub] = ent.Sec
+	}
+
+	k.salt, k.key = salt, key
+	return
+}
+
+// Lock the KeyStore removing all keys from memory.
+// An in-memory KeyStore can't be locked
+func (k *KeyStore) Lock() {
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	k.lock()
+}
+
+func (k *KeyStore) lock() {
+	if k.path != "" {
+		k.keys, k.key = nil, nil
+	}
+}
+
+func keyStoreKey(passphrase, salt []byte) []byte {
+	return pbkdf2.Key(passphrase, salt, keyStoreIterations, 32, sha256.New)
+}
+
+func keyStoreAEAD(key []byte) (aead gocipher.AEAD, err error) {
+
+	var block gocipher.Block
+	if block, err = aes.NewCipher(key); err != nil {
+		return
+	}
+
+	return gocipher.NewGCM(block)
+}
+
+// save encrypted keys to file
+func (k *KeyStore) save() (err error) {
+
+	if k.path == "" {
+		return // in-memory
+	}
+
+	var ents = make([]keyStoreEntry, 0, len(k.keys))
+
+	for pk, sk := range k.keys {
+		ents = append(ents, keyStoreEntry{pk, sk})
+	}
+
+	var aead gocipher.AEAD
+	if aead, err = keyStoreAEAD(k.key); err != nil {
+		return
+	}
+
+	var nonce = make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+
+	buf.WriteString(keyStoreMagic)
+	buf.Write(k.salt)
+	buf.Write(nonce)
+	buf.Write(aead.Seal(nil, nonce, encoder.Serialize(ents),
+		[]byte(keyStoreMagic)))
+
+	// write to temporary file and rename after
+
+	var tmp = k.path + ".tmp"
+
+	if err = ioutil.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return
+	}
+
+	if err = os.Rename(tmp, k.path); err != nil {
+		os.Remove(tmp)
+	}
+
+	return
+}
+
+// Add secret key of a feed to the KeyStore
+func (k *KeyStore) Add(sk cipher.SecKey) (pk cipher.PubKey, err error) {
+
+	if err = sk.Verify(); err != nil {
+		return
+	}
+
+	pk = cipher.PubKeyFromSecKey(sk)
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if k.keys == nil {
+		return pk, ErrKeyStoreLocked
+	}
+
+	if _, ok := k.keys[pk]; ok == true {
+		return // already have
+	}
+
+	k.keys[pk] = sk
+
+	if err = k.save(); err != nil {
+		delete(k.keys, pk)
+	}
+
+	return
+}
+
+// Del removes secret key of given feed
+// from the KeyStore
+func (k *KeyStore) Del(pk cipher.PubKey) (err error) {
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if k.keys == nil {
+		return ErrKeyStoreLocked
+	}
+
+	var sk, ok = k.keys[pk]
+
+	if ok == false {
+		return // not found
+	}
+
+	delete(k.keys, pk)
+
+	if err = k.save(); err != nil {
+		k.keys[pk] = sk
+	}
+
+	return
+}
+
+// Feeds returns sorted list of feeds
+// the KeyStore has secret keys of
+func (k *KeyStore) Feeds() (pks []cipher.PubKey, err error) {
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if k.keys == nil {
+		return nil, ErrKeyStoreLocked
+	}
+
+	pks = make([]cipher.PubKey, 0, len(k.keys))
+
+	for pk := range k.keys {
+		pks = append(pks, pk)
+	}
+
+	sort.Slice(pks, func(i, j int) bool {
+		return bytes.Compare(pks[i][:], pks[j][:]) < 0
+	})
+
+	return
+}
+
+// SecKey returns secret key of given feed
+func (k *KeyStore) SecKey(pk cipher.PubKey) (sk cipher.SecKey, err error) {
+
+	k.mx.Lock()
+	defer k.mx.Unlock()
+
+	if k.keys == nil {
+		return sk, ErrKeyStoreLocked
+	}
+
+	var ok bool
+	if sk, ok = k.keys[pk]; ok == false {
+		err = ErrNoSuchKey
+	}
+
+	return
+}
+
+// KeyStore of the Container
+func (c *Container) KeyStore() *KeyStore {
+	return c.keys
+}
+
+// UnpackFeed is the same as the Unpack, but it uses
+// secret key of given feed from the KeyStore
+func (c *Container) UnpackFeed(
+	pk cipher.PubKey, //         : feed
+	reg *registry.Registry, //   : registry
+) (
+	up *Unpack, //               : unpack
+	err error, //                : an error
+) {
+
+	var sk cipher.SecKey
+	if sk, err = c.keys.SecKey(pk); err != nil {
+		return
+	}
+
+	return c.Unpack(sk, reg)
+}
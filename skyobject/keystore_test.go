@@ -0,0 +1,100 @@
+package skyobject
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestKeyStore(t *testing.T) {
+
+	var conf, clean = testRestoreConfig(t)
+	defer clean()
+
+	var c, err = NewContainer(conf)
+	assertNil(t, err)
+
+	var (
+		ks     = c.KeyStore()
+		pk, sk = cipher.GenerateKeyPair()
+	)
+
+	assertTrue(t, ks.IsLocked(), "not locked")
+
+	if _, err = ks.Add(sk); err != ErrKeyStoreLocked {
+		t.Error("unexpected error", err)
+	}
+
+	assertNil(t, ks.Unlock([]byte("passphrase"))) // create
+
+	var apk cipher.PubKey
+	apk, err = ks.Add(sk)
+	assertNil(t, err)
+	assertTrue(t, apk == pk, "wrong public key")
+
+	ks.Lock()
+
+	if err = ks.Unlock([]byte("wrong")); err != ErrInvalidPassphrase {
+		t.Error("unexpected error", err)
+	}
+
+	assertNil(t, c.Close())
+
+	// reopen
+
+	c, err = NewContainer(conf)
+	assertNil(t, err)
+	defer c.Close()
+
+	ks = c.KeyStore()
+
+	assertNil(t, ks.Unlock([]byte("passphrase")))
+
+	var pks []cipher.PubKey
+	pks, err = ks.Feeds()
+	assertNil(t, err)
+	assertTrue(t, len(pks) == 1 && pks[0] == pk, "wrong feeds")
+
+	var ssk cipher.SecKey
+	ssk, err = ks.SecKey(pk)
+	assertNil(t, err)
+	assertTrue(t, ssk == sk, "wrong secret key")
+
+	// NewRoot
+
+	assertNil(t, c.AddFeed(pk))
+
+	var (
+		r  *registry.Root
+		up *Unpack
+	)
+
+	r, up, err = c.NewRoot(pk, 0, testRegistry)
+	assertNil(t, err)
+	assertTrue(t, r.Nonce != 0 && r.Pub == pk, "wrong Root")
+
+	r.Refs = []registry.Dynamic{
+		createDynamic(up, testRegistry, "test.User", &User{"Alice", 21}),
+	}
+
+	assertNil(t, c.Save(up, r))
+	assertNil(t, up.Close())
+
+	var next *registry.Root
+	next, up, err = c.NewRoot(pk, 0, testRegistry)
+	assertNil(t, err)
+	defer up.Close()
+
+	assertTrue(t, next.Nonce == r.Nonce && next.Hash == r.Hash, "not last Root")
+
+	// delete
+
+	assertNil(t, ks.Del(pk))
+
+	if _, _, err = c.NewRoot(pk, 0, testRegistry); err != ErrNoSuchKey {
+		t.Error("unexpected error", err)
+	}
+
+}
@@ -0,0 +1,128 @@
+package skyobject
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// ErrMergeHeads occurs if the Merge called with
+// less then two heads
+var ErrMergeHeads = errors.New("merge requires two or more heads")
+
+// A MergeFunc merges last Root objects of heads of a feed
+// and returns Refs of the merge Root. Given pack is Unpack
+// of the Merge, it can be used to read objects of the Root
+// objects and to create new objects. The heads are in the
+// order passed to the Merge. The MergeFunc must not modify
+// the Root objects
+type MergeFunc func(
+	pack registry.Pack, //       : pack to get and to save objects
+	heads []*registry.Root, //   : last Root objects of the heads
+) (
+	refs []registry.Dynamic, //  : Refs of the merge Root
+	err error, //                : an error
+)
+
+// Merge heads of a feed. The Merge gets last Root objects
+// of given heads, merges them using given MergeFunc and
+// saves given Root with resulting Refs. Hashes of the
+// merged Root objects are recorded in the Merged field
+// of the Root, thus peers can see the merge. The Root
+// should have Pub and Nonce fields (see Save). The Nonce
+// can be one of the merged heads or a new head. Use
+// the MergeAppend as the MergeFunc for append-only
+// Refs. The Merge doesn't remove merged heads
+func (c *Container) Merge(
+	up *Unpack, //          : pack to save
+	r *registry.Root, //    : the merge Root
+	heads []uint64, //      : heads to merge
+	merge MergeFunc, //     : merge function
+) (
+	err error, //           : an error
+) {
+
+	if len(heads) < 2 {
+		return ErrMergeHeads
+	}
+
+	var (
+		roots  = make([]*registry.Root, 0, len(heads))
+		merged = make([]cipher.SHA256, 0, len(heads))
+		seen   = make(map[uint64]struct{}, len(heads))
+	)
+
+	for _, nonce := range heads {
+
+		if _, ok := seen[nonce]; ok == true {
+			continue // repeated head
+		}
+
+		seen[nonce] = struct{}{}
+
+		var lr *registry.Root
+		if lr, err = c.LastRoot(r.Pub, nonce); err != nil {
+			return
+		}
+
+		roots = append(roots, lr)
+		merged = append(merged, lr.Hash)
+	}
+
+	if len(roots) < 2 {
+		return ErrMergeHeads
+	}
+
+	var refs []registry.Dynamic
+	if refs, err = merge(up, roots); err != nil {
+		return
+	}
+
+	r.Refs = refs
+	r.Merged = merged
+
+	return c.Save(up, r)
+}
+
+// MergeAppend is MergeFunc for append-only Refs. The
+// MergeAppend returns union of Refs of given Root
+// objects. The Refs are ordered by timestamps of the
+// Root objects (older first), keeping order of Refs
+// of every Root. Repeated Dynamic references are
+// skipped (the first one is kept)
+func MergeAppend(
+	_ registry.Pack,
+	heads []*registry.Root,
+) (
+	refs []registry.Dynamic,
+	err error,
+) {
+
+	var (
+		ordered = make([]*registry.Root, len(heads))
+		have    = make(map[registry.Dynamic]struct{})
+	)
+
+	copy(ordered, heads)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Time < ordered[j].Time
+	})
+
+	for _, r := range ordered {
+		for _, dr := range r.Refs {
+
+			if _, ok := have[dr]; ok == true {
+				continue
+			}
+
+			have[dr] = struct{}{}
+			refs = append(refs, dr)
+		}
+	}
+
+	return
+}
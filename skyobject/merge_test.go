@@ -0,0 +1,69 @@
+package skyobject
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestContainer_Merge(t *testing.T) {
+
+	var (
+		c      = getTestContainer()
+		pk, sk = cipher.GenerateKeyPair()
+	)
+
+	defer c.Close()
+
+	assertNil(t, c.AddFeed(pk))
+
+	var up, err = c.Unpack(sk, testRegistry)
+	assertNil(t, err)
+
+	var (
+		alice = createDynamic(up, testRegistry, "test.User", &User{"Alice", 21})
+		bob   = createDynamic(up, testRegistry, "test.User", &User{"Bob", 32})
+		eva   = createDynamic(up, testRegistry, "test.User", &User{"Eva", 19})
+
+		a = &registry.Root{Pub: pk, Nonce: 1, Refs: []registry.Dynamic{alice}}
+		b = &registry.Root{Pub: pk, Nonce: 2, Refs: []registry.Dynamic{eva}}
+	)
+
+	assertNil(t, c.Save(up, a))
+	assertNil(t, c.Save(up, b))
+
+	a.Refs = append(a.Refs, bob)
+	assertNil(t, c.Save(up, a))
+
+	var m = &registry.Root{Pub: pk, Nonce: 1}
+
+	if err = c.Merge(up, m, []uint64{1}, MergeAppend); err != ErrMergeHeads {
+		t.Error("unexpected error", err)
+	}
+
+	if err = c.Merge(up, m, []uint64{1, 1}, MergeAppend); err != ErrMergeHeads {
+		t.Error("unexpected error", err)
+	}
+
+	assertNil(t, c.Merge(up, m, []uint64{1, 2}, MergeAppend))
+
+	// the b is older, the a has Alice and Bob
+	var want = []registry.Dynamic{eva, alice, bob}
+
+	var lr *registry.Root
+	lr, err = c.LastRoot(pk, 1)
+	assertNil(t, err)
+
+	assertTrue(t, lr.Hash == m.Hash, "wrong last Root")
+	assertTrue(t, len(lr.Refs) == len(want), "wrong length of Refs")
+
+	for i, dr := range want {
+		assertTrue(t, lr.Refs[i] == dr, "wrong order of Refs")
+	}
+
+	assertTrue(t, len(lr.Merged) == 2 && lr.Merged[0] == a.Hash &&
+		lr.Merged[1] == b.Hash, "wrong Merged")
+
+}
@@ -1,6 +1,8 @@
 package skyobject
 
 import (
+	gocipher "crypto/cipher"
+
 	"github.com/skycoin/skycoin/src/cipher"
 
 	"github.com/skycoin/cxo/skyobject/registry"
@@ -12,6 +14,7 @@ type Pack struct {
 	c     *Container
 	deg   registry.Degree
 	flags registry.Flags
+	aead  gocipher.AEAD // ContentKey (see SetKey)
 }
 
 // Registry returns related registry
@@ -19,9 +22,12 @@ func (p *Pack) Registry() (reg *registry.Registry) {
 	return p.reg
 }
 
-// Get value by hash
+// Get value by hash, the Get opens sealed
+// objects if the Pack has a key (see SetKey)
 func (p *Pack) Get(key cipher.SHA256) (val []byte, err error) {
-	val, _, err = p.c.Get(key, 0)
+	if val, _, err = p.c.Get(key, 0); err == nil {
+		val = p.open(val)
+	}
 	return
 }
 
@@ -0,0 +1,205 @@
+package registry
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+// errors of delegations
+var (
+	ErrNotDelegated      = errors.New("signer of the Root is not delegated")
+	ErrInvalidDelegation = errors.New("invalid delegation set")
+)
+
+// A Delegation grants right to sign Root objects
+// of a feed to another key. The grant can be limited
+// by a head and by a time range of the Root objects
+type Delegation struct {
+	Delegate cipher.PubKey // key that can sign Root objects
+	Nonce    uint64        // head, zero for any head
+	From     int64         // start of time range (unix nano), zero for any
+	To       int64         // end of time range (exclusive), zero for any
+}
+
+// Allows returns true if the Delegation allows to sign
+// Root object of given head with given timestamp
+func (d *Delegation) Allows(nonce uint64, time int64) bool {
+
+	if d.Nonce != 0 && d.Nonce != nonce {
+		return false
+	}
+
+	if d.From != 0 && time < d.From {
+		return false
+	}
+
+	if d.To != 0 && time >= d.To {
+		return false
+	}
+
+	return true
+}
+
+// A DelegationSet is set of delegations of a feed signed
+// by owner of the feed. A Root object carries a DelegationSet
+// (see Delegations field of the Root) if the Root is signed
+// by a delegate key. Owner of a feed revokes delegations
+// publishing a Root with new DelegationSet that has greater
+// Version. The Container rejects delegated Root objects with
+// DelegationSet older then latest known
+type DelegationSet struct {
+	Feed        cipher.PubKey // the feed
+	Version     uint64        // greater version overrides older
+	Delegations []Delegation  // the grants
+
+	Sig cipher.Sig `enc:"-"` // signature of the feed owner
+}
+
+// encoded signed DelegationSet
+type signedDelegationSet struct {
+	Set []byte
+	Sig cipher.Sig
+}
+
+// Hash of the DelegationSet that is signed by owner
+func (d *DelegationSet) Hash() cipher.SHA256 {
+	return cipher.SumSHA256(encoder.Serialize(d))
+}
+
+// Sign the DelegationSet using secret key of the feed
+func (d *DelegationSet) Sign(sk cipher.SecKey) {
+	d.Sig = cipher.SignHash(d.Hash(), sk)
+}
+
+// Verify signature of the DelegationSet
+func (d *DelegationSet) Verify() (err error) {
+	if err = cipher.VerifySignature(d.Feed, d.Sig, d.Hash()); err != nil {
+		err = ErrInvalidDelegation
+	}
+	return
+}
+
+// Encode the DelegationSet with its signature
+func (d *DelegationSet) Encode() []byte {
+	return encoder.Serialize(signedDelegationSet{
+		Set: encoder.Serialize(d),
+		Sig: d.Sig,
+	})
+}
+
+// Allows returns Delegation that allows given
+// delegate to sign Root object of given head with
+// given timestamp, or nil if there is not such
+func (d *DelegationSet) Allows(
+	delegate cipher.PubKey, // : key
+	nonce uint64, //           : head
+	time int64, //             : timestamp of Root
+) (
+	dg *Delegation, //         : the delegation or nil
+) {
+
+	for i := range d.Delegations {
+		dg = &d.Delegations[i]
+		if dg.Delegate == delegate && dg.Allows(nonce, time) == true {
+			return
+		}
+	}
+
+	return nil
+}
+
+// DecodeDelegationSet decodes DelegationSet
+// encoded by the Encode method. It doesn't
+// verify signature of the DelegationSet
+func DecodeDelegationSet(p []byte) (d *DelegationSet, err error) {
+
+	var sds signedDelegationSet
+	if err = encoder.DeserializeRaw(p, &sds); err != nil {
+		return nil, ErrInvalidDelegation
+	}
+
+	d = new(DelegationSet)
+	if err = encoder.DeserializeRaw(sds.Set, d); err != nil {
+		return nil, ErrInvalidDelegation
+	}
+
+	d.Sig = sds.Sig
+	return
+}
+
+// DelegationSet of the Root, or nil if the
+// Root doesn't carry a DelegationSet. The
+// DelegationSet method verifies the set
+func (r *Root) DelegationSet() (d *DelegationSet, err error) {
+
+	if len(r.Delegations) == 0 {
+		return
+	}
+
+	if d, err = DecodeDelegationSet(r.Delegations); err != nil {
+		return
+	}
+
+	if d.Feed != r.Pub {
+		return nil, ErrInvalidDelegation
+	}
+
+	if err = d.Verify(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// SetDelegationSet sets encoded DelegationSet to
+// Delegations field of the Root. Use nil to remove
+func (r *Root) SetDelegationSet(d *DelegationSet) {
+	if d == nil {
+		r.Delegations = nil
+		return
+	}
+	r.Delegations = d.Encode()
+}
+
+// VerifySignature verifies signature of the Root. The Root
+// must have Hash and Sig fields. The Root can be signed by
+// owner of the feed or by a delegate key allowed by the
+// DelegationSet of the Root. The VerifySignature returns
+// the DelegationSet if the Root carries it, and public key
+// that signs the Root
+func (r *Root) VerifySignature() (
+	signer cipher.PubKey, // : owner or delegate
+	d *DelegationSet, //     : delegation set or nil
+	err error, //            : an error
+) {
+
+	var serr = cipher.VerifySignature(r.Pub, r.Sig, r.Hash)
+
+	if d, err = r.DelegationSet(); err != nil {
+		return
+	}
+
+	if serr == nil {
+		return r.Pub, d, nil // signed by owner
+	}
+
+	if d == nil {
+		return signer, nil, serr // not delegated
+	}
+
+	if signer, err = cipher.PubKeyFromSig(r.Sig, r.Hash); err != nil {
+		return signer, nil, serr
+	}
+
+	if d.Allows(signer, r.Nonce, r.Time) == nil {
+		return signer, nil, ErrNotDelegated
+	}
+
+	if err = cipher.VerifySignature(signer, r.Sig, r.Hash); err != nil {
+		return
+	}
+
+	return
+}
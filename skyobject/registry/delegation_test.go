@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+func testSignRoot(r *Root, sk cipher.SecKey) {
+	r.Hash = cipher.SumSHA256(r.Encode())
+	r.Sig = cipher.SignHash(r.Hash, sk)
+}
+
+func TestDelegation_Allows(t *testing.T) {
+
+	var d = Delegation{Nonce: 1, From: 10, To: 20}
+
+	for _, tt := range []struct {
+		nonce uint64
+		time  int64
+		allow bool
+	}{
+		{1, 10, true},
+		{1, 19, true},
+		{1, 9, false},
+		{1, 20, false},
+		{2, 15, false},
+	} {
+		if d.Allows(tt.nonce, tt.time) != tt.allow {
+			t.Error("wrong Allows", tt.nonce, tt.time)
+		}
+	}
+
+	if (&Delegation{}).Allows(100, 100) == false {
+		t.Error("blank Delegation doesn't allow")
+	}
+
+}
+
+func TestDelegationSet_Encode(t *testing.T) {
+
+	var (
+		pk, sk = cipher.GenerateKeyPair()
+		dpk, _ = cipher.GenerateKeyPair()
+
+		d = DelegationSet{
+			Feed:        pk,
+			Version:     2,
+			Delegations: []Delegation{{Delegate: dpk, Nonce: 1}},
+		}
+	)
+
+	d.Sign(sk)
+
+	if err := d.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	var dd, err = DecodeDelegationSet(d.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = dd.Verify(); err != nil {
+		t.Error(err)
+	}
+
+	if dd.Version != 2 || dd.Allows(dpk, 1, 0) == nil {
+		t.Error("wrong DelegationSet")
+	}
+
+	if dd.Allows(dpk, 2, 0) != nil || dd.Allows(pk, 1, 0) != nil {
+		t.Error("allows too much")
+	}
+
+	dd.Version++ // modify
+
+	if err = dd.Verify(); err != ErrInvalidDelegation {
+		t.Error("unexpected error", err)
+	}
+
+}
+
+func TestRoot_VerifySignature(t *testing.T) {
+
+	var (
+		pk, sk   = cipher.GenerateKeyPair()
+		dpk, dsk = cipher.GenerateKeyPair()
+		_, usk   = cipher.GenerateKeyPair()
+
+		d = &DelegationSet{
+			Feed:        pk,
+			Version:     1,
+			Delegations: []Delegation{{Delegate: dpk, Nonce: 1, To: 100}},
+		}
+
+		r = &Root{Pub: pk, Nonce: 1, Time: 10}
+	)
+
+	d.Sign(sk)
+
+	// owner
+
+	testSignRoot(r, sk)
+
+	var signer, dd, err = r.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signer != pk || dd != nil {
+		t.Error("wrong signer or DelegationSet")
+	}
+
+	// not delegated
+
+	testSignRoot(r, dsk)
+
+	if _, _, err = r.VerifySignature(); err == nil {
+		t.Error("missing error")
+	}
+
+	// delegated
+
+	r.SetDelegationSet(d)
+	testSignRoot(r, dsk)
+
+	if signer, dd, err = r.VerifySignature(); err != nil {
+		t.Fatal(err)
+	}
+
+	if signer != dpk || dd == nil || dd.Version != 1 {
+		t.Error("wrong signer or DelegationSet")
+	}
+
+	// out of time range
+
+	r.Time = 100
+	testSignRoot(r, dsk)
+
+	if _, _, err = r.VerifySignature(); err != ErrNotDelegated {
+		t.Error("unexpected error", err)
+	}
+
+	// unknown key
+
+	r.Time = 10
+	testSignRoot(r, usk)
+
+	if _, _, err = r.VerifySignature(); err != ErrNotDelegated {
+		t.Error("unexpected error", err)
+	}
+
+	// DelegationSet of another feed
+
+	var od = *d
+	od.Feed = dpk
+	od.Sign(dsk)
+
+	r.SetDelegationSet(&od)
+	testSignRoot(r, dsk)
+
+	if _, _, err = r.VerifySignature(); err != ErrInvalidDelegation {
+		t.Error("unexpected error", err)
+	}
+
+}
+
+func TestDecodeRoot_legacy(t *testing.T) {
+
+	var (
+		pk, _ = cipher.GenerateKeyPair()
+		v1    = rootV1{Pub: pk, Nonce: 1, Seq: 2, Descriptor: []byte("d")}
+	)
+
+	var r, err = DecodeRoot(encoder.Serialize(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Pub != pk || r.Nonce != 1 || r.Seq != 2 || string(r.Descriptor) != "d" {
+		t.Error("wrong Root", r)
+	}
+
+	if r.Delegations != nil {
+		t.Error("unexpected Delegations")
+	}
+
+	var v2 = rootV2{Pub: pk, Nonce: 1, Delegations: []byte("d")}
+
+	if r, err = DecodeRoot(encoder.Serialize(v2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Pub != pk || string(r.Delegations) != "d" || r.Merged != nil {
+		t.Error("wrong Root", r)
+	}
+
+}
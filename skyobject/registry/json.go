@@ -0,0 +1,1043 @@
+package registry
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+// JSON representation of CX objects
+//
+// The ToJSON and FromJSON functions convert encoded CX objects
+// to generic JSON documents and back using schemas of a Registry.
+// A document consist of map[string]interface{}, []interface{},
+// string, bool and numbers, and can be marshalled using the
+// encoding/json package. The FromJSON accepts documents
+// unmarshalled by the encoding/json package (use UseNumber of
+// json.Decoder to keep big integers). Objects represented as
+//
+//     bool, integers, floats, string    as is
+//     []byte                            hex encoded string
+//     arrays and slices                 JSON array
+//     struct                            JSON object {"Field": value}
+//
+//     Ref     {"hash": "hex", "value": element}
+//     Refs    {"hash": "hex", "length": n, "values": [elements]}
+//     Dynamic {"schema": "hex", "hash": "hex", "value": object}
+//
+// Blank references are null. The "value" and "values" are
+// present only if the references are expanded (see depth
+// argument of the ToJSON). The FromJSON uses "value" and
+// "values" if they are present, saving elements using the
+// Pack.Add, otherwise the "hash" is used. The "schema" of a
+// Dynamic can be a name of registered type too. A field of
+// a struct or element of an array can be omitted (or null)
+// in a document for the FromJSON, in this case zero value
+// is used
+
+// ToJSON converts given encoded object of given Schema
+// to generic JSON document. The depth is number of
+// levels of references to expand. Use zero to not
+// expand references, and negative number to expand
+// all references
+func ToJSON(
+	pack Pack, //          : pack to get referenced objects
+	sch Schema, //         : schema of the object
+	val []byte, //         : encoded object
+	depth int, //          : depth of references expansion
+) (
+	doc interface{}, //    : the document
+	err error, //          : an error
+) {
+
+	if doc, _, err = toJSON(pack, sch, val, depth); err != nil {
+		err = fmt.Errorf("can't convert %s to JSON: %v", sch.String(), err)
+	}
+
+	return
+}
+
+// FromJSON converts given generic JSON document to
+// encoded object of given Schema. The document can be
+// created by the ToJSON or unmarshalled using the
+// encoding/json package. Values of references (if any)
+// are saved using the Pack.Add. The result is not
+// saved. Use the AddJSON to save it
+func FromJSON(
+	pack Pack, //          : pack to save referenced objects
+	sch Schema, //         : schema of the object
+	doc interface{}, //    : the document
+) (
+	val []byte, //         : encoded object
+	err error, //          : an error
+) {
+
+	if val, err = fromJSON(pack, sch, doc); err != nil {
+		err = fmt.Errorf("can't convert JSON to %s: %v", sch.String(), err)
+	}
+
+	return
+}
+
+// AddJSON is FromJSON and Pack.Add (or Sealer.Seal,
+// see Sealer)
+func AddJSON(
+	pack Pack,
+	sch Schema,
+	doc interface{},
+) (
+	hash cipher.SHA256,
+	err error,
+) {
+
+	var val []byte
+	if val, err = FromJSON(pack, sch, doc); err != nil {
+		return
+	}
+
+	return addValue(pack, val, sch.HasReferences())
+}
+
+// JSON returns generic JSON document of the Root. The
+// Refs of the Root are Dynamic references (see ToJSON
+// for the depth)
+func (r *Root) JSON(pack Pack, depth int) (doc map[string]interface{},
+	err error) {
+
+	var refs = make([]interface{}, 0, len(r.Refs))
+
+	for i := range r.Refs {
+
+		var dr interface{}
+		if dr, err = dynamicToJSON(pack, &r.Refs[i], depth); err != nil {
+			return
+		}
+
+		refs = append(refs, dr)
+	}
+
+	var merged = make([]string, 0, len(r.Merged))
+
+	for _, hash := range r.Merged {
+		merged = append(merged, hash.Hex())
+	}
+
+	doc = map[string]interface{}{
+		"hash":       r.Hash.Hex(),
+		"sig":        r.Sig.Hex(),
+		"pub":        r.Pub.Hex(),
+		"nonce":      r.Nonce,
+		"seq":        r.Seq,
+		"time":       r.Time,
+		"prev":       hashToJSON(r.Prev),
+		"reg":        r.Reg.String(),
+		"descriptor": hex.EncodeToString(r.Descriptor),
+		"refs":       refs,
+		"merged":     merged,
+	}
+
+	return
+}
+
+func hashToJSON(hash cipher.SHA256) string {
+	if hash == (cipher.SHA256{}) {
+		return ""
+	}
+	return hash.Hex()
+}
+
+func hashFromJSON(doc interface{}) (hash cipher.SHA256, err error) {
+
+	switch x := doc.(type) {
+	case nil:
+		return
+	case string:
+		if x == "" {
+			return
+		}
+		return cipher.SHA256FromHex(x)
+	}
+
+	err = fmt.Errorf("invalid hash %v (%T)", doc, doc)
+	return
+}
+
+func toJSON(
+	pack Pack,
+	sch Schema,
+	val []byte,
+	depth int,
+) (
+	doc interface{},
+	n int, // used by the object
+	err error,
+) {
+
+	if n, err = sch.Size(val); err != nil {
+		return
+	}
+
+	val = val[:n]
+
+	if sch.IsReference() == true {
+		doc, err = referenceToJSON(pack, sch, val, depth)
+		return
+	}
+
+	switch sch.Kind() {
+
+	case reflect.Bool:
+
+		var x bool
+		err = encoder.DeserializeRaw(val, &x)
+		doc = x
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+
+		var x int64
+		switch sch.Kind() {
+		case reflect.Int8:
+			x = int64(int8(val[0]))
+		case reflect.Int16:
+			var y int16
+			err = encoder.DeserializeRaw(val, &y)
+			x = int64(y)
+		case reflect.Int32:
+			var y int32
+			err = encoder.DeserializeRaw(val, &y)
+			x = int64(y)
+		default:
+			err = encoder.DeserializeRaw(val, &x)
+		}
+		doc = x
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+
+		var x uint64
+		switch sch.Kind() {
+		case reflect.Uint8:
+			x = uint64(val[0])
+		case reflect.Uint16:
+			var y uint16
+			err = encoder.DeserializeRaw(val, &y)
+			x = uint64(y)
+		case reflect.Uint32:
+			var y uint32
+			err = encoder.DeserializeRaw(val, &y)
+			x = uint64(y)
+		default:
+			err = encoder.DeserializeRaw(val, &x)
+		}
+		doc = x
+
+	case reflect.Float32:
+
+		var x float32
+		err = encoder.DeserializeRaw(val, &x)
+		doc = float64(x)
+
+	case reflect.Float64:
+
+		var x float64
+		err = encoder.DeserializeRaw(val, &x)
+		doc = x
+
+	case reflect.String:
+
+		var x string
+		err = encoder.DeserializeRaw(val, &x)
+		doc = x
+
+	case reflect.Array, reflect.Slice:
+
+		doc, err = sliceToJSON(pack, sch, val, depth)
+
+	case reflect.Struct:
+
+		doc, err = structToJSON(pack, sch, val, depth)
+
+	default:
+
+		err = fmt.Errorf("invalid Kind <%s> of Schema %q", sch.Kind(),
+			sch.String())
+
+	}
+
+	return
+}
+
+func sliceToJSON(
+	pack Pack,
+	sch Schema,
+	val []byte,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	var el = sch.Elem()
+
+	if el == nil {
+		return nil, fmt.Errorf("invalid schema %q: nil-element", sch.String())
+	}
+
+	// special case for []byte
+	if sch.Kind() == reflect.Slice && el.Kind() == reflect.Uint8 &&
+		el.IsReference() == false {
+
+		var x []byte
+		if err = encoder.DeserializeRaw(val, &x); err != nil {
+			return
+		}
+
+		return hex.EncodeToString(x), nil
+	}
+
+	var ln, shift int
+
+	if sch.Kind() == reflect.Array {
+		ln = sch.Len()
+	} else {
+		if ln, err = getLength(val); err != nil {
+			return
+		}
+		shift = 4
+	}
+
+	var elems = make([]interface{}, 0, ln)
+
+	for i := 0; i < ln; i++ {
+
+		var (
+			elem interface{}
+			n    int
+		)
+
+		if elem, n, err = toJSON(pack, el, val[shift:], depth); err != nil {
+			return
+		}
+
+		elems = append(elems, elem)
+		shift += n
+	}
+
+	return elems, nil
+}
+
+func structToJSON(
+	pack Pack,
+	sch Schema,
+	val []byte,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	var (
+		fields = make(map[string]interface{}, len(sch.Fields()))
+		shift  int
+	)
+
+	for _, f := range sch.Fields() {
+
+		var (
+			field interface{}
+			n     int
+		)
+
+		if field, n, err = toJSON(pack, f.Schema(), val[shift:],
+			depth); err != nil {
+
+			return nil, fmt.Errorf("field %q: %v", f.Name(), err)
+		}
+
+		fields[f.Name()] = field
+		shift += n
+	}
+
+	return fields, nil
+}
+
+func referenceToJSON(
+	pack Pack,
+	sch Schema,
+	val []byte,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	switch rt := sch.ReferenceType(); rt {
+
+	case ReferenceTypeSingle:
+
+		var ref Ref
+		if err = encoder.DeserializeRaw(val, &ref); err != nil {
+			return
+		}
+
+		return refToJSON(pack, sch.Elem(), &ref, depth)
+
+	case ReferenceTypeSlice:
+
+		var refs Refs
+		if err = encoder.DeserializeRaw(val, &refs); err != nil {
+			return
+		}
+
+		return refsToJSON(pack, sch.Elem(), &refs, depth)
+
+	case ReferenceTypeDynamic:
+
+		var dr Dynamic
+		if err = encoder.DeserializeRaw(val, &dr); err != nil {
+			return
+		}
+
+		return dynamicToJSON(pack, &dr, depth)
+
+	default:
+
+		err = fmt.Errorf("invalid schema (%s): reference with invalid type %d",
+			sch.String(), rt)
+
+	}
+
+	return
+}
+
+func hashToJSONValue(
+	pack Pack,
+	sch Schema,
+	hash cipher.SHA256,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	var val []byte
+	if val, err = pack.Get(hash); err != nil {
+		return
+	}
+
+	doc, _, err = toJSON(pack, sch, val, depth)
+	return
+}
+
+func refToJSON(
+	pack Pack,
+	el Schema,
+	ref *Ref,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	if el == nil {
+		return nil, ErrInvalidSchema
+	}
+
+	if ref.IsBlank() == true {
+		return // null
+	}
+
+	var m = map[string]interface{}{
+		"hash": ref.Hash.Hex(),
+	}
+
+	if depth != 0 {
+		if m["value"], err = hashToJSONValue(pack, el, ref.Hash,
+			depth-1); err != nil {
+
+			return
+		}
+	}
+
+	return m, nil
+}
+
+func refsToJSON(
+	pack Pack,
+	el Schema,
+	refs *Refs,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	if el == nil {
+		return nil, ErrInvalidSchema
+	}
+
+	if refs.Hash == (cipher.SHA256{}) {
+		return // null
+	}
+
+	var ln int
+	if ln, err = refs.Len(pack); err != nil {
+		return
+	}
+
+	var m = map[string]interface{}{
+		"hash":   refs.Hash.Hex(),
+		"length": ln,
+	}
+
+	if depth != 0 {
+
+		var values = make([]interface{}, 0, ln)
+
+		err = refs.Ascend(pack, func(_ int, hash cipher.SHA256) (err error) {
+
+			var value interface{}
+			if value, err = hashToJSONValue(pack, el, hash,
+				depth-1); err != nil {
+
+				return
+			}
+
+			values = append(values, value)
+			return
+		})
+
+		if err != nil {
+			return
+		}
+
+		m["values"] = values
+	}
+
+	return m, nil
+}
+
+func dynamicToJSON(
+	pack Pack,
+	dr *Dynamic,
+	depth int,
+) (
+	doc interface{},
+	err error,
+) {
+
+	if dr.IsValid() == false {
+		return nil, ErrInvalidDynamicReference
+	}
+
+	if dr.IsBlank() == true {
+		return // null
+	}
+
+	var m = map[string]interface{}{
+		"schema": dr.Schema.String(),
+		"hash":   hashToJSON(dr.Hash),
+	}
+
+	if depth != 0 && dr.Hash != (cipher.SHA256{}) {
+
+		var reg = pack.Registry()
+
+		if reg == nil {
+			return nil, ErrMissingRegistry
+		}
+
+		var sch Schema
+		if sch, err = reg.SchemaByReference(dr.Schema); err != nil {
+			return
+		}
+
+		if m["value"], err = hashToJSONValue(pack, sch, dr.Hash,
+			depth-1); err != nil {
+
+			return
+		}
+
+	}
+
+	return m, nil
+}
+
+func fromJSON(pack Pack, sch Schema, doc interface{}) (val []byte, err error) {
+
+	if sch.IsReference() == true {
+		return referenceFromJSON(pack, sch, doc)
+	}
+
+	switch kind := sch.Kind(); kind {
+
+	case reflect.Bool:
+
+		var x bool
+		if doc != nil {
+			var ok bool
+			if x, ok = doc.(bool); ok == false {
+				return nil, fmt.Errorf("invalid bool %v (%T)", doc, doc)
+			}
+		}
+		val = encoder.Serialize(x)
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+
+		var x int64
+		if x, err = intFromJSON(doc, kind); err != nil {
+			return
+		}
+
+		switch kind {
+		case reflect.Int8:
+			val = encoder.Serialize(int8(x))
+		case reflect.Int16:
+			val = encoder.Serialize(int16(x))
+		case reflect.Int32:
+			val = encoder.Serialize(int32(x))
+		default:
+			val = encoder.Serialize(x)
+		}
+
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+
+		var x uint64
+		if x, err = uintFromJSON(doc, kind); err != nil {
+			return
+		}
+
+		switch kind {
+		case reflect.Uint8:
+			val = encoder.Serialize(uint8(x))
+		case reflect.Uint16:
+			val = encoder.Serialize(uint16(x))
+		case reflect.Uint32:
+			val = encoder.Serialize(uint32(x))
+		default:
+			val = encoder.Serialize(x)
+		}
+
+	case reflect.Float32, reflect.Float64:
+
+		var x float64
+		if x, err = floatFromJSON(doc); err != nil {
+			return
+		}
+
+		if kind == reflect.Float32 {
+			val = encoder.Serialize(float32(x))
+		} else {
+			val = encoder.Serialize(x)
+		}
+
+	case reflect.String:
+
+		var x string
+		if doc != nil {
+			var ok bool
+			if x, ok = doc.(string); ok == false {
+				return nil, fmt.Errorf("invalid string %v (%T)", doc, doc)
+			}
+		}
+		val = encoder.Serialize(x)
+
+	case reflect.Array, reflect.Slice:
+
+		return sliceFromJSON(pack, sch, doc)
+
+	case reflect.Struct:
+
+		return structFromJSON(pack, sch, doc)
+
+	default:
+
+		err = fmt.Errorf("invalid Kind <%s> of Schema %q", kind, sch.String())
+
+	}
+
+	return
+}
+
+func numberFromJSON(doc interface{}) (s string, err error) {
+
+	switch x := doc.(type) {
+	case nil:
+		return "0", nil
+	case json.Number:
+		return x.String(), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case int:
+		return strconv.FormatInt(int64(x), 10), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case uint64:
+		return strconv.FormatUint(x, 10), nil
+	}
+
+	err = fmt.Errorf("invalid number %v (%T)", doc, doc)
+	return
+}
+
+func bitSize(kind reflect.Kind) int {
+	return fixedSize(kind) * 8
+}
+
+func intFromJSON(doc interface{}, kind reflect.Kind) (x int64, err error) {
+
+	var s string
+	if s, err = numberFromJSON(doc); err != nil {
+		return
+	}
+
+	return strconv.ParseInt(s, 10, bitSize(kind))
+}
+
+func uintFromJSON(doc interface{}, kind reflect.Kind) (x uint64, err error) {
+
+	var s string
+	if s, err = numberFromJSON(doc); err != nil {
+		return
+	}
+
+	return strconv.ParseUint(s, 10, bitSize(kind))
+}
+
+func floatFromJSON(doc interface{}) (x float64, err error) {
+
+	var s string
+	if s, err = numberFromJSON(doc); err != nil {
+		return
+	}
+
+	if x, err = strconv.ParseFloat(s, 64); err == nil && math.IsNaN(x) {
+		err = fmt.Errorf("invalid float %s", s)
+	}
+
+	return
+}
+
+func sliceFromJSON(
+	pack Pack,
+	sch Schema,
+	doc interface{},
+) (
+	val []byte,
+	err error,
+) {
+
+	var el = sch.Elem()
+
+	if el == nil {
+		return nil, fmt.Errorf("invalid schema %q: nil-element", sch.String())
+	}
+
+	// special case for []byte
+	if sch.Kind() == reflect.Slice && el.Kind() == reflect.Uint8 &&
+		el.IsReference() == false {
+
+		var x []byte
+		switch y := doc.(type) {
+		case nil:
+		case string:
+			if x, err = hex.DecodeString(y); err != nil {
+				return
+			}
+		default:
+			return nil, fmt.Errorf("invalid []byte %v (%T)", doc, doc)
+		}
+
+		return encoder.Serialize(x), nil
+	}
+
+	var elems []interface{}
+
+	switch x := doc.(type) {
+	case nil:
+	case []interface{}:
+		elems = x
+	default:
+		return nil, fmt.Errorf("invalid array %v (%T)", doc, doc)
+	}
+
+	var ln = len(elems)
+
+	if sch.Kind() == reflect.Array {
+		if ln > sch.Len() {
+			return nil, fmt.Errorf("too many elements of array: %d, want %d",
+				ln, sch.Len())
+		}
+		ln = sch.Len() // fill by zeroes
+	} else {
+		val = encoder.Serialize(uint32(ln))
+	}
+
+	for i := 0; i < ln; i++ {
+
+		var elem interface{}
+		if i < len(elems) {
+			elem = elems[i]
+		}
+
+		var p []byte
+		if p, err = fromJSON(pack, el, elem); err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+
+		val = append(val, p...)
+	}
+
+	return
+}
+
+func structFromJSON(
+	pack Pack,
+	sch Schema,
+	doc interface{},
+) (
+	val []byte,
+	err error,
+) {
+
+	var fields map[string]interface{}
+
+	switch x := doc.(type) {
+	case nil:
+	case map[string]interface{}:
+		fields = x
+	default:
+		return nil, fmt.Errorf("invalid object %v (%T)", doc, doc)
+	}
+
+	var known int
+
+	for _, f := range sch.Fields() {
+
+		var field, ok = fields[f.Name()]
+
+		if ok == true {
+			known++
+		}
+
+		var p []byte
+		if p, err = fromJSON(pack, f.Schema(), field); err != nil {
+			return nil, fmt.Errorf("field %q: %v", f.Name(), err)
+		}
+
+		val = append(val, p...)
+	}
+
+	if known != len(fields) {
+
+	FieldsLoop:
+		for name := range fields {
+			for _, f := range sch.Fields() {
+				if f.Name() == name {
+					continue FieldsLoop
+				}
+			}
+			return nil, fmt.Errorf("%v: %q", ErrNoSuchField, name)
+		}
+
+	}
+
+	return
+}
+
+// get {"hash": "", "value": ...} (and other)
+func referenceDocument(doc interface{}) (m map[string]interface{},
+	err error) {
+
+	switch x := doc.(type) {
+	case nil:
+	case map[string]interface{}:
+		m = x
+	default:
+		err = fmt.Errorf("invalid reference %v (%T)", doc, doc)
+	}
+
+	return
+}
+
+func referenceFromJSON(
+	pack Pack,
+	sch Schema,
+	doc interface{},
+) (
+	val []byte,
+	err error,
+) {
+
+	var m map[string]interface{}
+	if m, err = referenceDocument(doc); err != nil {
+		return
+	}
+
+	switch rt := sch.ReferenceType(); rt {
+
+	case ReferenceTypeSingle:
+
+		var ref Ref
+		if ref, err = refFromJSON(pack, sch.Elem(), m); err != nil {
+			return
+		}
+
+		return encoder.Serialize(ref), nil
+
+	case ReferenceTypeSlice:
+
+		var refs Refs
+		if err = refsFromJSON(pack, sch.Elem(), m, &refs); err != nil {
+			return
+		}
+
+		return encoder.Serialize(&refs), nil
+
+	case ReferenceTypeDynamic:
+
+		var dr Dynamic
+		if dr, err = dynamicFromJSON(pack, m); err != nil {
+			return
+		}
+
+		return encoder.Serialize(dr), nil
+
+	default:
+
+		err = fmt.Errorf("invalid schema (%s): reference with invalid type %d",
+			sch.String(), rt)
+
+	}
+
+	return
+}
+
+// use "value", or "hash"
+func hashFromJSONValue(
+	pack Pack,
+	sch Schema,
+	m map[string]interface{},
+) (
+	hash cipher.SHA256,
+	err error,
+) {
+
+	if value, ok := m["value"]; ok == true && value != nil {
+		return AddJSON(pack, sch, value)
+	}
+
+	return hashFromJSON(m["hash"])
+}
+
+func refFromJSON(
+	pack Pack,
+	el Schema,
+	m map[string]interface{},
+) (
+	ref Ref,
+	err error,
+) {
+
+	if el == nil {
+		err = ErrInvalidSchema
+		return
+	}
+
+	ref.Hash, err = hashFromJSONValue(pack, el, m)
+	return
+}
+
+func refsFromJSON(
+	pack Pack,
+	el Schema,
+	m map[string]interface{},
+	refs *Refs,
+) (
+	err error,
+) {
+
+	if el == nil {
+		return ErrInvalidSchema
+	}
+
+	var values, ok = m["values"]
+
+	if ok == false || values == nil {
+		refs.Hash, err = hashFromJSON(m["hash"])
+		return
+	}
+
+	var elems []interface{}
+	if elems, ok = values.([]interface{}); ok == false {
+		return fmt.Errorf("invalid values of Refs %v (%T)", values, values)
+	}
+
+	var hashes = make([]cipher.SHA256, 0, len(elems))
+
+	for i, elem := range elems {
+
+		var hash cipher.SHA256
+		if hash, err = AddJSON(pack, el, elem); err != nil {
+			return fmt.Errorf("element %d: %v", i, err)
+		}
+
+		hashes = append(hashes, hash)
+	}
+
+	return refs.AppendHashes(pack, hashes...)
+}
+
+func dynamicFromJSON(
+	pack Pack,
+	m map[string]interface{},
+) (
+	dr Dynamic,
+	err error,
+) {
+
+	if m == nil {
+		return // blank
+	}
+
+	var name, ok = m["schema"].(string)
+
+	if ok == false || name == "" {
+		err = fmt.Errorf("%v: missing schema", ErrInvalidDynamicReference)
+		return
+	}
+
+	var reg = pack.Registry()
+
+	if reg == nil {
+		err = ErrMissingRegistry
+		return
+	}
+
+	// name or reference
+
+	var sch Schema
+	if sch, err = reg.SchemaByName(name); err != nil {
+
+		var hash cipher.SHA256
+		if hash, err = cipher.SHA256FromHex(name); err != nil {
+			err = fmt.Errorf("%v: unknown schema %q",
+				ErrInvalidDynamicReference, name)
+			return
+		}
+
+		if sch, err = reg.SchemaByReference(SchemaRef(hash)); err != nil {
+			return
+		}
+
+	}
+
+	dr.Schema = sch.Reference()
+	dr.Hash, err = hashFromJSONValue(pack, sch, m)
+	return
+}
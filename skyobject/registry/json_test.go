@@ -0,0 +1,237 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+// marshal and unmarshal the document
+func testJSONRemarshal(t *testing.T, doc interface{}) (rdoc interface{}) {
+	t.Helper()
+
+	var p, err = json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dec = json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+
+	if err = dec.Decode(&rdoc); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func testJSONGroup(t *testing.T, pack Pack) (val []byte) {
+	t.Helper()
+
+	var group = TestGroup{Name: "the CXO"}
+
+	if err := group.Members.AppendValues(pack, getTestUsers(5)...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := group.Curator.SetValue(pack, TestUser{Name: "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var sch, err = pack.Registry().SchemaByName("test.Man")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = group.Developer.SetValue(pack, TestMan{"kostyarin",
+		"logrusorgru"}); err != nil {
+
+		t.Fatal(err)
+	}
+
+	group.Developer.Schema = sch.Reference()
+
+	return encoder.Serialize(group)
+}
+
+func TestToJSON(t *testing.T) {
+
+	var (
+		pack = getTestPack()
+		reg  = pack.Registry()
+	)
+
+	t.Run("round trip", func(t *testing.T) {
+
+		for _, tt := range testTypes() {
+
+			var sch, err = reg.SchemaByName(tt.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var val = encoder.Serialize(tt.Val)
+
+			var doc interface{}
+			if doc, err = ToJSON(pack, sch, val, -1); err != nil {
+				t.Fatal(tt.Name, err)
+			}
+
+			var rval []byte
+			rval, err = FromJSON(pack, sch, testJSONRemarshal(t, doc))
+			if err != nil {
+				t.Fatal(tt.Name, err)
+			}
+
+			if bytes.Equal(val, rval) == false {
+				t.Error("wrong value of", tt.Name)
+			}
+
+		}
+
+	})
+
+	t.Run("references", func(t *testing.T) {
+
+		var (
+			val      = testJSONGroup(t, pack)
+			sch, err = reg.SchemaByName("test.Group")
+		)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// not expanded
+
+		var doc interface{}
+		if doc, err = ToJSON(pack, sch, val, 0); err != nil {
+			t.Fatal(err)
+		}
+
+		var curator = doc.(map[string]interface{})["Curator"]
+
+		if _, ok := curator.(map[string]interface{})["value"]; ok == true {
+			t.Error("expanded")
+		}
+
+		// expanded
+
+		if doc, err = ToJSON(pack, sch, val, -1); err != nil {
+			t.Fatal(err)
+		}
+
+		var group = doc.(map[string]interface{})
+
+		curator = group["Curator"].(map[string]interface{})["value"]
+
+		if name := curator.(map[string]interface{})["Name"]; name != "Bob" {
+			t.Error("wrong name of Curator:", name)
+		}
+
+		var members = group["Members"].(map[string]interface{})
+
+		if ln := members["length"]; ln != 5 {
+			t.Error("wrong length of Members:", ln)
+		}
+
+		if vs := members["values"].([]interface{}); len(vs) != 5 {
+			t.Error("wrong number of Members:", len(vs))
+		}
+
+		// and back
+
+		var rval []byte
+		if rval, err = FromJSON(pack, sch, testJSONRemarshal(t, doc)); err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Equal(val, rval) == false {
+			t.Error("wrong value")
+		}
+
+	})
+
+}
+
+func TestAddJSON(t *testing.T) {
+
+	var (
+		pack = getTestPack()
+		reg  = pack.Registry()
+	)
+
+	var sch, err = reg.SchemaByName("test.Group")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc interface{}
+	err = json.Unmarshal([]byte(`{
+		"Name": "the CXO",
+		"Members": {"values": [{"Name": "Alice", "Age": 21}]},
+		"Developer": {
+			"schema": "test.Man",
+			"value": {"Name": "kostyarin", "GitHub": "logrusorgru"}
+		}
+	}`), &doc)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hash cipher.SHA256
+	if hash, err = AddJSON(pack, sch, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var group TestGroup
+	if err = get(pack, hash, &group); err != nil {
+		t.Fatal(err)
+	}
+
+	if group.Curator.IsBlank() == false {
+		t.Error("not blank Curator")
+	}
+
+	var usr TestUser
+	if _, err = group.Members.ValueByIndex(pack, 0, &usr); err != nil {
+		t.Fatal(err)
+	}
+
+	if usr.Name != "Alice" || usr.Age != 21 {
+		t.Error("wrong member", usr)
+	}
+
+	var man TestMan
+	if err = group.Developer.Value(pack, &man); err != nil {
+		t.Fatal(err)
+	}
+
+	if man.GitHub != "logrusorgru" {
+		t.Error("wrong developer", man)
+	}
+
+	// errors
+
+	for _, invalid := range []string{
+		`{"Unknown": "field"}`,
+		`{"Name": 10}`,
+		`{"Members": {"values": [{"Age": -1}]}}`,
+		`{"Developer": {"schema": "test.Unknown"}}`,
+	} {
+
+		if err = json.Unmarshal([]byte(invalid), &doc); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = AddJSON(pack, sch, doc); err == nil {
+			t.Error("missing error:", invalid)
+		}
+
+	}
+
+}
@@ -62,7 +62,7 @@ func (r *Ref) SetValue(
 	}
 
 	var hash cipher.SHA256
-	if hash, err = pack.Add(encoder.Serialize(obj)); err != nil {
+	if hash, err = addObject(pack, obj); err != nil {
 		return
 	}
 
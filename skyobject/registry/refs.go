@@ -652,7 +652,7 @@ func (r *Refs) SetValueByIndex(
 	var hash cipher.SHA256
 
 	if isNil(obj) == false {
-		if hash, err = pack.Add(encoder.Serialize(obj)); err != nil {
+		if hash, err = addObject(pack, obj); err != nil {
 			return
 		}
 	}
@@ -1504,7 +1504,7 @@ func (r *Refs) AppendValues(
 
 		} else {
 
-			if hash, err = pack.Add(encoder.Serialize(val)); err != nil {
+			if hash, err = addObject(pack, val); err != nil {
 				return
 			}
 
@@ -119,6 +119,23 @@ func (r *Registry) SchemaByName(name string) (Schema, error) {
 	return r.schemaByName(name)
 }
 
+// Schemas returns all registered schemas
+// of the Registry ordered by name
+func (r *Registry) Schemas() (ss []Schema) {
+
+	ss = make([]Schema, 0, len(r.reg))
+
+	for _, sch := range r.reg {
+		ss = append(ss, sch)
+	}
+
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].Name() < ss[j].Name()
+	})
+
+	return
+}
+
 // Types returns Types of the Registry. If this registry creaded using
 // DecodeRegistry (received from network) then result will not
 // be valid (empty maps). The Types used to pack/unpack CX objects
@@ -336,6 +336,25 @@ func TestRegistry_SchemaByReference(t *testing.T) {
 
 }
 
+func TestRegistry_Schemas(t *testing.T) {
+
+	var reg = NewRegistry(func(r *Reg) {
+		r.Register("test.User", TestUser{})
+		r.Register("test.Group", TestGroup{})
+	})
+
+	var ss = reg.Schemas()
+
+	if len(ss) != 2 {
+		t.Fatal("wrong number of schemas", len(ss))
+	}
+
+	if ss[0].Name() != "test.Group" || ss[1].Name() != "test.User" {
+		t.Error("wrong order", ss)
+	}
+
+}
+
 func TestRegistry_Encode(t *testing.T) {
 	//
 }
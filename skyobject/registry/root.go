@@ -33,6 +33,17 @@ type Root struct {
 	// means the Root is first in chain
 	Prev cipher.SHA256
 
+	// Delegations is encoded DelegationSet if the Root
+	// signed by a delegate key, or if owner of the feed
+	// publishes new DelegationSet. It's nil otherwise
+	// (see DelegationSet and SetDelegationSet methods)
+	Delegations []byte
+
+	// Merged is list of hashes of last Root objects
+	// of heads merged by this Root. It's nil if the
+	// Root is not a merge (see skyobject.Merge)
+	Merged []cipher.SHA256
+
 	// IsFull means that this Root object
 	// has been successfully colelcted by this
 	// machine. E.g. this field is not part
@@ -78,13 +89,72 @@ func (r *Root) String() string {
 		r.Hash.Hex()[:7])
 }
 
-// DecodeRoot decodes and encoded Root object
+// Root without the Delegations and Merged fields
+type rootV1 struct {
+	Refs       []Dynamic
+	Descriptor []byte
+	Reg        RegistryRef
+	Pub        cipher.PubKey
+	Nonce      uint64
+	Seq        uint64
+	Time       int64
+	Prev       cipher.SHA256
+}
+
+func (v *rootV1) root() (r *Root) {
+	return &Root{
+		Refs:       v.Refs,
+		Descriptor: v.Descriptor,
+		Reg:        v.Reg,
+		Pub:        v.Pub,
+		Nonce:      v.Nonce,
+		Seq:        v.Seq,
+		Time:       v.Time,
+		Prev:       v.Prev,
+	}
+}
+
+// Root without the Merged field
+type rootV2 struct {
+	Refs        []Dynamic
+	Descriptor  []byte
+	Reg         RegistryRef
+	Pub         cipher.PubKey
+	Nonce       uint64
+	Seq         uint64
+	Time        int64
+	Prev        cipher.SHA256
+	Delegations []byte
+}
+
+func (v *rootV2) root() (r *Root) {
+	r = (&rootV1{v.Refs, v.Descriptor, v.Reg, v.Pub, v.Nonce, v.Seq,
+		v.Time, v.Prev}).root()
+	r.Delegations = v.Delegations
+	return
+}
+
+// DecodeRoot decodes and encoded Root object. The
+// DecodeRoot decodes Root objects encoded before
+// the Delegations and the Merged fields were
+// introduced too
 func DecodeRoot(val []byte) (r *Root, err error) {
 	r = new(Root)
-	if err = encoder.DeserializeRaw(val, r); err != nil {
-		r = nil
+	if err = encoder.DeserializeRaw(val, r); err == nil {
+		return
 	}
-	return
+
+	var v2 rootV2
+	if encoder.DeserializeRaw(val, &v2) == nil {
+		return v2.root(), nil
+	}
+
+	var v1 rootV1
+	if encoder.DeserializeRaw(val, &v1) == nil {
+		return v1.root(), nil
+	}
+
+	return nil, err // the first error
 }
 
 // Walk through elements of the Root. Given WalkFunc will not
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"reflect"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+// A Sealer is optional interface of a Pack. A Pack that
+// implements the Sealer can encrypt values of objects
+// (see confidential feeds of the skyobject package).
+// Only objects without references are sealed, since
+// nodes should walk Root objects to replicate them
+// without decrypting. E.g. nodes of a Refs and objects
+// with Ref, Refs or Dynamic fields are saved as is
+// using the Add method of the Pack
+type Sealer interface {
+	// Seal encrypts given encoded object, saves it
+	// and returns hash of the saved value. A Sealer
+	// without encryption key saves the value as is
+	Seal(val []byte) (key cipher.SHA256, err error)
+}
+
+// add encoded object to given Pack, the object is sealed
+// if the Pack is a Sealer and the object has not references
+func addValue(
+	pack Pack, //    : pack to save
+	val []byte, //   : encoded object
+	hasRefs bool, // : has the object references
+) (
+	hash cipher.SHA256, //
+	err error, //
+) {
+
+	if sealer, ok := pack.(Sealer); ok == true && hasRefs == false {
+		return sealer.Seal(val)
+	}
+
+	return pack.Add(val)
+}
+
+// encode and add given object (see addValue)
+func addObject(pack Pack, obj interface{}) (hash cipher.SHA256, err error) {
+	var hasRefs = hasReferences(typeOf(obj), nil)
+	return addValue(pack, encoder.Serialize(obj), hasRefs)
+}
+
+// has given type Ref, Refs or Dynamic inside
+func hasReferences(typ reflect.Type, seen map[reflect.Type]struct{}) bool {
+
+	switch typ {
+	case typeOfRef, typeOfRefs, typeOfDynamic:
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Ptr:
+		return hasReferences(typ.Elem(), seen)
+	case reflect.Struct:
+	default:
+		return false
+	}
+
+	// recursive types
+
+	if _, ok := seen[typ]; ok == true {
+		return false
+	}
+
+	if seen == nil {
+		seen = make(map[reflect.Type]struct{})
+	}
+
+	seen[typ] = struct{}{}
+
+	for i, nf := 0, typ.NumField(); i < nf; i++ {
+
+		var sf = typ.Field(i)
+
+		if sf.Tag.Get("enc") == "-" || sf.PkgPath != "" || sf.Name == "_" {
+			continue
+		}
+
+		if hasReferences(sf.Type, seen) == true {
+			return true
+		}
+
+	}
+
+	return false
+}
@@ -0,0 +1,400 @@
+package skyobject
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/data"
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+// A Retention is policy of keeping Root objects of a
+// feed. The KeepLast and the KeepFor are the same as
+// GCKeepLast and GCKeepFor of the Config, but for a
+// feed. Last Root of a head is never removed by a
+// Retention
+type Retention struct {
+	KeepLast int           // keep last n Root objects of a head, 0 - not used
+	KeepFor  time.Duration // keep Root objects newer then this, 0 - not used
+	KeepAll  bool          // keep all Root objects (overrides all other)
+	FullOnly bool          // remove Root objects not all objects of which exist
+}
+
+// IsBlank returns true if the Retention removes nothing
+func (r *Retention) IsBlank() bool {
+	return r.KeepAll == true ||
+		(r.KeepLast <= 0 && r.KeepFor <= 0 && r.FullOnly == false)
+}
+
+// Validate the Retention
+func (r *Retention) Validate() (err error) {
+	if r.KeepLast < 0 {
+		return fmt.Errorf("negative KeepLast: %d", r.KeepLast)
+	}
+	if r.KeepFor < 0 {
+		return fmt.Errorf("negative KeepFor: %s", r.KeepFor)
+	}
+	return
+}
+
+// String implements fmt.Stringer interface. The
+// String returns the Retention in format of the
+// Retentions flag (without feed)
+func (r Retention) String() string {
+
+	if r.KeepAll == true {
+		return "all"
+	}
+
+	var ps []string
+
+	if r.KeepLast > 0 {
+		ps = append(ps, "last="+strconv.Itoa(r.KeepLast))
+	}
+
+	if r.KeepFor > 0 {
+		ps = append(ps, "for="+r.KeepFor.String())
+	}
+
+	if r.FullOnly == true {
+		ps = append(ps, "full")
+	}
+
+	return strings.Join(ps, ",")
+}
+
+// ParseRetention parses Retention from string
+// like "last=10,for=24h,full" or "all" (see
+// String method of the Retention)
+func ParseRetention(s string) (r Retention, err error) {
+
+	if s == "" {
+		return
+	}
+
+	for _, p := range strings.Split(s, ",") {
+
+		var kv = strings.SplitN(p, "=", 2)
+
+		switch {
+		case kv[0] == "all" && len(kv) == 1:
+			r.KeepAll = true
+		case kv[0] == "full" && len(kv) == 1:
+			r.FullOnly = true
+		case kv[0] == "last" && len(kv) == 2:
+			r.KeepLast, err = strconv.Atoi(kv[1])
+		case kv[0] == "for" && len(kv) == 2:
+			r.KeepFor, err = time.ParseDuration(kv[1])
+		default:
+			err = fmt.Errorf("unknown retention policy %q", p)
+		}
+
+		if err != nil {
+			return
+		}
+
+	}
+
+	err = r.Validate()
+	return
+}
+
+// Retentions is Retention per feed. The Retentions
+// implements flag.Value interface. Format of a flag
+// is "feed:policy", where the feed is hex-encoded
+// public key and the policy is described in the
+// ParseRetention function
+type Retentions map[cipher.PubKey]Retention
+
+// String implements flag.Value interface
+func (r *Retentions) String() string {
+
+	var ps []string
+
+	for pk, rt := range *r {
+		ps = append(ps, pk.Hex()+":"+rt.String())
+	}
+
+	return strings.Join(ps, " ")
+}
+
+// Set implements flag.Value interface
+func (r *Retentions) Set(s string) (err error) {
+
+	var fp = strings.SplitN(s, ":", 2)
+
+	if len(fp) != 2 {
+		return fmt.Errorf("invalid retention %q, want feed:policy", s)
+	}
+
+	var pk cipher.PubKey
+	if pk, err = cipher.PubKeyFromHex(fp[0]); err != nil {
+		return
+	}
+
+	var rt Retention
+	if rt, err = ParseRetention(fp[1]); err != nil {
+		return
+	}
+
+	if *r == nil {
+		*r = make(Retentions)
+	}
+
+	(*r)[pk] = rt
+	return
+}
+
+// retentions of a Container
+type retentions struct {
+	mx sync.Mutex
+	rs Retentions
+}
+
+func (r *retentions) init(rs Retentions) {
+	r.rs = make(Retentions, len(rs))
+	for pk, rt := range rs {
+		r.rs[pk] = rt
+	}
+}
+
+// Retention returns Retention of given feed. If the
+// feed has not its own Retention, then the Retention
+// built from GCKeepLast and GCKeepFor of the Config
+// returned and the ok is false
+func (c *Container) Retention(pk cipher.PubKey) (r Retention, ok bool) {
+
+	c.retentions.mx.Lock()
+	defer c.retentions.mx.Unlock()
+
+	if r, ok = c.retentions.rs[pk]; ok == false {
+		r.KeepLast = c.conf.GCKeepLast
+		r.KeepFor = c.conf.GCKeepFor
+	}
+
+	return
+}
+
+// SetRetention sets Retention of given feed. The
+// Retention is used by the garbage collector and
+// by the Retain method. The SetRetention doesn't
+// change the Config and changes are not saved
+func (c *Container) SetRetention(pk cipher.PubKey, r Retention) (err error) {
+
+	if err = r.Validate(); err != nil {
+		return
+	}
+
+	c.retentions.mx.Lock()
+	defer c.retentions.mx.Unlock()
+
+	c.retentions.rs[pk] = r
+	return
+}
+
+// DelRetention removes Retention of given feed.
+// Default Retention is used for the feed after
+func (c *Container) DelRetention(pk cipher.PubKey) {
+
+	c.retentions.mx.Lock()
+	defer c.retentions.mx.Unlock()
+
+	delete(c.retentions.rs, pk)
+}
+
+// Retentions returns copy of all Retention
+// policies of feeds set
+func (c *Container) Retentions() (rs Retentions) {
+
+	c.retentions.mx.Lock()
+	defer c.retentions.mx.Unlock()
+
+	rs = make(Retentions, len(c.retentions.rs))
+
+	for pk, rt := range c.retentions.rs {
+		rs[pk] = rt
+	}
+
+	return
+}
+
+// Retain removes Root objects of given feed by
+// Retention of the feed. It returns number of
+// removed Root objects. The garbage collector
+// does the same for all feeds
+func (c *Container) Retain(pk cipher.PubKey) (removed int, err error) {
+
+	var rs []gcRoot
+	if rs, err = c.retentionRoots(&pk); err != nil {
+		return
+	}
+
+	for _, r := range rs {
+
+		// the last Root could be changed (see removeRoots of the gc)
+
+		var last uint64
+		if last, err = c.LastRootSeq(r.pk, r.nonce); err != nil {
+			err = nil // removed
+			continue
+		} else if last == r.seq {
+			continue
+		}
+
+		switch err = c.DelRoot(r.pk, r.nonce, r.seq); err {
+		case nil:
+			removed++
+		case data.ErrNotFound, data.ErrNoSuchFeed, data.ErrNoSuchHead:
+			err = nil // already removed
+		default:
+			return // DB failure
+		}
+
+	}
+
+	return
+}
+
+// Root objects to remove by Retention policies of feeds;
+// if given pk is nil, then Root objects of all feeds
+func (c *Container) retentionRoots(pk *cipher.PubKey) (rs []gcRoot,
+	err error) {
+
+	var (
+		now   = time.Now()
+		check []gcRoot // FullOnly
+	)
+
+	err = c.db.IdxDB().Tx(func(feeds data.Feeds) (err error) {
+
+		return feeds.Iterate(func(fpk cipher.PubKey) (err error) {
+
+			if pk != nil && *pk != fpk {
+				return // skip
+			}
+
+			var rt, _ = c.Retention(fpk)
+
+			if rt.IsBlank() == true {
+				return // keep all
+			}
+
+			var before int64 // remove Root objects older
+
+			if rt.KeepFor > 0 {
+				before = now.Add(-rt.KeepFor).UnixNano()
+			}
+
+			var hs data.Heads
+			if hs, err = feeds.Heads(fpk); err != nil {
+				return
+			}
+
+			return hs.Iterate(func(nonce uint64) (err error) {
+
+				var roots data.Roots
+				if roots, err = hs.Roots(nonce); err != nil {
+					return
+				}
+
+				// keep last Root anyway
+				var (
+					length = roots.Len()
+					limit  = length - 1
+					policy = rt.KeepLast > 0 || rt.KeepFor > 0
+				)
+
+				if rt.KeepLast > 0 && length-rt.KeepLast < limit {
+					limit = length - rt.KeepLast
+				}
+
+				var i int
+
+				return roots.Ascend(func(dr *data.Root) (err error) {
+
+					if i >= length-1 {
+						return data.ErrStopIteration // the last
+					}
+
+					var gr = gcRoot{fpk, nonce, dr.Seq, dr.Hash}
+
+					if policy == true && i < limit &&
+						(before == 0 || dr.Time < before) {
+
+						rs = append(rs, gr)
+					} else if rt.FullOnly == true {
+						check = append(check, gr)
+					}
+
+					i++
+					return
+				})
+
+			})
+
+		})
+
+	})
+
+	if err != nil {
+		return
+	}
+
+	// the FullOnly
+
+	for _, gr := range check {
+
+		var full bool
+		if full, err = c.isFullRoot(gr.hash); err != nil {
+			return
+		}
+
+		if full == false {
+			rs = append(rs, gr)
+		}
+
+	}
+
+	return
+}
+
+// has the DB all objects of the Root
+func (c *Container) isFullRoot(hash cipher.SHA256) (full bool, err error) {
+
+	var val []byte
+	if val, _, err = c.Get(hash, 0); err == data.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return
+	}
+
+	var r *registry.Root
+	if r, err = registry.DecodeRoot(val); err != nil {
+		return false, nil // broken
+	}
+
+	r.Hash = hash
+
+	// the Walk doesn't get objects without references,
+	// thus, check presence of every object
+
+	err = c.Walk(r, func(key cipher.SHA256, _ int) (deepper bool, err error) {
+		if _, err = c.Inc(key, 0); err != nil {
+			return
+		}
+		return true, nil // walk all
+	})
+
+	if err == data.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return
+	}
+
+	return true, nil
+}
@@ -0,0 +1,138 @@
+package skyobject
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/skycoin/cxo/skyobject/registry"
+)
+
+func TestParseRetention(t *testing.T) {
+
+	for _, tt := range []struct {
+		s  string
+		rt Retention
+	}{
+		{"all", Retention{KeepAll: true}},
+		{"last=10", Retention{KeepLast: 10}},
+		{"last=10,for=1h0m0s,full", Retention{10, time.Hour, false, true}},
+		{"full", Retention{FullOnly: true}},
+	} {
+
+		var rt, err = ParseRetention(tt.s)
+		assertNil(t, err)
+
+		assertTrue(t, rt == tt.rt, "wrong Retention: "+tt.s)
+		assertTrue(t, rt.String() == tt.s, "wrong String: "+rt.String())
+	}
+
+	for _, invalid := range []string{"last", "last=-1", "for=x", "some"} {
+		if _, err := ParseRetention(invalid); err == nil {
+			t.Error("missing error:", invalid)
+		}
+	}
+
+	var rs Retentions
+	var pk, _ = cipher.GenerateKeyPair()
+
+	assertNil(t, rs.Set(pk.Hex()+":last=1"))
+	assertTrue(t, rs[pk].KeepLast == 1, "wrong Retentions")
+
+	if err := rs.Set(pk.Hex()); err == nil {
+		t.Error("missing error")
+	}
+
+}
+
+func TestContainer_Retain(t *testing.T) {
+
+	t.Run("keep last", func(t *testing.T) {
+
+		var conf = getTestConfig()
+		conf.GCKeepLast = 1 // default
+
+		var c, err = NewContainer(conf)
+		assertNil(t, err)
+		defer c.Close()
+
+		var (
+			pk, r   = saveTestRoots(t, c, 10)
+			opk, or = saveTestRoots(t, c, 10)
+		)
+
+		assertNil(t, c.SetRetention(pk, Retention{KeepLast: 3}))
+		assertNil(t, c.SetRetention(opk, Retention{KeepAll: true}))
+
+		var rt, own = c.Retention(pk)
+		assertTrue(t, own == true && rt.KeepLast == 3, "wrong Retention")
+
+		var removed int
+		removed, err = c.Retain(pk)
+		assertNil(t, err)
+
+		assertTrue(t, removed == 7, "wrong number of removed roots")
+		assertTrue(t, headLen(c, pk, r.Nonce) == 3, "wrong number of roots")
+
+		// the gc uses the Retention too
+		assertNil(t, c.RunGC())
+		assertTrue(t, headLen(c, opk, or.Nonce) == 10, "wrong number of roots")
+
+		// default
+		c.DelRetention(opk)
+		assertNil(t, c.RunGC())
+		assertTrue(t, headLen(c, opk, or.Nonce) == 1, "wrong number of roots")
+
+		testGCLastRoot(t, c, r)
+		testGCLastRoot(t, c, or)
+	})
+
+	t.Run("full only", func(t *testing.T) {
+
+		var conf = getTestConfig()
+		conf.CacheMaxAmount = 0 // no cache
+		conf.CacheMaxVolume = 0
+		conf.CacheMaxItemSize = 0
+
+		var c, err = NewContainer(conf)
+		assertNil(t, err)
+		defer c.Close()
+
+		var pk, sk = cipher.GenerateKeyPair()
+
+		assertNil(t, c.AddFeed(pk))
+		assertNil(t, c.SetRetention(pk, Retention{FullOnly: true}))
+
+		var up *Unpack
+		up, err = c.Unpack(sk, testRegistry)
+		assertNil(t, err)
+
+		var (
+			r     = &registry.Root{Pub: pk, Nonce: 1}
+			users []registry.Dynamic
+		)
+
+		for i := 0; i < 3; i++ {
+			var usr = createDynamic(up, testRegistry, "test.User",
+				&User{"Alice", uint32(i)})
+			users = append(users, usr)
+			r.Refs = []registry.Dynamic{usr}
+			assertNil(t, c.Save(up, r))
+		}
+
+		var removed int
+		removed, err = c.Retain(pk)
+		assertNil(t, err)
+		assertTrue(t, removed == 0, "full Root objects removed")
+
+		// break first Root
+		assertNil(t, c.DB().CXDS().Del(users[0].Hash))
+
+		removed, err = c.Retain(pk)
+		assertNil(t, err)
+		assertTrue(t, removed == 1, "wrong number of removed roots")
+		assertTrue(t, headLen(c, pk, 1) == 2, "wrong number of roots")
+	})
+
+}
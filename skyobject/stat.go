@@ -34,6 +34,9 @@ type Stat struct {
 
 	// Feeds contains statistic of feeds
 	Feeds map[cipher.PubKey]FeedStat
+
+	// GC is statistic of the garbage collector
+	GC GCStat
 }
 
 // An ObjectsStat represents
@@ -108,6 +111,8 @@ func (c *Container) Stat() (s *Stat) {
 
 	s.Feeds = c.Index.feedsStat()
 
+	s.GC = c.gc.getStat()
+
 	return
 }
 
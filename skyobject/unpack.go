@@ -2,6 +2,7 @@ package skyobject
 
 import (
 	"errors"
+	"math/rand"
 	"time"
 
 	"github.com/skycoin/skycoin/src/cipher"
@@ -300,6 +301,12 @@ func (i *Index) saveRoot(
 
 		r.Sig = cipher.SignHash(r.Hash, up.sk)
 
+		// the Unpack can use a delegate key
+
+		if err = i.verifyRoot(r); err != nil {
+			return
+		}
+
 		dr.Seq = r.Seq
 		dr.Prev = r.Prev
 		dr.Hash = r.Hash
@@ -318,28 +325,48 @@ func (i *Index) saveRoot(
 	return
 }
 
-/*
-
-TODO (kostyarin): the NewRoot is just usablility trick
-
-// NewRoot creates new blank Root object
+// NewRoot creates Root object to save using secret key of
+// given feed from the KeyStore. If the nonce is zero, then
+// active head of the feed is used, or new random nonce is
+// generated if the feed has no heads. If the head has Root
+// objects, then the NewRoot returns last Root of the head
+// (with its Refs and Descriptor) to modify and save, otherwise
+// the Root is blank. Use the Save method to save the Root
 func (c *Container) NewRoot(
-	feed cipher.PubKey,
-	nonce uint64,
-	reg *registry.Registry,
+	feed cipher.PubKey, //     : feed
+	nonce uint64, //           : head
+	reg *registry.Registry, // : registry
 ) (
-	r *registry.Root,
-	up *Unpack,
-	err error,
+	r *registry.Root, //       : the Root
+	up *Unpack, //             : Unpack to save the Root
+	err error, //              : an error
 ) {
 
-	//
+	if up, err = c.UnpackFeed(feed, reg); err != nil {
+		return
+	}
 
-	return
+	if nonce == 0 {
+		if nonce = c.ActiveHead(feed); nonce == 0 {
+			for nonce == 0 {
+				nonce = rand.Uint64() // new head
+			}
+		}
+	}
 
-}
+	if r, err = c.LastRoot(feed, nonce); err == nil {
+		return
+	} else if err != data.ErrNotFound && err != data.ErrNoSuchHead {
+		up.Close()
+		return nil, nil, err
+	}
 
-*/
+	r = new(registry.Root)
+	r.Pub = feed
+	r.Nonce = nonce
+
+	return r, up, nil
+}
 
 // Close the Unpack, rejecting all saved objects that
 // will not be used